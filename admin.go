@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"ima-svc-management/audit"
+	"ima-svc-management/config"
+	"ima-svc-management/controllers"
+	"ima-svc-management/events"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/webhook"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const adminUsage = `usage: ima-svc-management admin <command> [flags]
+
+commands:
+  create-superadmin  create a platform superadmin, or an organization superadmin with -org
+  reset-password     replace the password of an account and revoke its sessions
+  list-sessions      list live sessions, of one account with -email
+  revoke-sessions    revoke every session of an account
+
+passwords are read from -password, -password-stdin or the ADMIN_PASSWORD
+environment variable, in that order.
+`
+
+func runAdmin(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return fmt.Errorf("admin command is required")
+	}
+
+	switch args[0] {
+	case "create-superadmin":
+		return adminCreateSuperadmin(args[1:])
+	case "reset-password":
+		return adminResetPassword(args[1:])
+	case "list-sessions":
+		return adminListSessions(args[1:])
+	case "revoke-sessions":
+		return adminRevokeSessions(args[1:])
+	}
+	fmt.Fprint(os.Stderr, adminUsage)
+	return fmt.Errorf("unknown admin command %q", args[0])
+}
+
+func adminCreateSuperadmin(args []string) error {
+	flags := flag.NewFlagSet("admin create-superadmin", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	email := flags.String("email", "", "email of the superadmin (required)")
+	name := flags.String("name", "Superadmin", "display name of the superadmin")
+	orgId := flags.String("org", "", "organization id, creates a platform superadmin when empty")
+	password := flags.String("password", "", "password of the superadmin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.STORAGE, config.AUDIT, config.WEBHOOK)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+	secret, err := readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	role := model.PLATFORM_SUPERADMIN
+	if *orgId != "" {
+		role = model.SUPERADMIN
+	}
+	account := model.AccountModel{
+		OrgId:    *orgId,
+		Name:     *name,
+		Email:    *email,
+		Password: secret,
+		Role:     string(role),
+	}
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	auditor := audit.InitAuditor(store.Audit, cfg.Audit)
+	outbox := events.InitOutbox(store.Mongo, store.Outbox, store.Transactor)
+	err = outbox.Check(context.Background())
+	if err != nil {
+		return err
+	}
+	id, err := controllers.InitAccount(store.Accounts, store.Roles, store.Organizations, store.Attributes, store.Avatars, auditor, webhook.InitDispatcher(store.Webhooks, cfg.Webhook, zerolog.Nop()), outbox, cfg.Compat).CreateAccount(context.Background(), account)
+	if err != nil {
+		return err
+	}
+	account.Id = id
+	err = auditor.RecordSystem("cli:admin create-superadmin", model.AuditEventModel{
+		OrgId:   account.OrgId,
+		Action:  model.AUDIT_ACCOUNT_CREATE,
+		Target:  model.AuditTargetModel{Type: "account", Id: id, Email: account.Email},
+		Changes: audit.Diff(nil, account),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %s %s with id %s\n", role, *email, id)
+	return nil
+}
+
+func adminResetPassword(args []string) error {
+	flags := flag.NewFlagSet("admin reset-password", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	email := flags.String("email", "", "email of the account (required)")
+	orgId := flags.String("org", "", "organization id of the account, empty for a platform superadmin")
+	password := flags.String("password", "", "new password")
+	passwordStdin := flags.Bool("password-stdin", false, "read the new password from the first line of stdin")
+	keepSessions := flags.Bool("keep-sessions", false, "do not revoke the sessions of the account")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.STORAGE, config.AUDIT, config.WEBHOOK, config.SESSION, config.REDIS)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+	secret, err := readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	auditor := audit.InitAuditor(store.Audit, cfg.Audit)
+	outbox := events.InitOutbox(store.Mongo, store.Outbox, store.Transactor)
+	err = outbox.Check(ctx)
+	if err != nil {
+		return err
+	}
+	account, err := controllers.InitAccount(store.Accounts, store.Roles, store.Organizations, store.Attributes, store.Avatars, auditor, webhook.InitDispatcher(store.Webhooks, cfg.Webhook, zerolog.Nop()), outbox, cfg.Compat).ResetPassword(ctx, *orgId, *email, secret)
+	if err != nil {
+		return err
+	}
+	err = auditor.RecordSystem("cli:admin reset-password", model.AuditEventModel{
+		OrgId:   account.OrgId,
+		Action:  model.AUDIT_ACCOUNT_PASSWORD,
+		Target:  model.AuditTargetModel{Type: "account", Id: account.Id, Email: account.Email},
+		Changes: []model.AuditChangeModel{{Field: "password", Before: audit.MASKED, After: audit.MASKED}},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("password of %s reset\n", account.Email)
+
+	if *keepSessions {
+		return nil
+	}
+	if cfg.Session.Store == "memory" {
+		fmt.Println("sessions are kept in the memory of the server, restart it to end them")
+		return nil
+	}
+	tokenAuth, closeAuth, err := adminAuth(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAuth()
+	revoked, err := tokenAuth.RevokeSessions(ctx, account.OrgId, account.Email)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d sessions revoked\n", revoked)
+	return recordRevokeSessions(auditor, outbox, "cli:admin reset-password", account.OrgId, account.Email, revoked)
+}
+
+func adminListSessions(args []string) error {
+	flags := flag.NewFlagSet("admin list-sessions", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	email := flags.String("email", "", "only list the sessions of this account")
+	orgId := flags.String("org", "", "organization id of the account, empty for a platform superadmin")
+	asJSON := flags.Bool("json", false, "print the sessions as json")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.SESSION, config.REDIS)
+	if err != nil {
+		return err
+	}
+
+	tokenAuth, closeAuth, err := adminAuth(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAuth()
+
+	sessions, err := tokenAuth.ListSessions(context.Background(), *orgId, *email)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(sessions)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "UUID\tORGANIZATION\tEMAIL\tEXPIRES IN")
+	for _, session := range sessions {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", session.Uuid, session.OrgId, session.Email, session.ExpiresIn)
+	}
+	return writer.Flush()
+}
+
+func adminRevokeSessions(args []string) error {
+	flags := flag.NewFlagSet("admin revoke-sessions", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	email := flags.String("email", "", "email of the account (required)")
+	orgId := flags.String("org", "", "organization id of the account, empty for a platform superadmin")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.STORAGE, config.AUDIT, config.SESSION, config.REDIS)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	tokenAuth, closeAuth, err := adminAuth(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAuth()
+
+	revoked, err := tokenAuth.RevokeSessions(context.Background(), *orgId, *email)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d sessions revoked\n", revoked)
+	return recordRevokeSessions(audit.InitAuditor(store.Audit, cfg.Audit), events.InitOutbox(store.Mongo, store.Outbox, store.Transactor), "cli:admin revoke-sessions", *orgId, *email, revoked)
+}
+
+// adminAuth opens the session store of the server. Sessions kept in memory
+// belong to the server process, and a bolt file can only be opened while the
+// server is stopped.
+func adminAuth(cfg *config.Config) (*helpers.Auth, func(), error) {
+	if cfg.Session.Store == "memory" {
+		return nil, nil, fmt.Errorf("sessions are kept in the memory of the server and cannot be reached from here")
+	}
+	var redisClient redis.UniversalClient
+	if cfg.Session.Store == "redis" {
+		client, err := config.Redis(cfg.Redis)
+		if err != nil {
+			return nil, nil, err
+		}
+		redisClient = client
+	}
+	sessions, closeSessions, err := openSessions(cfg, redisClient)
+	if err != nil {
+		if redisClient != nil {
+			redisClient.Close()
+		}
+		return nil, nil, err
+	}
+	return helpers.InitAuth(sessions, cfg.Auth), func() {
+		closeSessions()
+		if redisClient != nil {
+			redisClient.Close()
+		}
+	}, nil
+}
+
+// recordRevokeSessions audits revoked sessions and announces them as a
+// session.revoked event, the relay of a running server publishes it.
+func recordRevokeSessions(auditor *audit.Auditor, outbox *events.Outbox, system string, orgId string, email string, revoked int64) error {
+	err := auditor.RecordSystem(system, model.AuditEventModel{
+		OrgId:  orgId,
+		Action: model.AUDIT_SESSIONS_REVOKE,
+		Target: model.AuditTargetModel{Type: "account", Email: email},
+		Reason: fmt.Sprintf("%d sessions revoked", revoked),
+	})
+	if err != nil || revoked == 0 {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), events.EMIT_TIMEOUT)
+	defer cancel()
+	return outbox.Add(ctx, model.EVENT_SESSION_REVOKED, orgId, controllers.SessionAggregateId(email), map[string]interface{}{
+		"org_id":  orgId,
+		"email":   email,
+		"revoked": revoked,
+	})
+}
+
+// readPassword keeps passwords out of the process list when scripts use
+// -password-stdin or ADMIN_PASSWORD instead of -password.
+func readPassword(password string, fromStdin bool) (string, error) {
+	if password == "" && fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading password from stdin: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		password = os.Getenv("ADMIN_PASSWORD")
+	}
+	if password == "" {
+		return "", fmt.Errorf("password is required, use -password, -password-stdin or ADMIN_PASSWORD")
+	}
+	return password, nil
+}
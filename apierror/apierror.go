@@ -0,0 +1,59 @@
+// Package apierror writes error responses. Every error leaves the service
+// in the same envelope: a stable code from the catalog, a message in the
+// language the client asked for, optional details and the request id to
+// quote when reporting a problem.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"ima-svc-management/logging"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Abort writes the envelope of code and stops the handler chain.
+func Abort(c *gin.Context, code Code) {
+	AbortWithDetails(c, code, nil)
+}
+
+// AbortWithReason adds the text of err as details. Only use it for errors
+// written for clients, like validation failures.
+func AbortWithReason(c *gin.Context, code Code, err error) {
+	AbortWithDetails(c, code, gin.H{"reason": err.Error()})
+}
+
+func AbortWithDetails(c *gin.Context, code Code, details interface{}) {
+	language := Negotiate(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", language)
+	c.AbortWithStatusJSON(Status(code), model.ErrorModel{
+		Code:      string(code),
+		Message:   Message(code, language),
+		Details:   details,
+		RequestId: logging.GetRequestId(c),
+	})
+}
+
+// Internal answers a failure the client can not fix. The cause only goes to
+// the access log, the client gets a generic message and the request id.
+func Internal(c *gin.Context, err error) {
+	c.Error(err)
+	if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+		Abort(c, TIMEOUT)
+		return
+	}
+	Abort(c, INTERNAL_ERROR)
+}
+
+// Lookup answers a failed read of a single record, notFound when it does not
+// exist and an internal error otherwise.
+func Lookup(c *gin.Context, notFound Code, err error) {
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, mongo.ErrNoDocuments) {
+		Abort(c, notFound)
+		return
+	}
+	Internal(c, err)
+}
@@ -0,0 +1,119 @@
+package apierror
+
+import "net/http"
+
+// Code identifies an error for clients. Codes are part of the API, once
+// published they keep their meaning and status.
+type Code string
+
+const (
+	INVALID_REQUEST    Code = "INVALID_REQUEST"
+	VALIDATION_FAILED  Code = "VALIDATION_FAILED"
+	INVALID_IF_MATCH   Code = "INVALID_IF_MATCH"
+	ORG_ID_REQUIRED    Code = "ORG_ID_REQUIRED"
+	ORG_ID_AMBIGUOUS   Code = "ORG_ID_AMBIGUOUS"
+	EMAIL_UNREGISTERED Code = "EMAIL_UNREGISTERED"
+	WRONG_PASSWORD     Code = "WRONG_PASSWORD"
+	AVATAR_REQUIRED    Code = "AVATAR_REQUIRED"
+
+	UNAUTHORIZED          Code = "UNAUTHORIZED"
+	INVALID_TOKEN         Code = "INVALID_TOKEN"
+	TOKEN_EXPIRED         Code = "TOKEN_EXPIRED"
+	REFRESH_TOKEN_EXPIRED Code = "REFRESH_TOKEN_EXPIRED"
+
+	FORBIDDEN               Code = "FORBIDDEN"
+	PLATFORM_ROLE_FORBIDDEN Code = "PLATFORM_ROLE_FORBIDDEN"
+
+	NOT_FOUND              Code = "NOT_FOUND"
+	ACCOUNT_NOT_FOUND      Code = "ACCOUNT_NOT_FOUND"
+	ROLE_NOT_FOUND         Code = "ROLE_NOT_FOUND"
+	ORGANIZATION_NOT_FOUND Code = "ORGANIZATION_NOT_FOUND"
+	ATTRIBUTE_NOT_FOUND    Code = "ATTRIBUTE_NOT_FOUND"
+	WEBHOOK_NOT_FOUND      Code = "WEBHOOK_NOT_FOUND"
+	DELIVERY_NOT_FOUND     Code = "DELIVERY_NOT_FOUND"
+	AVATAR_NOT_FOUND       Code = "AVATAR_NOT_FOUND"
+
+	EMAIL_REGISTERED          Code = "EMAIL_REGISTERED"
+	ROLE_REGISTERED           Code = "ROLE_REGISTERED"
+	ORGANIZATION_REGISTERED   Code = "ORGANIZATION_REGISTERED"
+	ATTRIBUTE_REGISTERED      Code = "ATTRIBUTE_REGISTERED"
+	ROLE_IN_USE               Code = "ROLE_IN_USE"
+	ORGANIZATION_HAS_ACCOUNTS Code = "ORGANIZATION_HAS_ACCOUNTS"
+	VERSION_CONFLICT          Code = "VERSION_CONFLICT"
+	PATCH_TEST_FAILED         Code = "PATCH_TEST_FAILED"
+	AUDIT_CHAIN_BROKEN        Code = "AUDIT_CHAIN_BROKEN"
+
+	PRECONDITION_FAILED    Code = "PRECONDITION_FAILED"
+	AVATAR_TOO_LARGE       Code = "AVATAR_TOO_LARGE"
+	UNSUPPORTED_MEDIA_TYPE Code = "UNSUPPORTED_MEDIA_TYPE"
+
+	ROLE_UNDEFINED     Code = "ROLE_UNDEFINED"
+	INVALID_ATTRIBUTES Code = "INVALID_ATTRIBUTES"
+	INVALID_PATCH      Code = "INVALID_PATCH"
+	INVALID_WEBHOOK    Code = "INVALID_WEBHOOK"
+	INVALID_IMAGE      Code = "INVALID_IMAGE"
+
+	INTERNAL_ERROR Code = "INTERNAL_ERROR"
+	TIMEOUT        Code = "TIMEOUT"
+)
+
+var statuses = map[Code]int{
+	INVALID_REQUEST:    http.StatusBadRequest,
+	INVALID_IF_MATCH:   http.StatusBadRequest,
+	ORG_ID_REQUIRED:    http.StatusBadRequest,
+	ORG_ID_AMBIGUOUS:   http.StatusBadRequest,
+	EMAIL_UNREGISTERED: http.StatusBadRequest,
+	WRONG_PASSWORD:     http.StatusBadRequest,
+	AVATAR_REQUIRED:    http.StatusBadRequest,
+
+	UNAUTHORIZED:          http.StatusUnauthorized,
+	INVALID_TOKEN:         http.StatusUnauthorized,
+	TOKEN_EXPIRED:         http.StatusUnauthorized,
+	REFRESH_TOKEN_EXPIRED: http.StatusUnauthorized,
+
+	FORBIDDEN:               http.StatusForbidden,
+	PLATFORM_ROLE_FORBIDDEN: http.StatusForbidden,
+
+	NOT_FOUND:              http.StatusNotFound,
+	ACCOUNT_NOT_FOUND:      http.StatusNotFound,
+	ROLE_NOT_FOUND:         http.StatusNotFound,
+	ORGANIZATION_NOT_FOUND: http.StatusNotFound,
+	ATTRIBUTE_NOT_FOUND:    http.StatusNotFound,
+	WEBHOOK_NOT_FOUND:      http.StatusNotFound,
+	DELIVERY_NOT_FOUND:     http.StatusNotFound,
+	AVATAR_NOT_FOUND:       http.StatusNotFound,
+
+	EMAIL_REGISTERED:          http.StatusConflict,
+	ROLE_REGISTERED:           http.StatusConflict,
+	ORGANIZATION_REGISTERED:   http.StatusConflict,
+	ATTRIBUTE_REGISTERED:      http.StatusConflict,
+	ROLE_IN_USE:               http.StatusConflict,
+	ORGANIZATION_HAS_ACCOUNTS: http.StatusConflict,
+	VERSION_CONFLICT:          http.StatusConflict,
+	PATCH_TEST_FAILED:         http.StatusConflict,
+	AUDIT_CHAIN_BROKEN:        http.StatusConflict,
+
+	PRECONDITION_FAILED:    http.StatusPreconditionFailed,
+	AVATAR_TOO_LARGE:       http.StatusRequestEntityTooLarge,
+	UNSUPPORTED_MEDIA_TYPE: http.StatusUnsupportedMediaType,
+
+	VALIDATION_FAILED:  http.StatusUnprocessableEntity,
+	ROLE_UNDEFINED:     http.StatusUnprocessableEntity,
+	INVALID_ATTRIBUTES: http.StatusUnprocessableEntity,
+	INVALID_PATCH:      http.StatusUnprocessableEntity,
+	INVALID_WEBHOOK:    http.StatusUnprocessableEntity,
+	INVALID_IMAGE:      http.StatusUnprocessableEntity,
+
+	INTERNAL_ERROR: http.StatusInternalServerError,
+	TIMEOUT:        http.StatusGatewayTimeout,
+}
+
+// Status returns the response status of code, codes missing from the
+// catalog are server errors.
+func Status(code Code) int {
+	status, ok := statuses[code]
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	return status
+}
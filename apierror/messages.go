@@ -0,0 +1,172 @@
+package apierror
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const LANGUAGE_EN = "en"
+const LANGUAGE_ID = "id"
+
+// DEFAULT_LANGUAGE is used when the client accepts none of the languages
+// messages are translated to.
+const DEFAULT_LANGUAGE = LANGUAGE_EN
+
+var messages = map[string]map[Code]string{
+	LANGUAGE_EN: {
+		INVALID_REQUEST:    "The request is malformed",
+		INVALID_IF_MATCH:   "If-Match header must be an ETag returned by this service",
+		ORG_ID_REQUIRED:    "orgId is required",
+		ORG_ID_AMBIGUOUS:   "Email registered in several organizations, orgId is required",
+		EMAIL_UNREGISTERED: "Email is not registered",
+		WRONG_PASSWORD:     "Wrong password",
+		AVATAR_REQUIRED:    "Avatar file is required",
+
+		UNAUTHORIZED:          "Unauthorized",
+		INVALID_TOKEN:         "Invalid token",
+		TOKEN_EXPIRED:         "Token is expired",
+		REFRESH_TOKEN_EXPIRED: "Refresh token expired",
+
+		FORBIDDEN:               "Forbidden",
+		PLATFORM_ROLE_FORBIDDEN: "Only platform superadmin can grant role platform-superadmin",
+
+		NOT_FOUND:              "Resource not found",
+		ACCOUNT_NOT_FOUND:      "Account not found",
+		ROLE_NOT_FOUND:         "Role not found",
+		ORGANIZATION_NOT_FOUND: "Organization not found",
+		ATTRIBUTE_NOT_FOUND:    "Attribute not found",
+		WEBHOOK_NOT_FOUND:      "Webhook not found",
+		DELIVERY_NOT_FOUND:     "Delivery not found or still pending",
+		AVATAR_NOT_FOUND:       "Avatar not found",
+
+		EMAIL_REGISTERED:          "Email already registered",
+		ROLE_REGISTERED:           "Role already registered",
+		ORGANIZATION_REGISTERED:   "Organization already registered",
+		ATTRIBUTE_REGISTERED:      "Attribute already defined",
+		ROLE_IN_USE:               "Role is assigned to accounts",
+		ORGANIZATION_HAS_ACCOUNTS: "Organization still has accounts",
+		VERSION_CONFLICT:          "The record has been modified by another request",
+		PATCH_TEST_FAILED:         "JSON patch test operation failed",
+		AUDIT_CHAIN_BROKEN:        "Audit chain is broken",
+
+		PRECONDITION_FAILED:    "The record has been modified by another request",
+		AVATAR_TOO_LARGE:       "Avatar must not exceed 2MB",
+		UNSUPPORTED_MEDIA_TYPE: "Avatar must be a png, jpeg or gif image",
+
+		VALIDATION_FAILED:  "The request has invalid fields",
+		ROLE_UNDEFINED:     "Role is not defined in the organization",
+		INVALID_ATTRIBUTES: "Invalid attributes",
+		INVALID_PATCH:      "Invalid patch",
+		INVALID_WEBHOOK:    "Invalid webhook",
+		INVALID_IMAGE:      "Avatar image can not be read",
+
+		INTERNAL_ERROR: "Something went wrong, please try again later",
+		TIMEOUT:        "The request took too long, please try again later",
+	},
+	LANGUAGE_ID: {
+		INVALID_REQUEST:    "Format permintaan tidak valid",
+		INVALID_IF_MATCH:   "Header If-Match harus berisi ETag yang diberikan layanan ini",
+		ORG_ID_REQUIRED:    "orgId wajib diisi",
+		ORG_ID_AMBIGUOUS:   "Email terdaftar di beberapa organisasi, orgId wajib diisi",
+		EMAIL_UNREGISTERED: "Email belum terdaftar",
+		WRONG_PASSWORD:     "Kata sandi salah",
+		AVATAR_REQUIRED:    "Berkas avatar wajib diunggah",
+
+		UNAUTHORIZED:          "Tidak terautentikasi",
+		INVALID_TOKEN:         "Token tidak valid",
+		TOKEN_EXPIRED:         "Token sudah kedaluwarsa",
+		REFRESH_TOKEN_EXPIRED: "Refresh token sudah kedaluwarsa",
+
+		FORBIDDEN:               "Akses ditolak",
+		PLATFORM_ROLE_FORBIDDEN: "Hanya platform superadmin yang dapat memberikan peran platform-superadmin",
+
+		NOT_FOUND:              "Data tidak ditemukan",
+		ACCOUNT_NOT_FOUND:      "Akun tidak ditemukan",
+		ROLE_NOT_FOUND:         "Peran tidak ditemukan",
+		ORGANIZATION_NOT_FOUND: "Organisasi tidak ditemukan",
+		ATTRIBUTE_NOT_FOUND:    "Atribut tidak ditemukan",
+		WEBHOOK_NOT_FOUND:      "Webhook tidak ditemukan",
+		DELIVERY_NOT_FOUND:     "Pengiriman tidak ditemukan atau masih diproses",
+		AVATAR_NOT_FOUND:       "Avatar tidak ditemukan",
+
+		EMAIL_REGISTERED:          "Email sudah terdaftar",
+		ROLE_REGISTERED:           "Peran sudah terdaftar",
+		ORGANIZATION_REGISTERED:   "Organisasi sudah terdaftar",
+		ATTRIBUTE_REGISTERED:      "Atribut sudah didefinisikan",
+		ROLE_IN_USE:               "Peran masih digunakan oleh akun",
+		ORGANIZATION_HAS_ACCOUNTS: "Organisasi masih memiliki akun",
+		VERSION_CONFLICT:          "Data telah diubah oleh permintaan lain",
+		PATCH_TEST_FAILED:         "Operasi test pada JSON patch gagal",
+		AUDIT_CHAIN_BROKEN:        "Rantai audit terputus",
+
+		PRECONDITION_FAILED:    "Data telah diubah oleh permintaan lain",
+		AVATAR_TOO_LARGE:       "Ukuran avatar tidak boleh melebihi 2MB",
+		UNSUPPORTED_MEDIA_TYPE: "Avatar harus berupa gambar png, jpeg atau gif",
+
+		VALIDATION_FAILED:  "Terdapat isian yang tidak valid",
+		ROLE_UNDEFINED:     "Peran tidak didefinisikan di organisasi",
+		INVALID_ATTRIBUTES: "Atribut tidak valid",
+		INVALID_PATCH:      "Patch tidak valid",
+		INVALID_WEBHOOK:    "Webhook tidak valid",
+		INVALID_IMAGE:      "Gambar avatar tidak dapat dibaca",
+
+		INTERNAL_ERROR: "Terjadi kesalahan, silakan coba lagi nanti",
+		TIMEOUT:        "Permintaan terlalu lama diproses, silakan coba lagi nanti",
+	},
+}
+
+// Message returns the message of code in language, falling back to the
+// default language and then to the code itself.
+func Message(code Code, language string) string {
+	if message, ok := messages[language][code]; ok {
+		return message
+	}
+	if message, ok := messages[DEFAULT_LANGUAGE][code]; ok {
+		return message
+	}
+	return string(code)
+}
+
+type acceptedLanguage struct {
+	language string
+	quality  float64
+}
+
+// Negotiate picks the language of the messages from an Accept-Language
+// header. Ranges are tried by quality, the first supported primary
+// subtag wins. "in" is the deprecated tag of Bahasa Indonesia.
+func Negotiate(acceptLanguage string) string {
+	accepted := make([]acceptedLanguage, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		quality := 1.0
+		for _, parameter := range fields[1:] {
+			parameter = strings.TrimSpace(parameter)
+			if strings.HasPrefix(parameter, "q=") {
+				value, err := strconv.ParseFloat(strings.TrimPrefix(parameter, "q="), 64)
+				if err == nil {
+					quality = value
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		language := strings.SplitN(tag, "-", 2)[0]
+		if language == "in" {
+			language = LANGUAGE_ID
+		}
+		accepted = append(accepted, acceptedLanguage{language: language, quality: quality})
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+	for _, candidate := range accepted {
+		if _, ok := messages[candidate.language]; ok {
+			return candidate.language
+		}
+	}
+	return DEFAULT_LANGUAGE
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"ima-svc-management/config"
+	"ima-svc-management/helpers"
+	"ima-svc-management/logging"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/tracing"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+const MASKED = "[MASKED]"
+
+// WRITE_TIMEOUT bounds the insert of one event. The write does not use the
+// request context so a client hanging up can not drop an event.
+const WRITE_TIMEOUT = time.Second * 5
+
+// SECRET_FIELDS are recorded as changed without their values.
+var SECRET_FIELDS = map[string]bool{
+	"password": true,
+}
+
+// IGNORED_FIELDS change on every write and say nothing about the change.
+var IGNORED_FIELDS = map[string]bool{
+	"version":   true,
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// Auditor appends events to the audit log in Events. Events are only ever
+// inserted, nothing in the service updates or deletes them, and each one is
+// hash chained to the one before so changes made directly in the database
+// can be detected.
+type Auditor struct {
+	Events             repository.AuditRepository
+	CheckpointSecret   []byte
+	CheckpointInterval int64
+}
+
+func InitAuditor(events repository.AuditRepository, auditConfig config.AuditConfig) *Auditor {
+	return &Auditor{
+		Events:             events,
+		CheckpointSecret:   []byte(auditConfig.CheckpointSecret),
+		CheckpointInterval: int64(auditConfig.CheckpointInterval),
+	}
+}
+
+// Record stores event for the request c. The actor defaults to the
+// authenticated caller, ip, user agent, request and trace ids are taken from
+// the request. A failed write is logged and does not fail the request.
+func (auditor *Auditor) Record(c *gin.Context, event model.AuditEventModel) {
+	if event.Actor.Email == "" && event.Actor.System == "" {
+		event.Actor = model.AuditActorModel{
+			Email: c.GetString(helpers.CONTEXT_EMAIL),
+			OrgId: c.GetString(helpers.CONTEXT_ORG_ID),
+			Role:  c.GetString(helpers.CONTEXT_ROLE),
+		}
+	}
+	event.Ip = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	event.RequestId = logging.GetRequestId(c)
+	event.TraceId = c.GetString(tracing.CONTEXT_TRACE_ID)
+
+	err := auditor.Insert(event)
+	if err != nil {
+		zerolog.Ctx(c.Request.Context()).Error().Err(err).Str("action", string(event.Action)).Msg("writing audit event")
+	}
+}
+
+// RecordSystem stores event for an action taken outside of a request, such
+// as an admin CLI command named by system.
+func (auditor *Auditor) RecordSystem(system string, event model.AuditEventModel) error {
+	event.Actor = model.AuditActorModel{System: system}
+	return auditor.Insert(event)
+}
+
+func (auditor *Auditor) Insert(event model.AuditEventModel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), WRITE_TIMEOUT)
+	defer cancel()
+
+	event.Id = helpers.GenerateId()
+	event.CreatedAt = time.Now().UTC()
+	return auditor.chainEvent(ctx, event)
+}
+
+// Diff lists the fields that differ between two versions of a document, by
+// their json names. Either side may be nil for creates and deletes. Secret
+// fields only show that they changed.
+func Diff(before interface{}, after interface{}) []model.AuditChangeModel {
+	return diffDocuments(document(before), document(after))
+}
+
+func diffDocuments(beforeDocument map[string]interface{}, afterDocument map[string]interface{}) []model.AuditChangeModel {
+	fields := make([]string, 0)
+	for field := range beforeDocument {
+		fields = append(fields, field)
+	}
+	for field := range afterDocument {
+		if _, ok := beforeDocument[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := make([]model.AuditChangeModel, 0)
+	for _, field := range fields {
+		if IGNORED_FIELDS[field] {
+			continue
+		}
+		beforeValue, afterValue := beforeDocument[field], afterDocument[field]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		if SECRET_FIELDS[field] {
+			beforeValue, afterValue = mask(beforeValue), mask(afterValue)
+		}
+		changes = append(changes, model.AuditChangeModel{Field: field, Before: beforeValue, After: afterValue})
+	}
+	return changes
+}
+
+func document(value interface{}) map[string]interface{} {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return map[string]interface{}{}
+	}
+	converted, err := helpers.ToPatchDocument(value)
+	if err != nil {
+		return map[string]interface{}{"_error": fmt.Sprintf("can not diff: %v", err)}
+	}
+	return converted
+}
+
+func mask(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return nil
+	}
+	return MASKED
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"time"
+)
+
+// CHAIN_ATTEMPTS bounds how often an insert retries after another writer
+// took the next sequence number first.
+const CHAIN_ATTEMPTS = 10
+
+var ErrChainContention = errors.New("audit chain: too many concurrent writers")
+
+// chainEvent links event to the current head of the chain and stores it. The
+// hash covers the content the store encodes the event without its hash as,
+// a verifier recomputes it from the content read back.
+func (auditor *Auditor) chainEvent(ctx context.Context, event model.AuditEventModel) error {
+	for attempt := 0; attempt < CHAIN_ATTEMPTS; attempt++ {
+		sequence, hash, err := auditor.Events.Head(ctx)
+		if err != nil {
+			return err
+		}
+		event.Sequence = sequence + 1
+		event.PrevHash = hash
+		event.Hash = ""
+
+		content, err := auditor.Events.Content(event)
+		if err != nil {
+			return err
+		}
+		event.Hash = chainHash(content)
+		err = auditor.Events.Append(ctx, event, content)
+		if err == repository.ErrDuplicate {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return auditor.checkpoint(ctx, event)
+	}
+	return ErrChainContention
+}
+
+// checkpoint signs the head of the chain every CheckpointInterval events.
+// Without the secret a rewritten chain with recomputed hashes would still
+// verify, with it the rewrite has to stop at the last checkpoint.
+func (auditor *Auditor) checkpoint(ctx context.Context, event model.AuditEventModel) error {
+	if len(auditor.CheckpointSecret) == 0 || event.Sequence%auditor.CheckpointInterval != 0 {
+		return nil
+	}
+	checkpoint := model.AuditCheckpointModel{
+		Id:        helpers.GenerateId(),
+		Sequence:  event.Sequence,
+		Hash:      event.Hash,
+		Signature: auditor.sign(event.Sequence, event.Hash),
+		CreatedAt: time.Now().UTC(),
+	}
+	return auditor.Events.AddCheckpoint(ctx, checkpoint)
+}
+
+func (auditor *Auditor) sign(sequence int64, hash string) string {
+	mac := hmac.New(sha256.New, auditor.CheckpointSecret)
+	fmt.Fprintf(mac, "%d:%s", sequence, hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify walks the chain from the first event and reports the first broken
+// link: a missing sequence number, a prevHash that does not match the hash
+// before it, a hash that does not match the stored content or a checkpoint
+// that is not signed with the configured secret or does not match the chain.
+// Events recorded before the chain existed are only counted.
+func (auditor *Auditor) Verify(ctx context.Context) (*model.AuditVerifyModel, error) {
+	report := &model.AuditVerifyModel{}
+
+	unchained, err := auditor.Events.CountUnchained(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Unchained = unchained
+
+	checkpoints, err := auditor.Events.Checkpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bySequence := map[int64]model.AuditCheckpointModel{}
+	for _, checkpoint := range checkpoints {
+		if len(auditor.CheckpointSecret) > 0 && !hmac.Equal([]byte(checkpoint.Signature), []byte(auditor.sign(checkpoint.Sequence, checkpoint.Hash))) {
+			report.Broken = &model.AuditBrokenLinkModel{Sequence: checkpoint.Sequence, Reason: "checkpoint signature does not match"}
+			return report, nil
+		}
+		bySequence[checkpoint.Sequence] = checkpoint
+	}
+
+	err = auditor.Events.Walk(ctx, func(link model.AuditEventModel, content []byte) error {
+		reason := checkLink(link, content, report)
+		if reason == "" {
+			if checkpoint, ok := bySequence[link.Sequence]; ok {
+				if checkpoint.Hash != link.Hash {
+					reason = "hash does not match the signed checkpoint"
+				} else {
+					report.Checkpoints++
+				}
+			}
+		}
+		if reason != "" {
+			report.Broken = &model.AuditBrokenLinkModel{Sequence: link.Sequence, Id: link.Id, Reason: reason}
+			return errChainBroken
+		}
+		report.Checked++
+		report.LastSequence = link.Sequence
+		report.LastHash = link.Hash
+		return nil
+	})
+	if err == errChainBroken {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// a checkpoint past the last event means the end of the chain was cut off
+	if len(checkpoints) > 0 && checkpoints[len(checkpoints)-1].Sequence > report.LastSequence {
+		last := checkpoints[len(checkpoints)-1]
+		report.Broken = &model.AuditBrokenLinkModel{Sequence: report.LastSequence + 1, Reason: fmt.Sprintf("events up to checkpoint %d are missing", last.Sequence)}
+		return report, nil
+	}
+	report.Valid = true
+	return report, nil
+}
+
+// errChainBroken stops the walk at the first broken link.
+var errChainBroken = errors.New("audit chain broken")
+
+func checkLink(link model.AuditEventModel, content []byte, report *model.AuditVerifyModel) string {
+	if link.Sequence != report.LastSequence+1 {
+		return fmt.Sprintf("expected sequence %d", report.LastSequence+1)
+	}
+	if link.PrevHash != report.LastHash {
+		return "prevHash does not match the hash of the previous event"
+	}
+	if chainHash(content) != link.Hash {
+		return "hash does not match the event content"
+	}
+	return ""
+}
+
+func chainHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
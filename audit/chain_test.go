@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"strings"
+	"testing"
+)
+
+const chainSecret = "checkpoint-secret"
+
+// chainLength events are chained with a checkpoint every chainInterval, so
+// the checkpoints are at 3, 6 and 9 and event 10 follows the last one.
+const chainLength = 10
+const chainInterval = 3
+
+// chainLink is one event as Walk hands it to Verify.
+type chainLink struct {
+	event   model.AuditEventModel
+	content []byte
+}
+
+// tamperedAudit serves the chain kept in MemoryAudit after links and
+// checkpoints changed it, the way an edit made directly in the database
+// would show up. checkpoints sees the links after the change.
+type tamperedAudit struct {
+	*repository.MemoryAudit
+	links       func(links []chainLink) []chainLink
+	checkpoints func(checkpoints []model.AuditCheckpointModel, links []chainLink) []model.AuditCheckpointModel
+}
+
+func (audit *tamperedAudit) tampered(ctx context.Context) ([]chainLink, error) {
+	links := []chainLink{}
+	err := audit.MemoryAudit.Walk(ctx, func(event model.AuditEventModel, content []byte) error {
+		links = append(links, chainLink{event: event, content: content})
+		return nil
+	})
+	if err != nil || audit.links == nil {
+		return links, err
+	}
+	return audit.links(links), nil
+}
+
+func (audit *tamperedAudit) Walk(ctx context.Context, fn func(event model.AuditEventModel, content []byte) error) error {
+	links, err := audit.tampered(ctx)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		err = fn(link.event, link.content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (audit *tamperedAudit) Checkpoints(ctx context.Context) ([]model.AuditCheckpointModel, error) {
+	checkpoints, err := audit.MemoryAudit.Checkpoints(ctx)
+	if err != nil || audit.checkpoints == nil {
+		return checkpoints, err
+	}
+	links, err := audit.tampered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return audit.checkpoints(checkpoints, links), nil
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name        string
+		secret      string
+		links       func(links []chainLink) []chainLink
+		checkpoints func(checkpoints []model.AuditCheckpointModel, links []chainLink) []model.AuditCheckpointModel
+		// broken is the sequence Verify stops at, 0 for an intact chain
+		broken int64
+		reason string
+	}{
+		{name: "intact chain", secret: chainSecret},
+		{name: "intact chain without secret"},
+		{name: "changed content", secret: chainSecret, broken: 4, reason: "hash does not match the event content",
+			links: func(links []chainLink) []chainLink {
+				links[3].content = bytes.Replace(links[3].content, []byte(model.AUDIT_ACCOUNT_DELETE), []byte(model.AUDIT_ACCOUNT_UPDATE), 1)
+				return links
+			}},
+		{name: "changed hash", secret: chainSecret, broken: 2, reason: "hash does not match the event content",
+			links: func(links []chainLink) []chainLink {
+				links[1].event.Hash = chainHash([]byte("forged"))
+				return links
+			}},
+		{name: "changed prevHash", secret: chainSecret, broken: 5, reason: "prevHash does not match the hash of the previous event",
+			links: func(links []chainLink) []chainLink {
+				return relink(links[:4], links[4:], func(event *model.AuditEventModel) {
+					event.PrevHash = chainHash([]byte("forged"))
+				})
+			}},
+		{name: "dropped event", secret: chainSecret, broken: 6, reason: "expected sequence 5",
+			links: func(links []chainLink) []chainLink {
+				return append(links[:4], links[5:]...)
+			}},
+		{name: "dropped first event", secret: chainSecret, broken: 2, reason: "expected sequence 1",
+			links: func(links []chainLink) []chainLink {
+				return links[1:]
+			}},
+		{name: "swapped events", secret: chainSecret, broken: 8, reason: "expected sequence 7",
+			links: func(links []chainLink) []chainLink {
+				links[6], links[7] = links[7], links[6]
+				return links
+			}},
+		{name: "dropped end of the chain", secret: chainSecret, broken: 9, reason: "events up to checkpoint 9 are missing",
+			links: func(links []chainLink) []chainLink {
+				return links[:8]
+			}},
+		// recomputing every hash after the change keeps the links intact,
+		// only the checkpoint still knows the original hash
+		{name: "rewritten chain", secret: chainSecret, broken: 6, reason: "hash does not match the signed checkpoint",
+			links: func(links []chainLink) []chainLink {
+				return rewrite(links, 5)
+			}},
+		{name: "rewritten chain after the last checkpoint", secret: chainSecret,
+			links: func(links []chainLink) []chainLink {
+				return rewrite(links, 10)
+			}},
+		// without the secret the checkpoints can be moved along
+		{name: "rewritten chain and checkpoints without secret",
+			links: func(links []chainLink) []chainLink {
+				return rewrite(links, 5)
+			},
+			checkpoints: follow("")},
+		{name: "rewritten chain and checkpoints", secret: chainSecret, broken: 6, reason: "checkpoint signature does not match",
+			links: func(links []chainLink) []chainLink {
+				return rewrite(links, 5)
+			},
+			checkpoints: follow("")},
+		{name: "rewritten chain and forged checkpoints", secret: chainSecret, broken: 6, reason: "checkpoint signature does not match",
+			links: func(links []chainLink) []chainLink {
+				return rewrite(links, 5)
+			},
+			checkpoints: follow("guessed")},
+		{name: "checkpoint checked with another secret", secret: "other-secret", broken: 3, reason: "checkpoint signature does not match"},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			events := buildChain(t)
+			verifier := &Auditor{
+				Events:             &tamperedAudit{MemoryAudit: events, links: test.links, checkpoints: test.checkpoints},
+				CheckpointSecret:   []byte(test.secret),
+				CheckpointInterval: chainInterval,
+			}
+			report, err := verifier.Verify(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.broken == 0 {
+				if !report.Valid || report.Broken != nil || report.Checked != chainLength || report.LastSequence != chainLength {
+					t.Fatalf("got %+v %+v, want an intact chain of %d events", *report, report.Broken, chainLength)
+				}
+				if test.secret != "" && report.Checkpoints != chainLength/chainInterval {
+					t.Fatalf("got %d checkpoints matched, want %d", report.Checkpoints, chainLength/chainInterval)
+				}
+				return
+			}
+			if report.Valid || report.Broken == nil {
+				t.Fatalf("got %+v, want the chain broken at %d", *report, test.broken)
+			}
+			if report.Broken.Sequence != test.broken || !strings.Contains(report.Broken.Reason, test.reason) {
+				t.Fatalf("got broken at %d: %s, want %d: %s", report.Broken.Sequence, report.Broken.Reason, test.broken, test.reason)
+			}
+		})
+	}
+}
+
+func TestChainEventSignsCheckpoints(t *testing.T) {
+	events := buildChain(t)
+	checkpoints, err := events.Checkpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &Auditor{CheckpointSecret: []byte(chainSecret)}
+	sequences := []int64{}
+	for _, checkpoint := range checkpoints {
+		sequences = append(sequences, checkpoint.Sequence)
+		if checkpoint.Signature != signer.sign(checkpoint.Sequence, checkpoint.Hash) {
+			t.Fatalf("checkpoint %d is not signed with the secret", checkpoint.Sequence)
+		}
+	}
+	if len(sequences) != 3 || sequences[0] != 3 || sequences[1] != 6 || sequences[2] != 9 {
+		t.Fatalf("got checkpoints at %v, want 3, 6 and 9", sequences)
+	}
+
+	unsigned := repository.InitMemoryAudit()
+	auditor := &Auditor{Events: unsigned, CheckpointInterval: chainInterval}
+	for index := 0; index < chainLength; index++ {
+		err = auditor.Insert(model.AuditEventModel{Action: model.AUDIT_LOGIN})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkpoints, err = unsigned.Checkpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 0 {
+		t.Fatalf("got %d checkpoints without a secret", len(checkpoints))
+	}
+}
+
+// buildChain records chainLength events, every third one a delete, with
+// checkpoints signed with chainSecret.
+func buildChain(t *testing.T) *repository.MemoryAudit {
+	t.Helper()
+	events := repository.InitMemoryAudit()
+	auditor := &Auditor{Events: events, CheckpointSecret: []byte(chainSecret), CheckpointInterval: chainInterval}
+	for index := 0; index < chainLength; index++ {
+		action := model.AUDIT_ACCOUNT_UPDATE
+		if index%3 == 0 {
+			action = model.AUDIT_ACCOUNT_DELETE
+		}
+		err := auditor.Insert(model.AuditEventModel{
+			OrgId:  "org-a",
+			Action: action,
+			Actor:  model.AuditActorModel{Email: "admin@example.com"},
+			Target: model.AuditTargetModel{Type: "account", Id: "account-a"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return events
+}
+
+// follow moves the checkpoints to the hashes of links, the moved ones are
+// signed with secret when it is given and keep their old signature
+// otherwise.
+func follow(secret string) func(checkpoints []model.AuditCheckpointModel, links []chainLink) []model.AuditCheckpointModel {
+	return func(checkpoints []model.AuditCheckpointModel, links []chainLink) []model.AuditCheckpointModel {
+		signer := &Auditor{CheckpointSecret: []byte(secret)}
+		for index := range checkpoints {
+			checkpoint := &checkpoints[index]
+			hash := links[checkpoint.Sequence-1].event.Hash
+			if checkpoint.Hash == hash {
+				continue
+			}
+			checkpoint.Hash = hash
+			if secret != "" {
+				checkpoint.Signature = signer.sign(checkpoint.Sequence, checkpoint.Hash)
+			}
+		}
+		return checkpoints
+	}
+}
+
+// rewrite changes the events from sequence on and recomputes their hashes
+// and links, like an attacker with write access to the audit log would.
+func rewrite(links []chainLink, sequence int64) []chainLink {
+	return relink(links[:sequence-1], links[sequence-1:], func(event *model.AuditEventModel) {
+		event.Reason = "rewritten"
+	})
+}
+
+// relink appends tail to head after change, every event of tail is hashed
+// again and linked to the one before it.
+func relink(head []chainLink, tail []chainLink, change func(event *model.AuditEventModel)) []chainLink {
+	encoder := repository.InitMemoryAudit()
+	links := append([]chainLink{}, head...)
+	prevHash := head[len(head)-1].event.Hash
+	for _, link := range tail {
+		event := link.event
+		event.PrevHash = prevHash
+		change(&event)
+		// encoding an event as JSON does not fail
+		content, _ := encoder.Content(event)
+		event.Hash = chainHash(content)
+		links = append(links, chainLink{event: event, content: content})
+		prevHash = event.Hash
+	}
+	return links
+}
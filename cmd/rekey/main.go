@@ -0,0 +1,234 @@
+// Command rekey is a one-shot migration that replaces the legacy md5 content
+// hash ids with generated ObjectID ids, rewrites every reference to the old
+// ids, backfills normalized emails and finally applies the pending schema
+// migrations, which create the unique indexes.
+//
+// It is safe to run again after a failure: the old to new id mapping is kept in
+// the id_migration collection and reused.
+//
+//	go run ./cmd/rekey -dry-run
+//	go run ./cmd/rekey
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ima-svc-management/config"
+	"ima-svc-management/helpers"
+	"ima-svc-management/migrations"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reference is a field of another collection holding ids of a rekeyed collection.
+type reference struct {
+	Collection string
+	Field      string
+}
+
+// collections are rekeyed in order, references listed here are rewritten
+// before the next collection is processed.
+var collections = []struct {
+	Name       string
+	References []reference
+}{
+	{
+		Name: "organization",
+		References: []reference{
+			{Collection: "account", Field: "orgId"},
+			{Collection: "role", Field: "orgId"},
+			{Collection: "attribute_schema", Field: "orgId"},
+			{Collection: "avatar.files", Field: "metadata.orgId"},
+		},
+	},
+	{Name: "role"},
+	{Name: "attribute_schema"},
+	{
+		Name: "account",
+		References: []reference{
+			{Collection: "avatar.files", Field: "metadata.accountId"},
+		},
+	},
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would change")
+	loader := config.Bind(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load(config.STORAGE)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Storage.Backend != "mongo" {
+		// Postgres was created with generated ids and normalized emails
+		log.Fatalf("rekey only migrates mongo, storage.backend is %s", cfg.Storage.Backend)
+	}
+	client, err := config.Mongo(cfg.Mongo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.TODO())
+
+	ctx := context.Background()
+	database := client.Database(cfg.Mongo.Database)
+
+	for _, collection := range collections {
+		rekeyed, err := rekey(ctx, database, collection.Name, collection.References, *dryRun)
+		if err != nil {
+			log.Fatalf("rekey %s: %v", collection.Name, err)
+		}
+		fmt.Printf("%s: %d documents rekeyed\n", collection.Name, rekeyed)
+	}
+
+	normalized, err := normalizeEmails(ctx, database, *dryRun)
+	if err != nil {
+		log.Fatalf("normalize emails: %v", err)
+	}
+	fmt.Printf("account: %d emails normalized\n", normalized)
+
+	if *dryRun {
+		fmt.Println("dry run, no migration applied")
+		return
+	}
+	err = migrations.InitRunner(database).Up(ctx, 0, func(format string, args ...interface{}) {
+		fmt.Printf(format+"\n", args...)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "applying migrations failed, resolve these duplicates and run again:")
+		reportErr := reportDuplicates(ctx, database)
+		if reportErr != nil {
+			fmt.Fprintln(os.Stderr, reportErr)
+		}
+		log.Fatal(err)
+	}
+	fmt.Println("migrations applied, existing sessions still hold old organization ids and should be revoked")
+}
+
+func rekey(ctx context.Context, database *mongo.Database, name string, references []reference, dryRun bool) (int, error) {
+	collection := database.Collection(name)
+	mappings := database.Collection("id_migration")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rekeyed := 0
+	for cursor.Next(ctx) {
+		document := bson.M{}
+		err = cursor.Decode(&document)
+		if err != nil {
+			return rekeyed, err
+		}
+		oldId, ok := document["_id"].(string)
+		if !ok || primitive.IsValidObjectID(oldId) {
+			continue
+		}
+		rekeyed++
+		if dryRun {
+			continue
+		}
+
+		mapping := bson.M{}
+		mappingId := name + ":" + oldId
+		err = mappings.FindOne(ctx, bson.M{"_id": mappingId}).Decode(&mapping)
+		if err == mongo.ErrNoDocuments {
+			mapping = bson.M{"_id": mappingId, "collection": name, "oldId": oldId, "newId": helpers.GenerateId()}
+			_, err = mappings.InsertOne(ctx, mapping)
+		}
+		if err != nil {
+			return rekeyed, err
+		}
+		newId := mapping["newId"].(string)
+
+		document["_id"] = newId
+		_, err = collection.ReplaceOne(ctx, bson.M{"_id": newId}, document, options.Replace().SetUpsert(true))
+		if err != nil {
+			return rekeyed, err
+		}
+		for _, reference := range references {
+			_, err = database.Collection(reference.Collection).UpdateMany(ctx, bson.M{reference.Field: oldId}, bson.M{"$set": bson.M{reference.Field: newId}})
+			if err != nil {
+				return rekeyed, err
+			}
+		}
+		_, err = collection.DeleteOne(ctx, bson.M{"_id": oldId})
+		if err != nil {
+			return rekeyed, err
+		}
+	}
+	return rekeyed, cursor.Err()
+}
+
+func normalizeEmails(ctx context.Context, database *mongo.Database, dryRun bool) (int, error) {
+	collection := database.Collection("account")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	normalized := 0
+	for cursor.Next(ctx) {
+		account := struct {
+			Id              string `bson:"_id"`
+			Email           string `bson:"email"`
+			EmailNormalized string `bson:"emailNormalized"`
+		}{}
+		err = cursor.Decode(&account)
+		if err != nil {
+			return normalized, err
+		}
+		if account.EmailNormalized == helpers.NormalizeEmail(account.Email) {
+			continue
+		}
+		normalized++
+		if dryRun {
+			continue
+		}
+		update := bson.M{"$set": bson.M{"emailNormalized": helpers.NormalizeEmail(account.Email)}}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": account.Id}, update)
+		if err != nil {
+			return normalized, err
+		}
+	}
+	return normalized, cursor.Err()
+}
+
+// reportDuplicates prints the documents that block a unique index.
+func reportDuplicates(ctx context.Context, database *mongo.Database) error {
+	duplicates := map[string]bson.D{
+		"organization":     {{Key: "name", Value: "$name"}},
+		"account":          {{Key: "orgId", Value: "$orgId"}, {Key: "email", Value: "$emailNormalized"}},
+		"role":             {{Key: "orgId", Value: "$orgId"}, {Key: "name", Value: "$name"}},
+		"attribute_schema": {{Key: "orgId", Value: "$orgId"}, {Key: "name", Value: "$name"}},
+	}
+	for name, key := range duplicates {
+		pipeline := mongo.Pipeline{
+			{{Key: "$group", Value: bson.M{"_id": key, "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}}},
+			{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		}
+		cursor, err := database.Collection(name).Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		groups := make([]bson.M, 0)
+		err = cursor.All(ctx, &groups)
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			fmt.Fprintf(os.Stderr, "%s %v: %v\n", name, group["_id"], group["ids"])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,733 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is loaded once at startup from, in increasing precedence, the
+// defaults below, an optional YAML file, environment variables and command
+// line flags. Secrets have no flag so they never show up in the process list.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Mongo    MongoConfig    `yaml:"mongo"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Session  SessionConfig  `yaml:"session"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Migrate  MigrateConfig  `yaml:"migrate"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Log      LogConfig      `yaml:"log"`
+	Audit    AuditConfig    `yaml:"audit"`
+	Webhook  WebhookConfig  `yaml:"webhook"`
+	Events   EventsConfig   `yaml:"events"`
+	Compat   CompatConfig   `yaml:"compat"`
+}
+
+type ServerConfig struct {
+	Port             int           `yaml:"port" env:"PORT" flag:"port" usage:"HTTP listen port"`
+	ShutdownTimeout  time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long in-flight requests may take to finish on shutdown"`
+	ReadinessTimeout time.Duration `yaml:"readinessTimeout" env:"READINESS_TIMEOUT" flag:"readiness-timeout" usage:"timeout of each dependency ping in /readyz"`
+	RequestTimeout   time.Duration `yaml:"requestTimeout" env:"REQUEST_TIMEOUT" flag:"request-timeout" usage:"deadline of the database calls made while serving one API request"`
+}
+
+// MongoConfig reaches MongoDB through uri when it is set, the structured
+// settings below it apply otherwise.
+type MongoConfig struct {
+	URI             string        `yaml:"uri" env:"MONGO_URI"`
+	Host            string        `yaml:"host" env:"MONGO_HOST" flag:"mongo-host" usage:"MongoDB host, or comma separated hosts of a replica set"`
+	Port            int           `yaml:"port" env:"MONGO_PORT" flag:"mongo-port" usage:"MongoDB port of the hosts given without one"`
+	Username        string        `yaml:"username" env:"MONGO_USERNAME"`
+	Password        string        `yaml:"password" env:"MONGO_PASSWORD"`
+	AuthSource      string        `yaml:"authSource" env:"MONGO_AUTH_SOURCE" flag:"mongo-auth-source" usage:"MongoDB authentication database"`
+	ReplicaSet      string        `yaml:"replicaSet" env:"MONGO_REPLICA_SET" flag:"mongo-replica-set" usage:"name of the MongoDB replica set"`
+	TLS             bool          `yaml:"tls" env:"MONGO_TLS" flag:"mongo-tls" usage:"connect to MongoDB over TLS"`
+	CAFile          string        `yaml:"caFile" env:"MONGO_CA_FILE" flag:"mongo-ca-file" usage:"PEM bundle of the CAs trusted for MongoDB TLS, the system pool when empty"`
+	ReadPreference  string        `yaml:"readPreference" env:"MONGO_READ_PREFERENCE" flag:"mongo-read-preference" usage:"primary, primaryPreferred, secondary, secondaryPreferred or nearest"`
+	WriteConcern    string        `yaml:"writeConcern" env:"MONGO_WRITE_CONCERN" flag:"mongo-write-concern" usage:"majority or the number of members acknowledging a write"`
+	MaxPoolSize     int           `yaml:"maxPoolSize" env:"MONGO_MAX_POOL_SIZE" flag:"mongo-max-pool-size" usage:"most connections per MongoDB server"`
+	MinPoolSize     int           `yaml:"minPoolSize" env:"MONGO_MIN_POOL_SIZE" flag:"mongo-min-pool-size" usage:"connections kept open per MongoDB server"`
+	AppName         string        `yaml:"appName" env:"MONGO_APP_NAME" flag:"mongo-app-name" usage:"name the connections report to MongoDB"`
+	Database        string        `yaml:"database" env:"MONGO_DATABASE" flag:"mongo-database" usage:"MongoDB database holding the service collections"`
+	ConnectAttempts int           `yaml:"connectAttempts" env:"MONGO_CONNECT_ATTEMPTS" flag:"mongo-connect-attempts" usage:"startup pings before giving up on MongoDB"`
+	ConnectBackoff  time.Duration `yaml:"connectBackoff" env:"MONGO_CONNECT_BACKOFF" flag:"mongo-connect-backoff" usage:"delay after the first failed startup ping, doubled after every further one"`
+}
+
+// StorageConfig selects the database every repository is kept in, Mongo is
+// not connected with the postgres backend. Sessions stay in the session
+// store with either backend.
+type StorageConfig struct {
+	Backend string `yaml:"backend" env:"STORAGE_BACKEND" flag:"storage-backend" usage:"database holding every repository but the sessions: mongo or postgres"`
+}
+
+type PostgresConfig struct {
+	URL             string        `yaml:"url" env:"POSTGRES_URL"`
+	MaxOpenConns    int           `yaml:"maxOpenConns" env:"POSTGRES_MAX_OPEN_CONNS" flag:"postgres-max-open-conns" usage:"most open Postgres connections"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" env:"POSTGRES_MAX_IDLE_CONNS" flag:"postgres-max-idle-conns" usage:"most idle Postgres connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" env:"POSTGRES_CONN_MAX_LIFETIME" flag:"postgres-conn-max-lifetime" usage:"how long a Postgres connection is reused"`
+	ConnectAttempts int           `yaml:"connectAttempts" env:"POSTGRES_CONNECT_ATTEMPTS" flag:"postgres-connect-attempts" usage:"startup pings before giving up on Postgres"`
+	ConnectBackoff  time.Duration `yaml:"connectBackoff" env:"POSTGRES_CONNECT_BACKOFF" flag:"postgres-connect-backoff" usage:"delay after the first failed startup ping, doubled after every further one"`
+}
+
+// RedisConfig reaches a standalone server at host and port, the master named
+// masterName through the sentinels in addrs or the cluster seeded by addrs.
+type RedisConfig struct {
+	Mode             string        `yaml:"mode" env:"REDIS_MODE" flag:"redis-mode" usage:"Redis deployment: standalone, sentinel or cluster"`
+	Host             string        `yaml:"host" env:"REDIS_HOST" flag:"redis-host" usage:"Redis host in standalone mode"`
+	Port             int           `yaml:"port" env:"REDIS_PORT" flag:"redis-port" usage:"Redis port in standalone mode"`
+	Addrs            string        `yaml:"addrs" env:"REDIS_ADDRS" flag:"redis-addrs" usage:"comma separated host:port of the sentinels or cluster nodes"`
+	MasterName       string        `yaml:"masterName" env:"REDIS_MASTER_NAME" flag:"redis-master-name" usage:"name of the master monitored by the sentinels"`
+	Username         string        `yaml:"username" env:"REDIS_USERNAME" flag:"redis-username" usage:"Redis ACL user, the default user when empty"`
+	Password         string        `yaml:"password" env:"REDIS_PASSWORD"`
+	SentinelPassword string        `yaml:"sentinelPassword" env:"REDIS_SENTINEL_PASSWORD"`
+	DB               int           `yaml:"db" env:"REDIS_DB" flag:"redis-db" usage:"Redis logical database, not available in cluster mode"`
+	TLS              bool          `yaml:"tls" env:"REDIS_TLS" flag:"redis-tls" usage:"connect to Redis over TLS"`
+	CAFile           string        `yaml:"caFile" env:"REDIS_CA_FILE" flag:"redis-ca-file" usage:"PEM bundle of the CAs trusted for Redis TLS, the system pool when empty"`
+	PoolSize         int           `yaml:"poolSize" env:"REDIS_POOL_SIZE" flag:"redis-pool-size" usage:"most connections per Redis node"`
+	MinIdleConns     int           `yaml:"minIdleConns" env:"REDIS_MIN_IDLE_CONNS" flag:"redis-min-idle-conns" usage:"idle connections kept open per Redis node"`
+	DialTimeout      time.Duration `yaml:"dialTimeout" env:"REDIS_DIAL_TIMEOUT" flag:"redis-dial-timeout" usage:"timeout of opening a Redis connection"`
+	ReadTimeout      time.Duration `yaml:"readTimeout" env:"REDIS_READ_TIMEOUT" flag:"redis-read-timeout" usage:"timeout of reading a Redis reply"`
+	WriteTimeout     time.Duration `yaml:"writeTimeout" env:"REDIS_WRITE_TIMEOUT" flag:"redis-write-timeout" usage:"timeout of sending a Redis command"`
+	PoolTimeout      time.Duration `yaml:"poolTimeout" env:"REDIS_POOL_TIMEOUT" flag:"redis-pool-timeout" usage:"how long a command waits for a free connection"`
+	ConnectAttempts  int           `yaml:"connectAttempts" env:"REDIS_CONNECT_ATTEMPTS" flag:"redis-connect-attempts" usage:"startup pings before giving up on Redis"`
+	ConnectBackoff   time.Duration `yaml:"connectBackoff" env:"REDIS_CONNECT_BACKOFF" flag:"redis-connect-backoff" usage:"delay after the first failed startup ping, doubled after every further one"`
+}
+
+// SessionConfig selects where token sessions are kept. Without Redis the
+// domain events stay in the outbox and the events endpoint is not served.
+type SessionConfig struct {
+	Store string `yaml:"store" env:"SESSION_STORE" flag:"session-store" usage:"where sessions are kept: redis, memory or bolt"`
+	Path  string `yaml:"path" env:"SESSION_PATH" flag:"session-path" usage:"file of the bolt session store"`
+}
+
+type AuthConfig struct {
+	AccessTokenSecret  string        `yaml:"accessTokenSecret" env:"ACCESS_TOKEN_SECRET"`
+	RefreshTokenSecret string        `yaml:"refreshTokenSecret" env:"REFRESH_TOKEN_SECRET"`
+	AccessTokenTTL     time.Duration `yaml:"accessTokenTTL" env:"ACCESS_TOKEN_TTL" flag:"access-token-ttl" usage:"lifetime of access tokens"`
+	RefreshTokenTTL    time.Duration `yaml:"refreshTokenTTL" env:"REFRESH_TOKEN_TTL" flag:"refresh-token-ttl" usage:"lifetime of refresh tokens"`
+	CookieDomain       string        `yaml:"cookieDomain" env:"REFRESH_COOKIE_DOMAIN" flag:"refresh-cookie-domain" usage:"domain of the refresh token cookie, only the serving host when empty"`
+	CookieSecure       bool          `yaml:"cookieSecure" env:"REFRESH_COOKIE_SECURE" flag:"refresh-cookie-secure" usage:"only send the refresh token cookie over HTTPS"`
+}
+
+type MigrateConfig struct {
+	OnStartup bool `yaml:"onStartup" env:"MIGRATE_ON_STARTUP" flag:"migrate-on-startup" usage:"apply pending database migrations before serving, serve refuses to start while any are pending otherwise"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"trace exporter: none, otlp or stdout"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"tracing-endpoint" usage:"OTLP/HTTP collector endpoint, host:port or URL"`
+	Insecure    bool    `yaml:"insecure" env:"TRACING_INSECURE" flag:"tracing-insecure" usage:"send OTLP traces over plain HTTP"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME" flag:"tracing-service-name" usage:"service.name resource attribute"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" usage:"share of new traces to sample, parent decisions are kept"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum log level: debug, info, warn or error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"log format: json or console"`
+}
+
+type AuditConfig struct {
+	CheckpointSecret   string `yaml:"checkpointSecret" env:"AUDIT_CHECKPOINT_SECRET"`
+	CheckpointInterval int    `yaml:"checkpointInterval" env:"AUDIT_CHECKPOINT_INTERVAL" flag:"audit-checkpoint-interval" usage:"sign a checkpoint of the audit hash chain every this many events"`
+}
+
+type WebhookConfig struct {
+	Workers         int           `yaml:"workers" env:"WEBHOOK_WORKERS" flag:"webhook-workers" usage:"webhook deliveries sent in parallel"`
+	Timeout         time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" flag:"webhook-timeout" usage:"timeout of one webhook request"`
+	MaxAttempts     int           `yaml:"maxAttempts" env:"WEBHOOK_MAX_ATTEMPTS" flag:"webhook-max-attempts" usage:"attempts before a delivery is dead lettered"`
+	BackoffBase     time.Duration `yaml:"backoffBase" env:"WEBHOOK_BACKOFF_BASE" flag:"webhook-backoff-base" usage:"delay before the first retry, doubled on every further retry"`
+	BackoffMax      time.Duration `yaml:"backoffMax" env:"WEBHOOK_BACKOFF_MAX" flag:"webhook-backoff-max" usage:"longest delay between two retries"`
+	PollInterval    time.Duration `yaml:"pollInterval" env:"WEBHOOK_POLL_INTERVAL" flag:"webhook-poll-interval" usage:"how often due retries are looked for"`
+	AllowedNetworks string        `yaml:"allowedNetworks" env:"WEBHOOK_ALLOWED_NETWORKS" flag:"webhook-allowed-networks" usage:"comma separated CIDRs of internal networks webhooks may be delivered to"`
+}
+
+type EventsConfig struct {
+	Stream       string        `yaml:"stream" env:"EVENTS_STREAM" flag:"events-stream" usage:"Redis stream the domain events are published to"`
+	MaxLen       int           `yaml:"maxLen" env:"EVENTS_MAX_LEN" flag:"events-max-len" usage:"approximate number of events kept in the stream for replay"`
+	BatchSize    int           `yaml:"batchSize" env:"EVENTS_BATCH_SIZE" flag:"events-batch-size" usage:"outbox events published per relay round"`
+	PollInterval time.Duration `yaml:"pollInterval" env:"EVENTS_POLL_INTERVAL" flag:"events-poll-interval" usage:"how often the relay looks for unpublished events"`
+}
+
+// CompatConfig keeps older API behaviour available while clients migrate.
+type CompatConfig struct {
+	V1Responses bool `yaml:"v1Responses" env:"COMPAT_V1_RESPONSES" flag:"compat-v1-responses" usage:"answer accounts with the snake_case keys of the v1 responses"`
+}
+
+// ValidationError lists every invalid setting so they can be fixed in one go.
+type ValidationError []string
+
+func (validationError ValidationError) Error() string {
+	return "invalid config: " + strings.Join(validationError, "; ")
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:             45541,
+			ShutdownTimeout:  time.Second * 15,
+			ReadinessTimeout: time.Second * 2,
+			RequestTimeout:   time.Second * 30,
+		},
+		Mongo: MongoConfig{
+			Host:            "localhost",
+			Port:            27017,
+			AuthSource:      "admin",
+			ReadPreference:  "primary",
+			WriteConcern:    "majority",
+			MaxPoolSize:     100,
+			AppName:         "ima-svc-management",
+			Database:        "test",
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Millisecond * 500,
+		},
+		Storage: StorageConfig{
+			Backend: "mongo",
+		},
+		Postgres: PostgresConfig{
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: time.Minute * 30,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Millisecond * 500,
+		},
+		Redis: RedisConfig{
+			Mode:            "standalone",
+			Host:            "localhost",
+			Port:            6379,
+			PoolSize:        20,
+			DialTimeout:     time.Second * 5,
+			ReadTimeout:     time.Second * 3,
+			WriteTimeout:    time.Second * 3,
+			PoolTimeout:     time.Second * 4,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Millisecond * 500,
+		},
+		Session: SessionConfig{
+			Store: "redis",
+			Path:  "sessions.db",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  time.Minute * 15,
+			RefreshTokenTTL: time.Hour * 24,
+			CookieSecure:    true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			ServiceName: "ima-svc-management",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Audit: AuditConfig{
+			CheckpointInterval: 1000,
+		},
+		Webhook: WebhookConfig{
+			Workers:      4,
+			Timeout:      time.Second * 10,
+			MaxAttempts:  8,
+			BackoffBase:  time.Second * 30,
+			BackoffMax:   time.Hour,
+			PollInterval: time.Second * 5,
+		},
+		Events: EventsConfig{
+			Stream:       "ima:events",
+			MaxLen:       100000,
+			BatchSize:    100,
+			PollInterval: time.Second,
+		},
+	}
+}
+
+// Section names a part of the config a command depends on, a command only
+// validates the sections it uses so that, say, migrate runs without the
+// token secrets.
+type Section string
+
+const (
+	SERVER  Section = "server"
+	STORAGE Section = "storage"
+	SESSION Section = "session"
+	REDIS   Section = "redis"
+	AUTH    Section = "auth"
+	TRACING Section = "tracing"
+	LOG     Section = "log"
+	AUDIT   Section = "audit"
+	WEBHOOK Section = "webhook"
+	EVENTS  Section = "events"
+)
+
+// SECTIONS lists every section, serve uses all of them.
+var SECTIONS = []Section{SERVER, STORAGE, SESSION, REDIS, AUTH, TRACING, LOG, AUDIT, WEBHOOK, EVENTS}
+
+// Validate checks sections, every section when none is given.
+func (config *Config) Validate(sections ...Section) error {
+	if len(sections) == 0 {
+		sections = SECTIONS
+	}
+	problems := ValidationError{}
+	for _, section := range sections {
+		switch section {
+		case SERVER:
+			problems = append(problems, config.validateServer()...)
+		case STORAGE:
+			problems = append(problems, config.validateStorage()...)
+		case SESSION:
+			problems = append(problems, config.validateSession()...)
+		case REDIS:
+			problems = append(problems, config.validateRedis()...)
+		case AUTH:
+			problems = append(problems, config.validateAuth()...)
+		case TRACING:
+			problems = append(problems, config.validateTracing()...)
+		case LOG:
+			problems = append(problems, config.validateLog()...)
+		case AUDIT:
+			problems = append(problems, config.validateAudit()...)
+		case WEBHOOK:
+			problems = append(problems, config.validateWebhook()...)
+		case EVENTS:
+			problems = append(problems, config.validateEvents()...)
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (config *Config) validateServer() []string {
+	problems := []string{}
+	if config.Server.Port <= 0 || config.Server.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("server.port %d is not a valid port", config.Server.Port))
+	}
+	if config.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdownTimeout must be positive")
+	}
+	if config.Server.ReadinessTimeout <= 0 {
+		problems = append(problems, "server.readinessTimeout must be positive")
+	}
+	if config.Server.RequestTimeout <= 0 {
+		problems = append(problems, "server.requestTimeout must be positive")
+	}
+	return problems
+}
+
+func (config *Config) validateStorage() []string {
+	switch config.Storage.Backend {
+	case "mongo":
+		return config.validateMongo()
+	case "postgres":
+		return config.validatePostgres()
+	}
+	return []string{fmt.Sprintf("storage.backend %q must be mongo or postgres", config.Storage.Backend)}
+}
+
+func (config *Config) validateMongo() []string {
+	problems := []string{}
+	if config.Mongo.URI == "" {
+		if config.Mongo.Host == "" {
+			problems = append(problems, "mongo.host is required without mongo.uri")
+		}
+		if config.Mongo.Port <= 0 || config.Mongo.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("mongo.port %d is not a valid port", config.Mongo.Port))
+		}
+		if _, err := readpref.ModeFromString(config.Mongo.ReadPreference); err != nil {
+			problems = append(problems, fmt.Sprintf("mongo.readPreference %q must be primary, primaryPreferred, secondary, secondaryPreferred or nearest", config.Mongo.ReadPreference))
+		}
+		if _, err := mongoWriteConcern(config.Mongo.WriteConcern); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if config.Mongo.CAFile != "" && !config.Mongo.TLS {
+			problems = append(problems, "mongo.caFile is only used with mongo.tls")
+		}
+		if config.Mongo.MaxPoolSize <= 0 {
+			problems = append(problems, "mongo.maxPoolSize must be positive")
+		}
+		if config.Mongo.MinPoolSize < 0 || config.Mongo.MinPoolSize > config.Mongo.MaxPoolSize {
+			problems = append(problems, "mongo.minPoolSize must be between 0 and mongo.maxPoolSize")
+		}
+	}
+	if config.Mongo.Database == "" {
+		problems = append(problems, "mongo.database is required")
+	}
+	if config.Mongo.ConnectAttempts <= 0 {
+		problems = append(problems, "mongo.connectAttempts must be positive")
+	}
+	if config.Mongo.ConnectBackoff < 0 {
+		problems = append(problems, "mongo.connectBackoff must not be negative")
+	}
+	return problems
+}
+
+func (config *Config) validatePostgres() []string {
+	problems := []string{}
+	if config.Postgres.URL == "" {
+		problems = append(problems, "postgres.url (POSTGRES_URL) is required for the postgres storage backend")
+	}
+	if config.Postgres.MaxOpenConns <= 0 {
+		problems = append(problems, "postgres.maxOpenConns must be positive")
+	}
+	if config.Postgres.MaxIdleConns < 0 || config.Postgres.MaxIdleConns > config.Postgres.MaxOpenConns {
+		problems = append(problems, "postgres.maxIdleConns must be between 0 and postgres.maxOpenConns")
+	}
+	if config.Postgres.ConnMaxLifetime < 0 {
+		problems = append(problems, "postgres.connMaxLifetime must not be negative")
+	}
+	if config.Postgres.ConnectAttempts <= 0 {
+		problems = append(problems, "postgres.connectAttempts must be positive")
+	}
+	if config.Postgres.ConnectBackoff < 0 {
+		problems = append(problems, "postgres.connectBackoff must not be negative")
+	}
+	return problems
+}
+
+func (config *Config) validateSession() []string {
+	problems := []string{}
+	switch config.Session.Store {
+	case "redis":
+		if !config.Redis.Configured() {
+			problems = append(problems, "redis is required for the redis session store")
+		}
+	case "memory":
+	case "bolt":
+		if config.Session.Path == "" {
+			problems = append(problems, "session.path is required for the bolt session store")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("session.store %q must be redis, memory or bolt", config.Session.Store))
+	}
+	return problems
+}
+
+func (config *Config) validateRedis() []string {
+	problems := []string{}
+	switch config.Redis.Mode {
+	case "standalone":
+		if config.Redis.Host != "" && (config.Redis.Port <= 0 || config.Redis.Port > 65535) {
+			problems = append(problems, fmt.Sprintf("redis.port %d is not a valid port", config.Redis.Port))
+		}
+	case "sentinel":
+		if config.Redis.MasterName == "" {
+			problems = append(problems, "redis.masterName is required in sentinel mode")
+		}
+		if !config.Redis.Configured() {
+			problems = append(problems, "redis.addrs must list the sentinels in sentinel mode")
+		}
+	case "cluster":
+		if !config.Redis.Configured() {
+			problems = append(problems, "redis.addrs must list cluster nodes in cluster mode")
+		}
+		if config.Redis.DB != 0 {
+			problems = append(problems, "redis.db must be 0 in cluster mode")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("redis.mode %q must be standalone, sentinel or cluster", config.Redis.Mode))
+	}
+	if config.Redis.Configured() {
+		if config.Redis.DB < 0 {
+			problems = append(problems, "redis.db must not be negative")
+		}
+		if config.Redis.CAFile != "" && !config.Redis.TLS {
+			problems = append(problems, "redis.caFile is only used with redis.tls")
+		}
+		if config.Redis.PoolSize <= 0 {
+			problems = append(problems, "redis.poolSize must be positive")
+		}
+		if config.Redis.MinIdleConns < 0 || config.Redis.MinIdleConns > config.Redis.PoolSize {
+			problems = append(problems, "redis.minIdleConns must be between 0 and redis.poolSize")
+		}
+		if config.Redis.DialTimeout <= 0 || config.Redis.ReadTimeout <= 0 || config.Redis.WriteTimeout <= 0 || config.Redis.PoolTimeout <= 0 {
+			problems = append(problems, "redis.dialTimeout, readTimeout, writeTimeout and poolTimeout must be positive")
+		}
+		if config.Redis.ConnectAttempts <= 0 {
+			problems = append(problems, "redis.connectAttempts must be positive")
+		}
+		if config.Redis.ConnectBackoff < 0 {
+			problems = append(problems, "redis.connectBackoff must not be negative")
+		}
+	}
+	return problems
+}
+
+func (config *Config) validateAuth() []string {
+	problems := []string{}
+	if config.Auth.AccessTokenSecret == "" {
+		problems = append(problems, "auth.accessTokenSecret (ACCESS_TOKEN_SECRET) is required")
+	}
+	if config.Auth.RefreshTokenSecret == "" {
+		problems = append(problems, "auth.refreshTokenSecret (REFRESH_TOKEN_SECRET) is required")
+	}
+	if config.Auth.AccessTokenTTL <= 0 {
+		problems = append(problems, "auth.accessTokenTTL must be positive")
+	}
+	if config.Auth.RefreshTokenTTL < config.Auth.AccessTokenTTL {
+		problems = append(problems, "auth.refreshTokenTTL must not be shorter than auth.accessTokenTTL")
+	}
+	return problems
+}
+
+func (config *Config) validateTracing() []string {
+	problems := []string{}
+	switch config.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if config.Tracing.Endpoint == "" {
+			problems = append(problems, "tracing.endpoint is required for the otlp exporter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q must be none, otlp or stdout", config.Tracing.Exporter))
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sampleRatio must be between 0 and 1")
+	}
+	return problems
+}
+
+func (config *Config) validateLog() []string {
+	problems := []string{}
+	switch config.Log.Level {
+	case "trace", "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level %q must be debug, info, warn or error", config.Log.Level))
+	}
+	if config.Log.Format != "json" && config.Log.Format != "console" {
+		problems = append(problems, fmt.Sprintf("log.format %q must be json or console", config.Log.Format))
+	}
+	return problems
+}
+
+func (config *Config) validateAudit() []string {
+	problems := []string{}
+	if config.Audit.CheckpointInterval <= 0 {
+		problems = append(problems, "audit.checkpointInterval must be positive")
+	}
+	return problems
+}
+
+func (config *Config) validateWebhook() []string {
+	problems := []string{}
+	if config.Webhook.Workers <= 0 {
+		problems = append(problems, "webhook.workers must be positive")
+	}
+	if config.Webhook.Timeout <= 0 {
+		problems = append(problems, "webhook.timeout must be positive")
+	}
+	if config.Webhook.MaxAttempts <= 0 {
+		problems = append(problems, "webhook.maxAttempts must be positive")
+	}
+	if config.Webhook.BackoffBase <= 0 || config.Webhook.BackoffMax < config.Webhook.BackoffBase {
+		problems = append(problems, "webhook.backoffBase must be positive and not longer than webhook.backoffMax")
+	}
+	if config.Webhook.PollInterval <= 0 {
+		problems = append(problems, "webhook.pollInterval must be positive")
+	}
+	for _, cidr := range strings.Split(config.Webhook.AllowedNetworks, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			problems = append(problems, fmt.Sprintf("webhook.allowedNetworks %q is not a CIDR", cidr))
+		}
+	}
+	return problems
+}
+
+func (config *Config) validateEvents() []string {
+	problems := []string{}
+	if config.Events.Stream == "" {
+		problems = append(problems, "events.stream is required")
+	}
+	if config.Events.MaxLen <= 0 {
+		problems = append(problems, "events.maxLen must be positive")
+	}
+	if config.Events.BatchSize <= 0 {
+		problems = append(problems, "events.batchSize must be positive")
+	}
+	if config.Events.PollInterval <= 0 {
+		problems = append(problems, "events.pollInterval must be positive")
+	}
+	return problems
+}
+
+// Loader registers the config flags on a command's flag set and builds the
+// Config once the flags are parsed.
+type Loader struct {
+	file  string
+	flags map[string]string
+}
+
+func Bind(flags *flag.FlagSet) *Loader {
+	loader := &Loader{flags: map[string]string{}}
+	flags.StringVar(&loader.file, "config", "", "path of a YAML config file, defaults to $CONFIG_FILE")
+	for _, setting := range settings(Default()) {
+		if setting.flag == "" {
+			continue
+		}
+		flags.Var(&settingFlag{loader: loader, setting: setting}, setting.flag, setting.usage)
+	}
+	return loader
+}
+
+// Load reads a .env file when there is one, applies every source in order of
+// precedence and validates sections of the result, every section when none
+// is given.
+func (loader *Loader) Load(sections ...Section) (*Config, error) {
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	config := Default()
+	file := loader.file
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(raw))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("parsing config file %s: %w", file, err)
+		}
+	}
+
+	for _, setting := range settings(config) {
+		value, ok := os.LookupEnv(setting.env)
+		if setting.env == "" || !ok {
+			continue
+		}
+		err := setValue(setting.value, value)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", setting.env, err)
+		}
+	}
+
+	for _, setting := range settings(config) {
+		value, ok := loader.flags[setting.flag]
+		if setting.flag == "" || !ok {
+			continue
+		}
+		err := setValue(setting.value, value)
+		if err != nil {
+			return nil, fmt.Errorf("flag -%s: %w", setting.flag, err)
+		}
+	}
+
+	err = config.Validate(sections...)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// settingFlag records the raw flag value so Load can apply it after the file
+// and environment, it only parses the value to reject typos early.
+type settingFlag struct {
+	loader  *Loader
+	setting setting
+}
+
+func (settingFlag *settingFlag) String() string {
+	if settingFlag == nil || !settingFlag.setting.value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(settingFlag.setting.value.Interface())
+}
+
+func (settingFlag *settingFlag) Set(value string) error {
+	err := setValue(reflect.New(settingFlag.setting.value.Type()).Elem(), value)
+	if err != nil {
+		return err
+	}
+	settingFlag.loader.flags[settingFlag.setting.flag] = value
+	return nil
+}
+
+func (settingFlag *settingFlag) IsBoolFlag() bool {
+	return settingFlag.setting.value.Kind() == reflect.Bool
+}
+
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value reflect.Value
+}
+
+// settings walks the sections of config and returns one addressable value per
+// leaf field together with its env and flag names.
+func settings(config *Config) []setting {
+	result := make([]setting, 0)
+	root := reflect.ValueOf(config).Elem()
+	for index := 0; index < root.NumField(); index++ {
+		section := root.Field(index)
+		for fieldIndex := 0; fieldIndex < section.NumField(); fieldIndex++ {
+			field := section.Type().Field(fieldIndex)
+			result = append(result, setting{
+				env:   field.Tag.Get("env"),
+				flag:  field.Tag.Get("flag"),
+				usage: field.Tag.Get("usage"),
+				value: section.Field(fieldIndex),
+			})
+		}
+	}
+	return result
+}
+
+func setValue(target reflect.Value, value string) error {
+	if target.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		target.SetInt(int64(duration))
+		return nil
+	}
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		target.SetInt(int64(number))
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		target.SetFloat(number)
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		target.SetBool(boolean)
+	default:
+		return fmt.Errorf("unsupported setting type %s", target.Type())
+	}
+	return nil
+}
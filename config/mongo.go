@@ -2,26 +2,133 @@ package config
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"strconv"
+	"strings"
 
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
-var MongoClient *mongo.Client
-
-func Mongo() (*mongo.Client, error) {
-	err := godotenv.Load(".env")
+// Mongo connects with the configured uri or settings and pings until MongoDB
+// answers or the connect attempts run out, every monitor sees each command.
+func Mongo(config MongoConfig, monitors ...*event.CommandMonitor) (*mongo.Client, error) {
+	clientOptions, err := mongoOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(monitors) > 0 {
+		clientOptions.SetMonitor(combineMonitors(monitors))
+	}
+	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
 		return nil, err
 	}
-	uri := "mongodb://" + os.Getenv("MONGO_USERNAME") + ":" + os.Getenv("MONGO_PASSWORD") + "@" + os.Getenv("MONGO_HOST") + ":" + os.Getenv("MONGO_PORT") + "/default?authSource=" + os.Getenv("MONGO_AUTH_SOURCE")
-	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+	err = pingWithRetry("mongo", config.ConnectAttempts, config.ConnectBackoff, func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	})
 	if err != nil {
+		client.Disconnect(context.Background())
 		return nil, err
 	}
-	MongoClient = client
 	return client, nil
 }
+
+// mongoOptions builds the client options from the settings, credentials are
+// passed as they are instead of being escaped into a URI.
+func mongoOptions(config MongoConfig) (*options.ClientOptions, error) {
+	if config.URI != "" {
+		clientOptions := options.Client().ApplyURI(config.URI)
+		return clientOptions, clientOptions.Validate()
+	}
+
+	hosts := make([]string, 0)
+	for _, host := range strings.Split(config.Host, ",") {
+		host = strings.TrimSpace(host)
+		if host != "" && !strings.Contains(host, ":") {
+			host = fmt.Sprintf("%s:%d", host, config.Port)
+		}
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	mode, err := readpref.ModeFromString(config.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	readPreference, err := readpref.New(mode)
+	if err != nil {
+		return nil, err
+	}
+	writeConcern, err := mongoWriteConcern(config.WriteConcern)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().
+		SetHosts(hosts).
+		SetAppName(config.AppName).
+		SetReadPreference(readPreference).
+		SetWriteConcern(writeConcern).
+		SetMaxPoolSize(uint64(config.MaxPoolSize)).
+		SetMinPoolSize(uint64(config.MinPoolSize))
+	if config.Username != "" {
+		clientOptions.SetAuth(options.Credential{
+			AuthSource: config.AuthSource,
+			Username:   config.Username,
+			Password:   config.Password,
+		})
+	}
+	if config.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(config.ReplicaSet)
+	}
+	if config.TLS {
+		tlsConfig, err := clientTLS("mongo", config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+	return clientOptions, clientOptions.Validate()
+}
+
+// mongoWriteConcern accepts majority or a number of acknowledging members.
+func mongoWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if value == "majority" {
+		return writeconcern.New(writeconcern.WMajority()), nil
+	}
+	members, err := strconv.Atoi(value)
+	if err != nil || members < 0 {
+		return nil, fmt.Errorf("mongo.writeConcern %q must be majority or a number of members", value)
+	}
+	return writeconcern.New(writeconcern.W(members)), nil
+}
+
+func combineMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, started)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, succeeded *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, succeeded)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, failed *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, failed)
+				}
+			}
+		},
+	}
+}
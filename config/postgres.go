@@ -0,0 +1,26 @@
+package config
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// Postgres opens the connection pool of the postgres storage backend and
+// pings until the server answers or the configured attempts are used up.
+func Postgres(config PostgresConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.URL)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	err = pingWithRetry("postgres", config.ConnectAttempts, config.ConnectBackoff, db.PingContext)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -1,29 +1,78 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"context"
+	"fmt"
+	"strings"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/joho/godotenv"
 )
 
-var RedisClient *redis.Client
+// Redis connects in the configured mode and pings until Redis answers or
+// the connect attempts run out.
+func Redis(config RedisConfig) (redis.UniversalClient, error) {
+	options := &redis.UniversalOptions{
+		Addrs:            config.Addresses(),
+		DB:               config.DB,
+		Username:         config.Username,
+		Password:         config.Password,
+		SentinelPassword: config.SentinelPassword,
+		MasterName:       config.MasterName,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		DialTimeout:      config.DialTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		PoolTimeout:      config.PoolTimeout,
+	}
+	if config.TLS {
+		tlsConfig, err := clientTLS("redis", config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		options.TLSConfig = tlsConfig
+	}
 
-func Redis() (*redis.Client, error) {
-	err := godotenv.Load(".env")
-	if err != nil {
-		return nil, err
+	var client redis.UniversalClient
+	switch config.Mode {
+	case "sentinel":
+		client = redis.NewFailoverClient(options.Failover())
+	case "cluster":
+		client = redis.NewClusterClient(options.Cluster())
+	default:
+		client = redis.NewClient(options.Simple())
 	}
-	intPort, err := strconv.Atoi(os.Getenv("REDIS_DB"))
+	err := pingWithRetry("redis", config.ConnectAttempts, config.ConnectBackoff, func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
-	client := redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
-		DB:       intPort,
-		Password: os.Getenv("REDIS_PASSWORD"),
-	})
-	RedisClient = client
 	return client, nil
 }
+
+// Configured reports whether a Redis server is set up at all, the service
+// runs without one when sessions are kept elsewhere.
+func (config RedisConfig) Configured() bool {
+	return len(config.Addresses()) > 0
+}
+
+// Addresses returns host:port in standalone mode and the sentinel or cluster
+// nodes otherwise.
+func (config RedisConfig) Addresses() []string {
+	if config.Mode == "sentinel" || config.Mode == "cluster" {
+		addresses := make([]string, 0)
+		for _, address := range strings.Split(config.Addrs, ",") {
+			address = strings.TrimSpace(address)
+			if address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+		return addresses
+	}
+	if config.Host == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s:%d", config.Host, config.Port)}
+}
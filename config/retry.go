@@ -0,0 +1,30 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PING_TIMEOUT bounds each startup ping of a dependency.
+const PING_TIMEOUT = time.Second * 5
+
+// pingWithRetry pings a dependency up to attempts times, waiting backoff
+// after the first failure and twice as long after every further one, so a
+// service started together with its databases does not give up right away.
+func pingWithRetry(name string, attempts int, backoff time.Duration, ping func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), PING_TIMEOUT)
+		err = ping(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("%s ping failed %d times: %w", name, attempts, err)
+}
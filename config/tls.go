@@ -0,0 +1,27 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// clientTLS trusts the CAs of caFile, the system pool when it is empty. name
+// tells which connection the file is for in errors.
+func clientTLS(name string, caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s CA file: %w", name, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s CA file %s holds no PEM certificate", name, caFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -2,142 +2,250 @@ package controllers
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/hex"
+	"errors"
+	"fmt"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/config"
+	"ima-svc-management/events"
 	"ima-svc-management/helpers"
 	"ima-svc-management/model"
-	"log"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
 	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AccountController struct {
-	MongoClient *mongo.Client
+	Accounts      repository.AccountRepository
+	Roles         repository.RoleRepository
+	Organizations repository.OrganizationRepository
+	Attributes    repository.AttributeSchemaRepository
+	Avatars       repository.AvatarStore
+	Auditor       *audit.Auditor
+	Webhooks      *webhook.Dispatcher
+	Outbox        *events.Outbox
+	Compat        config.CompatConfig
 }
 
-func InitAccount(mongoClient *mongo.Client) *AccountController {
+func InitAccount(accounts repository.AccountRepository, roles repository.RoleRepository, organizations repository.OrganizationRepository, attributes repository.AttributeSchemaRepository, avatars repository.AvatarStore, auditor *audit.Auditor, webhooks *webhook.Dispatcher, outbox *events.Outbox, compat config.CompatConfig) *AccountController {
 	return &AccountController{
-		MongoClient: mongoClient,
+		Accounts:      accounts,
+		Roles:         roles,
+		Organizations: organizations,
+		Attributes:    attributes,
+		Avatars:       avatars,
+		Auditor:       auditor,
+		Webhooks:      webhooks,
+		Outbox:        outbox,
+		Compat:        compat,
 	}
 }
 
 // @Summary Add account
-// @Description create new account
-// @Param body body model.AccountModel true "body"
+// @Description create new account in the organization of the caller, organization admins only. Platform superadmins name the organization with orgId.
+// @Param body body model.AccountCreateModel true "body"
 // @Tags Account
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/add [post]
+// @Security BearerAuth
 func (accountController AccountController) AddAccount(c *gin.Context) {
 
-	collection := accountController.MongoClient.Database("test").Collection("account")
-
-	account := model.AccountModel{}
-	err := c.BindJSON(&account)
+	request := model.AccountCreateModel{}
+	err := c.ShouldBindJSON(&request)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
-		c.Abort()
+		validation.Abort(c, err)
 		return
 	}
+	account := request.Model()
 
-	dataAccount := bson.M{
-		"name":      account.Name,
-		"email":     account.Email,
-		"password":  helpers.GeneratePasswordHash([]byte(account.Password)),
-		"role":      account.Role,
-		"createdAt": time.Now().Unix(),
-		"updatedAt": nil,
+	account.OrgId, err = helpers.TenantOrgId(c, request.OrgId)
+	if err != nil {
+		apierror.Abort(c, apierror.ORG_ID_REQUIRED)
+		return
+	}
+	if account.Role == string(model.PLATFORM_SUPERADMIN) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
 	}
 
-	hashId, err := bson.Marshal(dataAccount)
+	id, err := accountController.CreateAccount(c.Request.Context(), account)
+	if validation.Failures(err) != nil {
+		validation.Abort(c, err)
+		return
+	}
+	if err == ErrOrganizationNotFound {
+		apierror.Abort(c, apierror.ORGANIZATION_NOT_FOUND)
+		return
+	}
+	if errors.Is(err, ErrInvalidAttributes) {
+		apierror.AbortWithReason(c, apierror.INVALID_ATTRIBUTES, err)
+		return
+	}
+	if err == ErrEmailRegistered {
+		apierror.Abort(c, apierror.EMAIL_REGISTERED)
+		return
+	}
+	if err == ErrRoleNotFound {
+		apierror.Abort(c, apierror.ROLE_UNDEFINED)
+		return
+	}
 	if err != nil {
-		log.Fatal(err)
+		apierror.Internal(c, err)
+		return
 	}
-	hash := md5.Sum(hashId)
+	account.Id = id
+	accountController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   account.OrgId,
+		Action:  model.AUDIT_ACCOUNT_CREATE,
+		Target:  accountTarget(account),
+		Changes: audit.Diff(nil, account),
+	})
+	accountController.Webhooks.Emit(c, account.OrgId, model.WEBHOOK_ACCOUNT_CREATED, accountEvent(account))
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Create account successful"})
 
-	dataAccount["_id"] = hex.EncodeToString(hash[:])
+}
 
-	registeredAccount := model.AccountModel{}
-	filter := bson.M{"email": account.Email}
-	err = collection.FindOne(context.TODO(), filter).Decode(&registeredAccount)
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"message": "Email already registered"})
-		c.Abort()
-		return
+// CreateAccount validates and stores a new account and returns its id. An
+// account without organization is only allowed for platform superadmins.
+func (accountController AccountController) CreateAccount(ctx context.Context, account model.AccountModel) (string, error) {
+	if account.OrgId == "" && account.Role != string(model.PLATFORM_SUPERADMIN) {
+		return "", helpers.ErrOrganizationRequired
 	}
 
-	_, err = collection.InsertOne(context.Background(), dataAccount)
+	schemas := make([]model.AttributeSchemaModel, 0)
+	if account.OrgId != "" {
+		_, err := accountController.Organizations.FindById(ctx, account.OrgId)
+		if err == repository.ErrNotFound {
+			return "", ErrOrganizationNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		schemas, err = accountController.Attributes.FindByOrganization(ctx, account.OrgId)
+		if err != nil {
+			return "", err
+		}
+		err = validation.RoleExists(ctx, accountController.Roles, account.OrgId, "role", account.Role)
+		if err != nil {
+			return "", err
+		}
+	}
+	err := helpers.ValidateAttributes(schemas, account.Attributes)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
-		return
+		return "", fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Create account successful"})
 
+	account.Id = helpers.GenerateId()
+	account.Email = strings.TrimSpace(account.Email)
+	account.EmailNormalized = helpers.NormalizeEmail(account.Email)
+	account.Password = helpers.GeneratePasswordHash([]byte(account.Password))
+	account.Version = 1
+	account.CreatedAt = time.Now().Unix()
+	account.UpdatedAt = 0
+
+	err = accountController.Outbox.Transaction(ctx, func(ctx context.Context) error {
+		err := accountController.Accounts.Create(ctx, &account)
+		if err != nil {
+			return err
+		}
+		return accountController.Outbox.Add(ctx, model.EVENT_ACCOUNT_CREATED, account.OrgId, account.Id, accountEvent(account))
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return "", ErrEmailRegistered
+	}
+	if errors.Is(err, repository.ErrReference) {
+		return "", ErrRoleNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return account.Id, nil
+}
+
+// ResetPassword replaces the password of the account registered with email in
+// organization orgId, an empty orgId addresses platform superadmins.
+func (accountController AccountController) ResetPassword(ctx context.Context, orgId string, email string, password string) (*model.AccountModel, error) {
+	hash := helpers.GeneratePasswordHash([]byte(password))
+	change := repository.AccountChange{Password: &hash, UpdatedAt: time.Now().Unix()}
+
+	account, err := accountController.publishUpdate(ctx, func(ctx context.Context) (*model.AccountModel, *model.AccountModel, error) {
+		return accountController.Accounts.UpdateByEmail(ctx, repository.OrganizationScope(orgId), helpers.NormalizeEmail(email), change)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
 }
 
 // @Summary Get all account
-// @Description get all account with pagination
-// @Param body body model.PaginateRoleModel true "body"
+// @Description get all account with pagination, optionally filtered by custom attributes
+// @Param body body model.PaginateAccountModel true "body"
 // @Tags Account
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} object{status=string,datas=[]model.AccountModel} "ok"
+// @Success 200 {object} object{status=string,data=[]model.AccountResponseModel} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/getAll [post]
 // @Security BearerAuth
 func (accountController AccountController) GetAccount(c *gin.Context) {
 
 	paginationModel := model.PaginateAccountModel{}
 
-	err := c.BindJSON(&paginationModel)
+	err := c.ShouldBindJSON(&paginationModel)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		validation.Abort(c, err)
 		return
 	}
-	collection := accountController.MongoClient.Database("test").Collection("account")
 
-	pageOptions := options.Find()
-	pageOptions.SetLimit(int64(paginationModel.Size))
-	if paginationModel.Order != "" && paginationModel.OrderBy != "" {
-		if paginationModel.Order == "asc" {
-			pageOptions.SetSort(bson.M{paginationModel.OrderBy: 1})
-		} else {
-			pageOptions.SetSort(bson.M{paginationModel.OrderBy: -1})
+	if len(paginationModel.Attributes) > 0 {
+		orgId, err := helpers.TenantOrgId(c, "")
+		if err != nil {
+			apierror.Abort(c, apierror.ORG_ID_REQUIRED)
+			return
+		}
+		schemas, err := accountController.Attributes.FindByOrganization(c.Request.Context(), orgId)
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+		err = helpers.ValidateAttributeFilter(schemas, paginationModel.Attributes)
+		if err != nil {
+			apierror.AbortWithReason(c, apierror.INVALID_ATTRIBUTES, err)
+			return
 		}
 	}
-	pageOptions.SetSkip(int64(paginationModel.Page))
 
-	cursor, err := collection.Find(context.TODO(), bson.D{{}}, pageOptions)
+	accounts, err := accountController.Accounts.List(c.Request.Context(), helpers.TenantScope(c), repository.AccountQuery{
+		Page: repository.Page{
+			Order:   paginationModel.Order,
+			OrderBy: paginationModel.OrderBy,
+			Skip:    paginationModel.Page,
+			Size:    pageSize(paginationModel.Size),
+		},
+		Attributes: paginationModel.Attributes,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Internal(c, err)
 		return
 	}
 
-	datas := make([]map[string]interface{}, 0)
-	for cursor.Next(context.TODO()) {
-		account := model.AccountModel{}
-		if err := cursor.Decode(&account); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-			c.Abort()
-			return
-		}
-		data := map[string]interface{}{
-			"id":         account.Id,
-			"name":       account.Name,
-			"email":      account.Email,
-			"role":       account.Role,
-			"created_at": account.CreatedAt,
-			"updated_at": account.UpdatedAt,
-		}
-		datas = append(datas, data)
+	datas := make([]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		datas = append(datas, accountController.accountData(account))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
@@ -150,37 +258,23 @@ func (accountController AccountController) GetAccount(c *gin.Context) {
 // @Tags Account
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} object{status=string,datas=[]model.AccountModel} "ok"
+// @Success 200 {object} object{status=string,data=[]model.AccountResponseModel} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/getByEmail [get]
 // @Security BearerAuth
 func (accountController AccountController) GetAccountByEmail(c *gin.Context) {
 
-	account := model.AccountModel{}
-
 	email := c.Query("email")
 
-	collection := accountController.MongoClient.Database("test").Collection("account")
-
-	filter := bson.M{"email": email}
-
-	err := collection.FindOne(context.TODO(), filter).Decode(&account)
+	account, err := accountController.Accounts.FindByEmail(c.Request.Context(), helpers.TenantScope(c), helpers.NormalizeEmail(email))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
 		return
 	}
 
-	datas := make([]map[string]interface{}, 0)
-	data := map[string]interface{}{
-		"id":         account.Id,
-		"name":       account.Name,
-		"email":      account.Email,
-		"role":       account.Role,
-		"created_at": account.CreatedAt,
-		"updated_at": account.UpdatedAt,
-	}
-	datas = append(datas, data)
+	datas := []interface{}{accountController.accountData(*account)}
 
+	c.Header("ETag", helpers.VersionETag(account.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
 }
 
@@ -190,108 +284,482 @@ func (accountController AccountController) GetAccountByEmail(c *gin.Context) {
 // @Tags Account
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} object{status=string,datas=[]model.AccountModel} "ok"
+// @Success 200 {object} object{status=string,data=[]model.AccountResponseModel} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/getById [get]
 // @Security BearerAuth
 func (accountController AccountController) GetAccountById(c *gin.Context) {
 
-	account := model.AccountModel{}
-
 	id := c.Query("id")
 
-	collection := accountController.MongoClient.Database("test").Collection("account")
-
-	filter := bson.M{"_id": id}
-
-	err := collection.FindOne(context.TODO(), filter).Decode(&account)
+	account, err := accountController.Accounts.FindById(c.Request.Context(), helpers.TenantScope(c), id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
 		return
 	}
 
-	datas := make([]map[string]interface{}, 0)
-	data := map[string]interface{}{
-		"id":         account.Id,
-		"name":       account.Name,
-		"email":      account.Email,
-		"role":       account.Role,
-		"created_at": account.CreatedAt,
-		"updated_at": account.UpdatedAt,
-	}
-	datas = append(datas, data)
+	datas := []interface{}{accountController.accountData(*account)}
 
+	c.Header("ETag", helpers.VersionETag(account.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
 }
 
 // @Summary Update account
-// @Description Update account
-// @Param body body model.AccountModel true "body"
+// @Description Update account, send If-Match or version to reject the update when the account was changed meanwhile. Members may only update their own account without changing its role.
+// @Param body body model.AccountUpdateModel true "body"
+// @Param If-Match header string false "ETag of the account being updated"
 // @Tags Account
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 409 {object} model.ErrorModel{details=model.AccountResponseModel} "version conflict"
+// @Failure 412 {object} model.ErrorModel{details=model.AccountResponseModel} "If-Match does not match"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/update [put]
 // @Security BearerAuth
 func (accountController AccountController) UpdateAccount(c *gin.Context) {
 
-	collection := accountController.MongoClient.Database("test").Collection("account")
+	account := model.AccountUpdateModel{}
+	err := c.ShouldBindJSON(&account)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	if account.Role == string(model.PLATFORM_SUPERADMIN) && !helpers.IsPlatformSuperadmin(c) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
+	}
+
+	precondition, err := helpers.ParsePrecondition(c, account.Version)
+	if err != nil {
+		apierror.Abort(c, apierror.INVALID_IF_MATCH)
+		return
+	}
 
-	account := model.AccountModel{}
-	err := c.BindJSON(&account)
+	scope := helpers.TenantScope(c)
+	currentAccount, err := accountController.Accounts.FindById(c.Request.Context(), scope, account.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
+		return
+	}
+	if !mayEditAccount(c, *currentAccount, account.Role != "" && account.Role != currentAccount.Role) {
+		apierror.Abort(c, apierror.FORBIDDEN)
+		return
+	}
+	if precondition != nil && precondition.Version != currentAccount.Version {
+		accountController.accountConflict(c, precondition.Status, *currentAccount)
 		return
 	}
 
-	filter := bson.M{"_id": account.Id}
-	updateAccount := bson.M{
-		"updatedAt": time.Now().Unix(),
+	change := repository.AccountChange{
+		UpdatedAt: time.Now().Unix(),
 	}
-	if account.Email != "" {
-		updateAccount["email"] = account.Email
+	if account.Email != "" && account.Email != currentAccount.Email {
+		email := strings.TrimSpace(account.Email)
+		emailNormalized := helpers.NormalizeEmail(account.Email)
+		change.Email = &email
+		change.EmailNormalized = &emailNormalized
 	}
 	if account.Name != "" {
-		updateAccount["name"] = account.Name
+		change.Name = &account.Name
 	}
 	if account.Password != "" {
-		updateAccount["password"] = helpers.GeneratePasswordHash([]byte(account.Password))
+		hash := helpers.GeneratePasswordHash([]byte(account.Password))
+		change.Password = &hash
 	}
 	if account.Role != "" {
-		updateAccount["role"] = account.Role
+		err = validation.RoleExists(c.Request.Context(), accountController.Roles, currentAccount.OrgId, "role", account.Role)
+		if validation.Failures(err) != nil {
+			validation.Abort(c, err)
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+		change.Role = &account.Role
+	}
+	if account.Attributes != nil {
+		attributes := make(map[string]interface{}, len(currentAccount.Attributes)+len(account.Attributes))
+		for name, value := range currentAccount.Attributes {
+			attributes[name] = value
+		}
+		for name, value := range account.Attributes {
+			attributes[name] = value
+		}
+		schemas, err := accountController.Attributes.FindByOrganization(c.Request.Context(), currentAccount.OrgId)
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+		err = helpers.ValidateAttributes(schemas, attributes)
+		if err != nil {
+			apierror.AbortWithReason(c, apierror.INVALID_ATTRIBUTES, err)
+			return
+		}
+		change.Attributes = attributes
+		change.ReplaceAttributes = true
 	}
-	update := bson.M{"$set": updateAccount}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	var version *int64
+	if precondition != nil {
+		version = &precondition.Version
+	}
+	updatedAccount, err := accountController.publishUpdate(c.Request.Context(), func(ctx context.Context) (*model.AccountModel, *model.AccountModel, error) {
+		return accountController.Accounts.Update(ctx, scope, currentAccount.Id, version, change)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.EMAIL_REGISTERED)
+		return
+	}
+	if errors.Is(err, repository.ErrReference) {
+		apierror.Abort(c, apierror.ROLE_UNDEFINED)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) && precondition != nil {
+		currentAccount, err = accountController.Accounts.FindById(c.Request.Context(), scope, currentAccount.Id)
+		if err == nil {
+			accountController.accountConflict(c, precondition.Status, *currentAccount)
+			return
+		}
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
 		return
 	}
+	accountController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   updatedAccount.OrgId,
+		Action:  model.AUDIT_ACCOUNT_UPDATE,
+		Target:  accountTarget(*updatedAccount),
+		Changes: audit.Diff(*currentAccount, *updatedAccount),
+	})
+	accountController.emitUpdated(c, *currentAccount, *updatedAccount)
+	c.Header("ETag", helpers.VersionETag(updatedAccount.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Update account successful"})
 
 }
 
+// @Summary Patch account
+// @Description Partially update account with a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json) document. Patchable fields are name, email, role, password and attributes, a null attribute removes it. Nothing is written when the patch does not change the account. Members may only patch their own account without changing its role.
+// @Param id query string true "id"
+// @Param body body object true "merge patch document or array of json patch operations"
+// @Param If-Match header string false "ETag of the account being patched"
+// @Tags Account
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=model.AccountResponseModel} "ok"
+// @Failure 409 {object} model.ErrorModel{details=model.AccountResponseModel} "version conflict"
+// @Failure 412 {object} model.ErrorModel{details=model.AccountResponseModel} "If-Match does not match"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid patch or fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/account/patch [patch]
+// @Security BearerAuth
+func (accountController AccountController) PatchAccount(c *gin.Context) {
+	precondition, err := helpers.ParsePrecondition(c, 0)
+	if err != nil {
+		apierror.Abort(c, apierror.INVALID_IF_MATCH)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_REQUEST, err)
+		return
+	}
+
+	scope := helpers.TenantScope(c)
+	currentAccount, err := accountController.Accounts.FindById(c.Request.Context(), scope, c.Query("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.ACCOUNT_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if precondition != nil && precondition.Version != currentAccount.Version {
+		accountController.accountConflict(c, precondition.Status, *currentAccount)
+		return
+	}
+
+	document, err := helpers.ToPatchDocument(map[string]interface{}{
+		"name":       currentAccount.Name,
+		"email":      currentAccount.Email,
+		"role":       currentAccount.Role,
+		"attributes": currentAccount.Attributes,
+	})
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if document["attributes"] == nil {
+		delete(document, "attributes")
+	}
+
+	patched, err := helpers.ApplyPatch(c.ContentType(), document, body)
+	if errors.Is(err, helpers.ErrPatchTest) {
+		apierror.Abort(c, apierror.PATCH_TEST_FAILED)
+		return
+	}
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_PATCH, err)
+		return
+	}
+
+	schemas, err := accountController.Attributes.FindByOrganization(c.Request.Context(), currentAccount.OrgId)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	err = validateAccountPatch(patched)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	attributes, _ := patched["attributes"].(map[string]interface{})
+	err = helpers.ValidateAttributes(schemas, attributes)
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_ATTRIBUTES, err)
+		return
+	}
+	password, _ := patched["password"].(string)
+	err = validation.Struct(model.AccountUpdateModel{
+		Id:       currentAccount.Id,
+		Name:     patched["name"].(string),
+		Email:    patched["email"].(string),
+		Role:     patched["role"].(string),
+		Password: password,
+	})
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	change := repository.AccountChange{}
+	changed := false
+	if name := patched["name"]; name != document["name"] {
+		name := name.(string)
+		change.Name = &name
+		changed = true
+	}
+	if email := patched["email"]; email != document["email"] {
+		email := strings.TrimSpace(email.(string))
+		emailNormalized := helpers.NormalizeEmail(email)
+		change.Email = &email
+		change.EmailNormalized = &emailNormalized
+		changed = true
+	}
+	if role := patched["role"]; role != document["role"] {
+		role := role.(string)
+		change.Role = &role
+		changed = true
+	}
+	if password, ok := patched["password"]; ok {
+		hash := helpers.GeneratePasswordHash([]byte(password.(string)))
+		change.Password = &hash
+		changed = true
+	}
+	if !reflect.DeepEqual(patched["attributes"], document["attributes"]) {
+		change.Attributes, _ = patched["attributes"].(map[string]interface{})
+		change.ReplaceAttributes = true
+		changed = true
+	}
+
+	if !changed {
+		c.Header("ETag", helpers.VersionETag(currentAccount.Version))
+		c.JSON(http.StatusOK, gin.H{"status": "OK", "data": accountController.accountData(*currentAccount)})
+		return
+	}
+
+	if !mayEditAccount(c, *currentAccount, change.Role != nil) {
+		apierror.Abort(c, apierror.FORBIDDEN)
+		return
+	}
+	if change.Role != nil && *change.Role == string(model.PLATFORM_SUPERADMIN) && !helpers.IsPlatformSuperadmin(c) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
+	}
+	if change.Role != nil {
+		err = validation.RoleExists(c.Request.Context(), accountController.Roles, currentAccount.OrgId, "role", *change.Role)
+		if validation.Failures(err) != nil {
+			validation.Abort(c, err)
+			return
+		}
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+	}
+
+	change.UpdatedAt = time.Now().Unix()
+	updatedAccount, err := accountController.publishUpdate(c.Request.Context(), func(ctx context.Context) (*model.AccountModel, *model.AccountModel, error) {
+		return accountController.Accounts.Update(ctx, scope, currentAccount.Id, &currentAccount.Version, change)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.EMAIL_REGISTERED)
+		return
+	}
+	if errors.Is(err, repository.ErrReference) {
+		apierror.Abort(c, apierror.ROLE_UNDEFINED)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		currentAccount, err = accountController.Accounts.FindById(c.Request.Context(), scope, currentAccount.Id)
+		if err == nil {
+			status := http.StatusConflict
+			if precondition != nil {
+				status = precondition.Status
+			}
+			accountController.accountConflict(c, status, *currentAccount)
+			return
+		}
+	}
+	if err != nil {
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
+		return
+	}
+	accountController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   updatedAccount.OrgId,
+		Action:  model.AUDIT_ACCOUNT_UPDATE,
+		Target:  accountTarget(*updatedAccount),
+		Changes: audit.Diff(*currentAccount, *updatedAccount),
+	})
+	accountController.emitUpdated(c, *currentAccount, *updatedAccount)
+	c.Header("ETag", helpers.VersionETag(updatedAccount.Version))
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": accountController.accountData(*updatedAccount)})
+}
+
 // @Summary Delete account by id
-// @Description delete account using id
+// @Description delete account using id together with its avatar, organization admins only
 // @Param id query string true "id"
 // @Tags Account
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/account/delete [delete]
 // @Security BearerAuth
 func (accountController AccountController) DeleteAccount(c *gin.Context) {
 	id := c.Query("id")
-	collection := accountController.MongoClient.Database("test").Collection("account")
+	scope := helpers.TenantScope(c)
 
-	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": id})
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+	deletedAccount := model.AccountModel{}
+	err := accountController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		account, err := accountController.Accounts.Delete(ctx, scope, id)
+		if err != nil {
+			return err
+		}
+		deletedAccount = *account
+		return accountController.Outbox.Add(ctx, model.EVENT_ACCOUNT_DELETED, deletedAccount.OrgId, deletedAccount.Id, accountEvent(deletedAccount))
+	})
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Internal(c, err)
 		return
 	}
+	if err == nil {
+		err = accountController.Avatars.Delete(c.Request.Context(), id)
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+		accountController.Auditor.Record(c, model.AuditEventModel{
+			OrgId:   deletedAccount.OrgId,
+			Action:  model.AUDIT_ACCOUNT_DELETE,
+			Target:  accountTarget(deletedAccount),
+			Changes: audit.Diff(deletedAccount, nil),
+		})
+		accountController.Webhooks.Emit(c, deletedAccount.OrgId, model.WEBHOOK_ACCOUNT_DELETED, accountEvent(deletedAccount))
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete account successful"})
 
 }
+
+// CountAccountsByRole returns the number of accounts of every role across
+// all organizations.
+func (accountController AccountController) CountAccountsByRole(ctx context.Context) (map[string]int64, error) {
+	return accountController.Accounts.CountByRole(ctx)
+}
+
+// publishUpdate runs update and writes the account.updated event of the
+// updated account in the same transaction.
+func (accountController AccountController) publishUpdate(ctx context.Context, update func(ctx context.Context) (*model.AccountModel, *model.AccountModel, error)) (*model.AccountModel, error) {
+	var updatedAccount *model.AccountModel
+	err := accountController.Outbox.Transaction(ctx, func(ctx context.Context) error {
+		_, after, err := update(ctx)
+		if err != nil {
+			return err
+		}
+		updatedAccount = after
+		return accountController.Outbox.Add(ctx, model.EVENT_ACCOUNT_UPDATED, after.OrgId, after.Id, accountEvent(*after))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedAccount, nil
+}
+
+// accountConflict reports a failed conditional update together with the
+// current server state so the client can merge and retry.
+func (accountController AccountController) accountConflict(c *gin.Context, status int, account model.AccountModel) {
+	c.Header("ETag", helpers.VersionETag(account.Version))
+	apierror.AbortWithDetails(c, conflictCode(status), accountController.accountData(account))
+}
+
+// emitUpdated notifies webhooks of an account change, a changed role is also
+// announced on its own so subscribers can react to privilege changes alone.
+func (accountController AccountController) emitUpdated(c *gin.Context, before model.AccountModel, after model.AccountModel) {
+	accountController.Webhooks.Emit(c, after.OrgId, model.WEBHOOK_ACCOUNT_UPDATED, accountEvent(after))
+	if before.Role != after.Role {
+		accountController.Webhooks.Emit(c, after.OrgId, model.WEBHOOK_ACCOUNT_ROLE_CHANGED, gin.H{"account": accountEvent(after), "previousRole": before.Role})
+	}
+}
+
+// mayEditAccount allows organization admins to change any account of their
+// organization, members only their own and never its role.
+func mayEditAccount(c *gin.Context, account model.AccountModel, roleChanged bool) bool {
+	if helpers.IsOrganizationAdmin(c) {
+		return true
+	}
+	return !roleChanged && helpers.IsCaller(c, account.OrgId, account.Email)
+}
+
+func accountTarget(account model.AccountModel) model.AuditTargetModel {
+	return model.AuditTargetModel{Type: "account", Id: account.Id, Email: account.Email}
+}
+
+// accountData is an account as responses show it, in the v1 shape while
+// compat.v1Responses is set.
+func (accountController AccountController) accountData(account model.AccountModel) interface{} {
+	if accountController.Compat.V1Responses {
+		return model.NewAccountV1Response(account)
+	}
+	return model.NewAccountResponse(account)
+}
+
+// accountEvent is an account as webhooks and events carry it, always the
+// current shape whatever compat.v1Responses says.
+func accountEvent(account model.AccountModel) model.AccountResponseModel {
+	return model.NewAccountResponse(account)
+}
+
+// validateAccountPatch checks every field of a patched account document, the
+// patch itself is free-form so nothing else guards these fields.
+func validateAccountPatch(patched map[string]interface{}) error {
+	failures := []validation.Failure{}
+	for field, value := range patched {
+		switch field {
+		case "name", "email", "role", "password":
+			failures = append(failures, patchedText(field, value, true)...)
+		case "attributes":
+			if _, ok := value.(map[string]interface{}); !ok {
+				failures = append(failures, validation.Failure{Field: field, Rule: validation.RULE_TYPE, Param: "object"})
+			}
+		default:
+			failures = append(failures, validation.Failure{Field: field, Rule: validation.RULE_READ_ONLY})
+		}
+	}
+	return patchFailures(patched, failures, "name", "email", "role")
+}
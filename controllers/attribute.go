@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/events"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeController struct {
+	Attributes repository.AttributeSchemaRepository
+	Accounts   repository.AccountRepository
+	Auditor    *audit.Auditor
+	Webhooks   *webhook.Dispatcher
+	Outbox     *events.Outbox
+}
+
+func InitAttribute(attributes repository.AttributeSchemaRepository, accounts repository.AccountRepository, auditor *audit.Auditor, webhooks *webhook.Dispatcher, outbox *events.Outbox) *AttributeController {
+	return &AttributeController{
+		Attributes: attributes,
+		Accounts:   accounts,
+		Auditor:    auditor,
+		Webhooks:   webhooks,
+		Outbox:     outbox,
+	}
+}
+
+// @Summary Add attribute schema
+// @Description define a new custom account attribute for the organization
+// @Param body body model.AttributeSchemaCreateModel true "body"
+// @Tags Attribute
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/attribute/add [post]
+// @Security BearerAuth
+func (attributeController AttributeController) AddAttribute(c *gin.Context) {
+	request := model.AttributeSchemaCreateModel{}
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	attribute := request.Model()
+
+	orgId, err := helpers.TenantOrgId(c, attribute.OrgId)
+	if err != nil {
+		apierror.Abort(c, apierror.ORG_ID_REQUIRED)
+		return
+	}
+
+	attribute.Id = helpers.GenerateId()
+	attribute.OrgId = orgId
+	attribute.CreatedAt = time.Now().Unix()
+	attribute.UpdatedAt = 0
+
+	err = attributeController.Attributes.Create(c.Request.Context(), &attribute)
+	if err == repository.ErrDuplicate {
+		apierror.Abort(c, apierror.ATTRIBUTE_REGISTERED)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	attributeController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   orgId,
+		Action:  model.AUDIT_ATTRIBUTE_CREATE,
+		Target:  attributeTarget(attribute),
+		Changes: audit.Diff(nil, attribute),
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Create attribute successful"})
+}
+
+// @Summary Get all attribute schema
+// @Description get all custom account attribute of the organization with pagination
+// @Param body body model.PaginateAttributeSchemaModel true "body"
+// @Tags Attribute
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.AttributeSchemaResponseModel} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/attribute/getAll [post]
+// @Security BearerAuth
+func (attributeController AttributeController) GetAttribute(c *gin.Context) {
+	paginationModel := model.PaginateAttributeSchemaModel{}
+
+	err := c.ShouldBindJSON(&paginationModel)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	attributes, err := attributeController.Attributes.List(c.Request.Context(), helpers.TenantScope(c), repository.Page{
+		Order:   paginationModel.Order,
+		OrderBy: paginationModel.OrderBy,
+		Skip:    paginationModel.Page,
+		Size:    pageSize(paginationModel.Size),
+	})
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	datas := make([]model.AttributeSchemaResponseModel, 0, len(attributes))
+	for _, attribute := range attributes {
+		datas = append(datas, model.NewAttributeSchemaResponse(attribute))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
+}
+
+// @Summary Update attribute schema
+// @Description replace the definition of a custom account attribute, the name can not be changed
+// @Param body body model.AttributeSchemaUpdateModel true "body"
+// @Tags Attribute
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/attribute/update [put]
+// @Security BearerAuth
+func (attributeController AttributeController) UpdateAttribute(c *gin.Context) {
+	request := model.AttributeSchemaUpdateModel{}
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	previousAttribute, updatedAttribute, err := attributeController.Attributes.Update(c.Request.Context(), helpers.TenantScope(c), request.Id, repository.AttributeSchemaChange{
+		Type:        request.Type,
+		Required:    request.Required,
+		Enum:        request.Enum,
+		Regex:       request.Regex,
+		TokenClaim:  request.TokenClaim,
+		Description: request.Description,
+		UpdatedAt:   time.Now().Unix(),
+	})
+	if err != nil {
+		apierror.Lookup(c, apierror.ATTRIBUTE_NOT_FOUND, err)
+		return
+	}
+	attributeController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   previousAttribute.OrgId,
+		Action:  model.AUDIT_ATTRIBUTE_UPDATE,
+		Target:  attributeTarget(*previousAttribute),
+		Changes: audit.Diff(*previousAttribute, *updatedAttribute),
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Update attribute successful"})
+}
+
+// @Summary Delete attribute schema by id
+// @Description delete custom account attribute using id and remove its value from every account, each changed account is announced as account.updated
+// @Param id query string true "id"
+// @Tags Attribute
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/attribute/delete [delete]
+// @Security BearerAuth
+func (attributeController AttributeController) DeleteAttribute(c *gin.Context) {
+	id := c.Query("id")
+
+	var attribute *model.AttributeSchemaModel
+	var updates []repository.AccountUpdate
+	err := attributeController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		var err error
+		attribute, err = attributeController.Attributes.Delete(ctx, helpers.TenantScope(c), id)
+		if err != nil {
+			return err
+		}
+		updates, err = attributeController.Accounts.UnsetAttribute(ctx, attribute.OrgId, attribute.Name)
+		if err != nil {
+			return err
+		}
+		for _, update := range updates {
+			err = attributeController.Outbox.Add(ctx, model.EVENT_ACCOUNT_UPDATED, update.Updated.OrgId, update.Updated.Id, accountEvent(update.Updated))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		apierror.Lookup(c, apierror.ATTRIBUTE_NOT_FOUND, err)
+		return
+	}
+	attributeController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   attribute.OrgId,
+		Action:  model.AUDIT_ATTRIBUTE_DELETE,
+		Target:  attributeTarget(*attribute),
+		Changes: audit.Diff(*attribute, nil),
+	})
+	for _, update := range updates {
+		attributeController.Webhooks.Emit(c, update.Updated.OrgId, model.WEBHOOK_ACCOUNT_UPDATED, accountEvent(update.Updated))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete attribute successful"})
+}
+
+func attributeTarget(attribute model.AttributeSchemaModel) model.AuditTargetModel {
+	return model.AuditTargetModel{Type: "attribute", Id: attribute.Id}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const AUDIT_DEFAULT_PAGE_SIZE = 20
+
+type AuditController struct {
+	Auditor *audit.Auditor
+}
+
+func InitAudit(auditor *audit.Auditor) *AuditController {
+	return &AuditController{
+		Auditor: auditor,
+	}
+}
+
+// @Summary Get audit events
+// @Description list audit events of the caller's organization, newest first. Platform superadmins see every organization unless orgId is given.
+// @Param action query string false "action, e.g. account.update"
+// @Param actorEmail query string false "email of the acting account"
+// @Param targetType query string false "account, role, organization, attribute or session"
+// @Param targetId query string false "id of the target"
+// @Param from query string false "RFC 3339 lower bound of createdAt, inclusive"
+// @Param to query string false "RFC 3339 upper bound of createdAt, exclusive"
+// @Param order query string false "asc or desc (default)"
+// @Param page query int false "page, starting at 1"
+// @Param size query int false "page size, at most 100"
+// @Param orgId query string false "organization, platform superadmin only"
+// @Tags Audit
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.AuditEventModel,page=int,size=int,total=int} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/audit [get]
+// @Security BearerAuth
+func (auditController AuditController) GetAudit(c *gin.Context) {
+	query := model.PaginateAuditModel{}
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Size < 1 {
+		query.Size = AUDIT_DEFAULT_PAGE_SIZE
+	}
+	events, total, err := auditController.Auditor.Events.List(c.Request.Context(), helpers.TenantScope(c), repository.AuditQuery{
+		Action:     query.Action,
+		ActorEmail: query.ActorEmail,
+		TargetType: query.TargetType,
+		TargetId:   query.TargetId,
+		From:       query.From,
+		To:         query.To,
+		Descending: query.Order != "asc",
+		Skip:       (query.Page - 1) * query.Size,
+		Size:       query.Size,
+	})
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": events, "page": query.Page, "size": query.Size, "total": total})
+}
+
+// @Summary Verify audit chain
+// @Description walk the hash chain of the whole audit log and report the first broken link, signed checkpoints are checked when a checkpoint secret is configured
+// @Tags Audit
+// @Produce  json
+// @Success 200 {object} object{status=string,data=model.AuditVerifyModel} "chain intact"
+// @Failure 409 {object} model.ErrorModel{details=model.AuditVerifyModel} "chain broken"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/audit/verify [get]
+// @Security BearerAuth
+func (auditController AuditController) VerifyAudit(c *gin.Context) {
+	report, err := auditController.Auditor.Verify(c.Request.Context())
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if !report.Valid {
+		apierror.AbortWithDetails(c, apierror.AUDIT_CHAIN_BROKEN, report)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": report})
+}
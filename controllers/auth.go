@@ -2,91 +2,139 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/events"
 	"ima-svc-management/helpers"
+	"ima-svc-management/metrics"
 	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
 	"net/http"
-	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var ACCESS_TOKEN = time.Duration(5) * time.Minute
-var REFRESH_TOKEN = time.Duration(10) * time.Minute
-
 type AuthController struct {
-	MongoClient *mongo.Client
-	RedisClient *redis.Client
-	Auth        *helpers.Auth
+	Accounts   repository.AccountRepository
+	Roles      repository.RoleRepository
+	Attributes repository.AttributeSchemaRepository
+	Auth       *helpers.Auth
+	Metrics    *metrics.Metrics
+	Auditor    *audit.Auditor
+	Webhooks   *webhook.Dispatcher
+	Outbox     *events.Outbox
 }
 
-func InitAuth(redisClient *redis.Client, mongoClient *mongo.Client) *AuthController {
+func InitAuth(accounts repository.AccountRepository, roles repository.RoleRepository, attributes repository.AttributeSchemaRepository, auth *helpers.Auth, metrics *metrics.Metrics, auditor *audit.Auditor, webhooks *webhook.Dispatcher, outbox *events.Outbox) *AuthController {
 	return &AuthController{
-		MongoClient: mongoClient,
-		RedisClient: redisClient,
-		Auth:        &helpers.Auth{},
+		Accounts:   accounts,
+		Roles:      roles,
+		Attributes: attributes,
+		Auth:       auth,
+		Metrics:    metrics,
+		Auditor:    auditor,
+		Webhooks:   webhooks,
+		Outbox:     outbox,
 	}
 }
 
 // @Summary Login
-// @Description Login user
+// @Description Login user, orgId is only needed when the email is registered in several organizations
 // @Param body body model.LoginModel true "body"
 // @Tags Auth
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/auth/login [post]
 func (authController AuthController) Login(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	login := model.LoginModel{}
-	err := c.BindJSON(&login)
+	err := c.ShouldBindJSON(&login)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		validation.Abort(c, err)
 		return
 	}
-	collection := authController.MongoClient.Database("test").Collection("account")
-
-	filter := bson.M{"email": login.Email}
-
-	account := model.AccountModel{}
+	emailNormalized := helpers.NormalizeEmail(login.Email)
+	scope := repository.OrganizationScope(login.OrgId)
+	if login.OrgId == "" {
+		scope = repository.AnyScope()
+		registered, err := authController.Accounts.CountByEmail(ctx, emailNormalized)
+		if err != nil {
+			authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+			apierror.Internal(c, err)
+			return
+		}
+		if registered > 1 {
+			authController.loginFailed(c, login, metrics.OUTCOME_ORGANIZATION_REQUIRED)
+			apierror.Abort(c, apierror.ORG_ID_AMBIGUOUS)
+			return
+		}
+	}
 
-	err = collection.FindOne(context.TODO(), filter).Decode(&account)
+	account, err := authController.Accounts.FindByEmail(ctx, scope, emailNormalized)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Email are not registered"})
-			c.Abort()
+		if errors.Is(err, repository.ErrNotFound) {
+			authController.loginFailed(c, login, metrics.OUTCOME_UNKNOWN_EMAIL)
+			apierror.Abort(c, apierror.EMAIL_UNREGISTERED)
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+		apierror.Internal(c, err)
+		return
+	}
+
+	if compare, _ := helpers.PasswordCompare([]byte(login.Password), []byte(account.Password)); !compare {
+		authController.loginFailed(c, login, metrics.OUTCOME_INVALID_CREDENTIALS)
+		apierror.Abort(c, apierror.WRONG_PASSWORD)
+		return
+	}
+
+	attributeClaims, err := authController.attributeClaims(ctx, account)
+	if err != nil {
+		authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+		apierror.Internal(c, err)
 		return
 	}
 
-	if compare, _ := helpers.PasswordCompare([]byte(login.Password), []byte(account.Password)); !compare && err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Wrong Password"})
-		c.Abort()
+	roleType, err := authController.roleType(ctx, account)
+	if err != nil {
+		authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+		apierror.Internal(c, err)
 		return
 	}
 
-	tokenDetails, err := authController.Auth.CreateToken(login.Email)
+	tokenDetails, err := authController.Auth.CreateToken(account, roleType, attributeClaims)
 	if err != nil {
-		c.JSON(http.StatusNotAcceptable, gin.H{"message": "Failed creating token"})
-		c.Abort()
+		authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+		apierror.Internal(c, err)
 		return
 	}
 
-	err = authController.Auth.CreateAuth(ctx, login.Email, tokenDetails)
+	err = authController.Auth.CreateAuth(ctx, account, tokenDetails)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, err.Error())
+		authController.loginFailed(c, login, metrics.OUTCOME_ERROR)
+		apierror.Internal(c, err)
+		return
 	}
+	authController.Metrics.Logins.WithLabelValues(metrics.OUTCOME_SUCCESS).Inc()
+	authController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:  account.OrgId,
+		Action: model.AUDIT_LOGIN,
+		Actor:  accountActor(*account),
+		Target: accountTarget(*account),
+	})
+	authController.Webhooks.Emit(c, account.OrgId, model.WEBHOOK_SESSION_CREATED, sessionData(account.OrgId, account.Email, account.Role))
+	authController.Outbox.Emit(c, model.EVENT_SESSION_CREATED, account.OrgId, SessionAggregateId(account.Email), sessionData(account.OrgId, account.Email, account.Role))
 
 	c.Header("Authorization", "Bearer "+tokenDetails.AccessToken)
-	c.SetCookie("refresh_token", tokenDetails.RefreshToken, 86400, "/", "localhost", false, true)
+	authController.Auth.SetRefreshCookie(c, tokenDetails.RefreshToken)
 
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Login Success"})
 }
@@ -97,18 +145,32 @@ func (authController AuthController) Login(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/auth/logout [post]
 func (authController AuthController) Logout(c *gin.Context) {
 	auth, err := authController.Auth.ExtractTokenMetadata(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, "Unauthorized")
+		apierror.Abort(c, apierror.UNAUTHORIZED)
+		return
+	}
+	deleted, err := authController.Auth.DeleteAuth(c.Request.Context(), auth.AccessUUID)
+	if err != nil {
+		apierror.Internal(c, err)
 		return
 	}
-	deleted, err := authController.Auth.DeleteAuth(context.Background(), auth.AccessUUID)
-	if err != nil || deleted == 0 {
-		c.JSON(http.StatusUnauthorized, "Unauthorized")
+	if deleted == 0 {
+		apierror.Abort(c, apierror.UNAUTHORIZED)
 		return
 	}
+	authController.Metrics.Logouts.Inc()
+	authController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:  c.GetString(helpers.CONTEXT_ORG_ID),
+		Action: model.AUDIT_LOGOUT,
+		Target: model.AuditTargetModel{Type: "account", Email: c.GetString(helpers.CONTEXT_EMAIL)},
+	})
+	session := sessionData(helpers.CallerOrgId(c), c.GetString(helpers.CONTEXT_EMAIL), c.GetString(helpers.CONTEXT_ROLE))
+	authController.Webhooks.Emit(c, helpers.CallerOrgId(c), model.WEBHOOK_SESSION_ENDED, session)
+	authController.Outbox.Emit(c, model.EVENT_SESSION_ENDED, helpers.CallerOrgId(c), SessionAggregateId(c.GetString(helpers.CONTEXT_EMAIL)), session)
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Logout Success"})
 }
 
@@ -118,24 +180,27 @@ func (authController AuthController) Logout(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/auth/refresh [get]
 func (authController AuthController) Refresh(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, "Unauthorized")
+		authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_INVALID_TOKEN).Inc()
+		apierror.Abort(c, apierror.UNAUTHORIZED)
 		return
 	}
 
 	token, err := authController.Auth.VerifyRefreshToken(c, refreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token expired"})
-		c.Abort()
+		authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_INVALID_TOKEN).Inc()
+		apierror.Abort(c, apierror.REFRESH_TOKEN_EXPIRED)
 		return
 	}
 
 	if _, ok := token.Claims.(jwt.MapClaims); !ok && !token.Valid {
-		c.JSON(http.StatusUnauthorized, err)
+		authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_INVALID_TOKEN).Inc()
+		apierror.Abort(c, apierror.INVALID_TOKEN)
 		return
 	}
 
@@ -143,30 +208,151 @@ func (authController AuthController) Refresh(c *gin.Context) {
 	if ok && token.Valid {
 		refreshUuid, ok := claims["refresh_uuid"].(string)
 		if !ok {
-			c.JSON(http.StatusUnprocessableEntity, err)
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_INVALID_TOKEN).Inc()
+			apierror.Abort(c, apierror.INVALID_TOKEN)
 			return
 		}
 		email := claims["email"].(string)
+		orgId, _ := claims["org_id"].(string)
 		deleted, err := authController.Auth.DeleteAuth(ctx, refreshUuid)
-		if err != nil || deleted == 0 {
-			c.JSON(http.StatusUnauthorized, "Unauthorized")
+		if err != nil {
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_ERROR).Inc()
+			apierror.Internal(c, err)
 			return
 		}
-		newToken, err := authController.Auth.CreateToken(email)
+		if deleted == 0 {
+			// the signature is valid but the token was already rotated or revoked
+			authController.Metrics.TokenReuse.Inc()
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_REUSED_TOKEN).Inc()
+			authController.Auditor.Record(c, model.AuditEventModel{
+				OrgId:  orgId,
+				Action: model.AUDIT_REFRESH_REUSED,
+				Actor:  model.AuditActorModel{Email: email, OrgId: orgId},
+				Target: model.AuditTargetModel{Type: "account", Email: email},
+				Reason: metrics.OUTCOME_REUSED_TOKEN,
+			})
+			authController.Webhooks.Emit(c, orgId, model.WEBHOOK_SESSION_REUSED, sessionData(orgId, email, ""))
+			authController.Outbox.Emit(c, model.EVENT_SESSION_REUSED, orgId, SessionAggregateId(email), sessionData(orgId, email, ""))
+			apierror.Abort(c, apierror.INVALID_TOKEN)
+			return
+		}
+
+		account, err := authController.Accounts.FindByEmail(ctx, repository.OrganizationScope(orgId), helpers.NormalizeEmail(email))
 		if err != nil {
-			c.JSON(http.StatusForbidden, err.Error())
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_UNKNOWN_EMAIL).Inc()
+			apierror.Abort(c, apierror.UNAUTHORIZED)
 			return
 		}
 
-		err = authController.Auth.CreateAuth(ctx, email, newToken)
+		attributeClaims, err := authController.attributeClaims(ctx, account)
 		if err != nil {
-			c.JSON(http.StatusForbidden, err.Error())
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_ERROR).Inc()
+			apierror.Internal(c, err)
+			return
+		}
+
+		roleType, err := authController.roleType(ctx, account)
+		if err != nil {
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_ERROR).Inc()
+			apierror.Internal(c, err)
+			return
+		}
+
+		newToken, err := authController.Auth.CreateToken(account, roleType, attributeClaims)
+		if err != nil {
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_ERROR).Inc()
+			apierror.Internal(c, err)
+			return
+		}
+
+		err = authController.Auth.CreateAuth(ctx, account, newToken)
+		if err != nil {
+			authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_ERROR).Inc()
+			apierror.Internal(c, err)
 			return
 		}
 
 		c.Header("Authorization", "Bearer "+newToken.AccessToken)
-		c.SetCookie("refresh_token", newToken.RefreshToken, 86400, "/", "localhost", false, true)
+		authController.Auth.SetRefreshCookie(c, newToken.RefreshToken)
+		authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_SUCCESS).Inc()
+		authController.Auditor.Record(c, model.AuditEventModel{
+			OrgId:  account.OrgId,
+			Action: model.AUDIT_REFRESH,
+			Actor:  accountActor(*account),
+			Target: accountTarget(*account),
+		})
+		authController.Outbox.Emit(c, model.EVENT_SESSION_REFRESHED, account.OrgId, SessionAggregateId(account.Email), sessionData(account.OrgId, account.Email, account.Role))
+		c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Refresh Success"})
+		return
+	}
+	authController.Metrics.Refreshes.WithLabelValues(metrics.OUTCOME_INVALID_TOKEN).Inc()
+	apierror.Abort(c, apierror.INVALID_TOKEN)
+}
+
+// loginFailed counts a failed login and records it under the email that was
+// tried, outcome says why it failed.
+func (authController AuthController) loginFailed(c *gin.Context, login model.LoginModel, outcome string) {
+	authController.Metrics.Logins.WithLabelValues(outcome).Inc()
+	authController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:  login.OrgId,
+		Action: model.AUDIT_LOGIN_FAILED,
+		Actor:  model.AuditActorModel{Email: login.Email, OrgId: login.OrgId},
+		Target: model.AuditTargetModel{Type: "account", Email: login.Email},
+		Reason: outcome,
+	})
+}
 
+// SessionAggregateId is the aggregate id of session events, sessions are kept
+// per account and only the email is known for every one of them.
+func SessionAggregateId(email string) string {
+	return helpers.NormalizeEmail(email)
+}
+
+func sessionData(orgId string, email string, role string) map[string]interface{} {
+	return map[string]interface{}{
+		"org_id": orgId,
+		"email":  email,
+		"role":   role,
 	}
+}
 
+func accountActor(account model.AccountModel) model.AuditActorModel {
+	return model.AuditActorModel{Email: account.Email, OrgId: account.OrgId, Role: account.Role}
+}
+
+// roleType resolves the EnumRole granted by the role document the account
+// names. Only accounts outside any organization can be platform superadmins,
+// an account whose role was deleted gets no privileges.
+func (authController AuthController) roleType(ctx context.Context, account *model.AccountModel) (model.EnumRole, error) {
+	if account.OrgId == "" {
+		if account.Role == string(model.PLATFORM_SUPERADMIN) {
+			return model.PLATFORM_SUPERADMIN, nil
+		}
+		return "", nil
+	}
+	if account.Role == "" {
+		return "", nil
+	}
+	role, err := authController.Roles.FindByName(ctx, repository.OrganizationScope(account.OrgId), account.Role)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if role.Role == model.PLATFORM_SUPERADMIN {
+		return model.SUPERADMIN, nil
+	}
+	return role.Role, nil
+}
+
+func (authController AuthController) attributeClaims(ctx context.Context, account *model.AccountModel) (map[string]interface{}, error) {
+	if len(account.Attributes) == 0 {
+		return nil, nil
+	}
+	schemas, err := authController.Attributes.FindByOrganization(ctx, account.OrgId)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.TokenAttributes(schemas, account.Attributes), nil
 }
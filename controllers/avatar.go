@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const AVATAR_ORIGINAL = "original"
+
+type AvatarController struct {
+	Avatars  repository.AvatarStore
+	Accounts repository.AccountRepository
+	Auditor  *audit.Auditor
+}
+
+func InitAvatar(avatars repository.AvatarStore, accounts repository.AccountRepository, auditor *audit.Auditor) *AvatarController {
+	return &AvatarController{
+		Avatars:  avatars,
+		Accounts: accounts,
+		Auditor:  auditor,
+	}
+}
+
+// @Summary Upload avatar
+// @Description upload avatar of the logged in account, png, jpeg or gif up to 2MB
+// @Param avatar formData file true "avatar"
+// @Tags Avatar
+// @Accept  multipart/form-data
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/me/avatar [put]
+// @Security BearerAuth
+func (avatarController AvatarController) UploadAvatar(c *gin.Context) {
+	scope := repository.OrganizationScope(helpers.CallerOrgId(c))
+	account, err := avatarController.Accounts.FindByEmail(c.Request.Context(), scope, helpers.NormalizeEmail(c.GetString(helpers.CONTEXT_EMAIL)))
+	if err != nil {
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, helpers.AVATAR_MAX_SIZE+(1<<20))
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		apierror.Abort(c, apierror.AVATAR_REQUIRED)
+		return
+	}
+	if fileHeader.Size > helpers.AVATAR_MAX_SIZE {
+		apierror.Abort(c, apierror.AVATAR_TOO_LARGE)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_REQUEST, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, helpers.AVATAR_MAX_SIZE+1))
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_REQUEST, err)
+		return
+	}
+	if len(data) > helpers.AVATAR_MAX_SIZE {
+		apierror.Abort(c, apierror.AVATAR_TOO_LARGE)
+		return
+	}
+
+	contentType, err := helpers.DetectImageType(data)
+	if err != nil {
+		apierror.Abort(c, apierror.UNSUPPORTED_MEDIA_TYPE)
+		return
+	}
+	img, err := helpers.DecodeImage(data)
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_IMAGE, err)
+		return
+	}
+
+	variants := []model.AvatarVariantModel{avatarVariant(*account, AVATAR_ORIGINAL, contentType, data)}
+	for _, size := range helpers.AVATAR_THUMBNAIL_SIZES {
+		thumbnail, err := helpers.Thumbnail(img, size)
+		if err != nil {
+			apierror.AbortWithReason(c, apierror.INVALID_IMAGE, err)
+			return
+		}
+		variants = append(variants, avatarVariant(*account, strconv.Itoa(size), "image/png", thumbnail))
+	}
+
+	err = avatarController.Avatars.Replace(c.Request.Context(), account.Id, variants)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	avatarController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   account.OrgId,
+		Action:  model.AUDIT_AVATAR_UPDATE,
+		Target:  accountTarget(*account),
+		Changes: []model.AuditChangeModel{{Field: "avatar", After: helpers.ContentETag(data)}},
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Upload avatar successful"})
+}
+
+// @Summary Get avatar
+// @Description get avatar of an account, size selects a thumbnail (64 or 256) instead of the original image
+// @Param id path string true "id"
+// @Param size query string false "size"
+// @Tags Avatar
+// @Produce  png
+// @Produce  jpeg
+// @Produce  gif
+// @Success 200 {file} binary "ok"
+// @Success 304 "not modified"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/account/{id}/avatar [get]
+// @Security BearerAuth
+func (avatarController AvatarController) GetAvatar(c *gin.Context) {
+	account, err := avatarController.Accounts.FindById(c.Request.Context(), helpers.TenantScope(c), c.Param("id"))
+	if err != nil {
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
+		return
+	}
+
+	variant := c.DefaultQuery("size", AVATAR_ORIGINAL)
+
+	file, content, err := avatarController.Avatars.Open(c.Request.Context(), account.Id, variant)
+	if err != nil {
+		apierror.Lookup(c, apierror.AVATAR_NOT_FOUND, err)
+		return
+	}
+	defer content.Close()
+
+	lastModified := file.UploadDate.UTC().Truncate(time.Second)
+	c.Header("ETag", file.Metadata.ETag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, max-age=0, must-revalidate")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if match == file.Metadata.ETag || match == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, file.Length, file.Metadata.ContentType, content, nil)
+}
+
+// @Summary Delete avatar
+// @Description delete avatar of the logged in account
+// @Tags Avatar
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/me/avatar [delete]
+// @Security BearerAuth
+func (avatarController AvatarController) DeleteAvatar(c *gin.Context) {
+	scope := repository.OrganizationScope(helpers.CallerOrgId(c))
+	account, err := avatarController.Accounts.FindByEmail(c.Request.Context(), scope, helpers.NormalizeEmail(c.GetString(helpers.CONTEXT_EMAIL)))
+	if err != nil {
+		apierror.Lookup(c, apierror.ACCOUNT_NOT_FOUND, err)
+		return
+	}
+
+	err = avatarController.Avatars.Delete(c.Request.Context(), account.Id)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	avatarController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:  account.OrgId,
+		Action: model.AUDIT_AVATAR_DELETE,
+		Target: accountTarget(*account),
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete avatar successful"})
+}
+
+// avatarVariant is one image of an upload by account.
+func avatarVariant(account model.AccountModel, variant string, contentType string, content []byte) model.AvatarVariantModel {
+	return model.AvatarVariantModel{
+		Metadata: model.AvatarMetadataModel{
+			AccountId:   account.Id,
+			OrgId:       account.OrgId,
+			Variant:     variant,
+			ContentType: contentType,
+			ETag:        helpers.ContentETag(content),
+		},
+		Content: content,
+	}
+}
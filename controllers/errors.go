@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"errors"
+	"ima-svc-management/apierror"
+	"net/http"
+)
+
+// Errors returned by the controller operations shared with the admin command
+// line, handlers map them to a response status.
+var ErrOrganizationNotFound = errors.New("Organization not found")
+var ErrOrganizationRegistered = errors.New("Organization already registered")
+var ErrAccountNotFound = errors.New("Account not found")
+var ErrEmailRegistered = errors.New("Email already registered")
+var ErrRoleRegistered = errors.New("Role already registered")
+var ErrInvalidAttributes = errors.New("invalid attributes")
+var ErrOrganizationHasAccounts = errors.New("Organization still has accounts")
+
+// Returned when the storage enforces references between accounts and roles.
+var ErrRoleNotFound = errors.New("Role is not defined in the organization")
+
+// conflictCode is the error code of a conditional update that failed with
+// status, see helpers.ParsePrecondition.
+func conflictCode(status int) apierror.Code {
+	if status == http.StatusPreconditionFailed {
+		return apierror.PRECONDITION_FAILED
+	}
+	return apierror.VERSION_CONFLICT
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"ima-svc-management/apierror"
+	"ima-svc-management/config"
+	"ima-svc-management/events"
+	"ima-svc-management/model"
+	"ima-svc-management/validation"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const EVENT_DEFAULT_COUNT = 100
+const EVENT_MAX_COUNT = 1000
+
+// streamIdPattern matches the ids Redis gives stream entries, anything else
+// would make the range read fail.
+var streamIdPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?$`)
+
+type EventController struct {
+	RedisClient redis.UniversalClient
+	Stream      string
+}
+
+func InitEvent(redisClient redis.UniversalClient, eventsConfig config.EventsConfig) *EventController {
+	return &EventController{
+		RedisClient: redisClient,
+		Stream:      eventsConfig.Stream,
+	}
+}
+
+// @Summary Replay domain events
+// @Description read the domain event stream from a position, platform superadmin only. Pass the streamId of the last event seen as after to continue reading, an empty after starts at the oldest event still kept.
+// @Param after query string false "stream id to read after, exclusive"
+// @Param count query int false "number of events, at most 1000"
+// @Tags Event
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.StreamEventModel,next=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/events [get]
+// @Security BearerAuth
+func (eventController EventController) GetEvents(c *gin.Context) {
+	query := model.ReplayEventModel{}
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	if query.After != "" && !streamIdPattern.MatchString(query.After) {
+		apierror.AbortWithDetails(c, apierror.INVALID_REQUEST, gin.H{"reason": "after must be the streamId of an event"})
+		return
+	}
+	if query.Count < 1 {
+		query.Count = EVENT_DEFAULT_COUNT
+	}
+	if query.Count > EVENT_MAX_COUNT {
+		query.Count = EVENT_MAX_COUNT
+	}
+
+	entries, err := events.Replay(c.Request.Context(), eventController.RedisClient, eventController.Stream, query.After, query.Count)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	next := query.After
+	if len(entries) > 0 {
+		next = entries[len(entries)-1].StreamId
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": entries, "next": next})
+}
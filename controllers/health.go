@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	CheckTimeout time.Duration
+	checks       map[string]HealthCheck
+	shuttingDown *int32
+}
+
+func InitHealth(checkTimeout time.Duration) *HealthController {
+	return &HealthController{
+		CheckTimeout: checkTimeout,
+		checks:       map[string]HealthCheck{},
+		shuttingDown: new(int32),
+	}
+}
+
+// AddCheck makes readiness depend on a dependency.
+func (healthController HealthController) AddCheck(name string, check HealthCheck) {
+	healthController.checks[name] = check
+}
+
+// ShuttingDown makes readiness fail so load balancers stop routing new
+// requests while the server drains the ones in flight.
+func (healthController HealthController) ShuttingDown() {
+	atomic.StoreInt32(healthController.shuttingDown, 1)
+}
+
+// @Summary Liveness
+// @Description report that the process is up, dependencies are not checked
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} model.HealthModel "ok"
+// @Router /healthz [get]
+func (healthController HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, model.HealthModel{Status: model.HEALTH_OK})
+}
+
+// @Summary Readiness
+// @Description ping the storage backend, mongo or postgres, and redis when it is configured and report the status of each dependency
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} model.HealthModel "ok"
+// @Failure 503 {object} model.HealthModel "unavailable"
+// @Router /readyz [get]
+func (healthController HealthController) Readiness(c *gin.Context) {
+	if atomic.LoadInt32(healthController.shuttingDown) == 1 {
+		c.JSON(http.StatusServiceUnavailable, model.HealthModel{Status: model.HEALTH_UNAVAILABLE})
+		return
+	}
+
+	health := model.HealthModel{Status: model.HEALTH_OK, Checks: map[string]model.HealthCheckModel{}}
+	for name, check := range healthController.checks {
+		result := healthController.run(c.Request.Context(), check)
+		if result.Status != model.HEALTH_OK {
+			health.Status = model.HEALTH_UNAVAILABLE
+		}
+		health.Checks[name] = result
+	}
+
+	if health.Status != model.HEALTH_OK {
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+type HealthCheck func(ctx context.Context) error
+
+func (healthController HealthController) run(ctx context.Context, check HealthCheck) model.HealthCheckModel {
+	ctx, cancel := context.WithTimeout(ctx, healthController.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := model.HealthCheckModel{Status: model.HEALTH_OK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = model.HEALTH_UNAVAILABLE
+		result.Error = err.Error()
+	}
+	return result
+}
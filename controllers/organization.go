@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"context"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/events"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationController struct {
+	Organizations repository.OrganizationRepository
+	Accounts      repository.AccountRepository
+	Roles         repository.RoleRepository
+	Attributes    repository.AttributeSchemaRepository
+	Webhooks      repository.WebhookRepository
+	Avatars       repository.AvatarStore
+	Auditor       *audit.Auditor
+	Outbox        *events.Outbox
+}
+
+func InitOrganization(organizations repository.OrganizationRepository, accounts repository.AccountRepository, roles repository.RoleRepository, attributes repository.AttributeSchemaRepository, webhooks repository.WebhookRepository, avatars repository.AvatarStore, auditor *audit.Auditor, outbox *events.Outbox) *OrganizationController {
+	return &OrganizationController{
+		Organizations: organizations,
+		Accounts:      accounts,
+		Roles:         roles,
+		Attributes:    attributes,
+		Webhooks:      webhooks,
+		Avatars:       avatars,
+		Auditor:       auditor,
+		Outbox:        outbox,
+	}
+}
+
+// @Summary Add organization
+// @Description create new organization, platform superadmin only
+// @Param body body model.OrganizationCreateModel true "body"
+// @Tags Organization
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/organization/add [post]
+// @Security BearerAuth
+func (organizationController OrganizationController) AddOrganization(c *gin.Context) {
+	request := model.OrganizationCreateModel{}
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	organization := request.Model()
+
+	organization.Id, err = organizationController.CreateOrganization(c.Request.Context(), organization)
+	if err == ErrOrganizationRegistered {
+		apierror.Abort(c, apierror.ORGANIZATION_REGISTERED)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	organizationController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   organization.Id,
+		Action:  model.AUDIT_ORGANIZATION_CREATE,
+		Target:  organizationTarget(organization),
+		Changes: audit.Diff(nil, organization),
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Create organization successful"})
+}
+
+// CreateOrganization stores a new organization and returns its id.
+func (organizationController OrganizationController) CreateOrganization(ctx context.Context, organization model.OrganizationModel) (string, error) {
+	organization.Id = helpers.GenerateId()
+	organization.CreatedAt = time.Now().Unix()
+	organization.UpdatedAt = 0
+
+	err := organizationController.Organizations.Create(ctx, &organization)
+	if err == repository.ErrDuplicate {
+		return "", ErrOrganizationRegistered
+	}
+	if err != nil {
+		return "", err
+	}
+	return organization.Id, nil
+}
+
+// FindOrganizationByName returns the organization registered under name.
+func (organizationController OrganizationController) FindOrganizationByName(ctx context.Context, name string) (*model.OrganizationModel, error) {
+	organization, err := organizationController.Organizations.FindByName(ctx, name)
+	if err == repository.ErrNotFound {
+		return nil, ErrOrganizationNotFound
+	}
+	return organization, err
+}
+
+// @Summary Get all organization
+// @Description get all organization with pagination, platform superadmin only
+// @Param body body model.PaginateOrganizationModel true "body"
+// @Tags Organization
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.OrganizationResponseModel} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/organization/getAll [post]
+// @Security BearerAuth
+func (organizationController OrganizationController) GetOrganization(c *gin.Context) {
+	paginationModel := model.PaginateOrganizationModel{}
+
+	err := c.ShouldBindJSON(&paginationModel)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	organizations, err := organizationController.Organizations.List(c.Request.Context(), repository.Page{
+		Order:   paginationModel.Order,
+		OrderBy: paginationModel.OrderBy,
+		Skip:    paginationModel.Page,
+		Size:    pageSize(paginationModel.Size),
+	})
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	datas := make([]model.OrganizationResponseModel, 0, len(organizations))
+	for _, organization := range organizations {
+		datas = append(datas, model.NewOrganizationResponse(organization))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
+}
+
+// @Summary Get organization by id
+// @Description get organization using id, platform superadmin only
+// @Param id query string true "id"
+// @Tags Organization
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.OrganizationResponseModel} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/organization/getById [get]
+// @Security BearerAuth
+func (organizationController OrganizationController) GetOrganizationById(c *gin.Context) {
+	id := c.Query("id")
+
+	organization, err := organizationController.Organizations.FindById(c.Request.Context(), id)
+	if err != nil {
+		apierror.Lookup(c, apierror.ORGANIZATION_NOT_FOUND, err)
+		return
+	}
+
+	datas := []model.OrganizationResponseModel{model.NewOrganizationResponse(*organization)}
+
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
+}
+
+// @Summary Update organization
+// @Description Update organization, platform superadmin only
+// @Param body body model.OrganizationUpdateModel true "body"
+// @Tags Organization
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/organization/update [put]
+// @Security BearerAuth
+func (organizationController OrganizationController) UpdateOrganization(c *gin.Context) {
+	organization := model.OrganizationUpdateModel{}
+	err := c.ShouldBindJSON(&organization)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	change := repository.OrganizationChange{UpdatedAt: time.Now().Unix()}
+	if organization.Name != "" {
+		change.Name = &organization.Name
+	}
+	if organization.Description != "" {
+		change.Description = &organization.Description
+	}
+
+	previousOrganization, updatedOrganization, err := organizationController.Organizations.Update(c.Request.Context(), organization.Id, change)
+	if err == repository.ErrDuplicate {
+		apierror.Abort(c, apierror.ORGANIZATION_REGISTERED)
+		return
+	}
+	if err != nil && err != repository.ErrNotFound {
+		apierror.Internal(c, err)
+		return
+	}
+	if err == nil {
+		organizationController.Auditor.Record(c, model.AuditEventModel{
+			OrgId:   previousOrganization.Id,
+			Action:  model.AUDIT_ORGANIZATION_UPDATE,
+			Target:  organizationTarget(*previousOrganization),
+			Changes: audit.Diff(*previousOrganization, *updatedOrganization),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Update organization successful"})
+
+}
+
+// @Summary Delete organization by id
+// @Description delete organization using id with its roles, attribute schemas, webhooks and avatars, only allowed once it has no accounts left
+// @Param id query string true "id"
+// @Tags Organization
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/organization/delete [delete]
+// @Security BearerAuth
+func (organizationController OrganizationController) DeleteOrganization(c *gin.Context) {
+	id := c.Query("id")
+
+	// the accounts are counted in the transaction that deletes the
+	// organization, in Postgres the role reference of an account also keeps
+	// one from being added meanwhile. The roles go with the organization,
+	// each one is announced as deleted.
+	var deletedOrganization *model.OrganizationModel
+	err := organizationController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		organization, err := organizationController.Organizations.Delete(ctx, id)
+		if err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		deletedOrganization = organization
+		roles, err := organizationController.Roles.DeleteByOrganization(ctx, id)
+		if err == repository.ErrReference {
+			return ErrOrganizationHasAccounts
+		}
+		if err != nil {
+			return err
+		}
+		accounts, err := organizationController.Accounts.CountByOrganization(ctx, id)
+		if err != nil {
+			return err
+		}
+		if accounts > 0 {
+			return ErrOrganizationHasAccounts
+		}
+		for _, role := range roles {
+			err = organizationController.Outbox.Add(ctx, model.EVENT_ROLE_DELETED, role.OrgId, role.Id, model.NewRoleResponse(role))
+			if err != nil {
+				return err
+			}
+		}
+		err = organizationController.Attributes.DeleteByOrganization(ctx, id)
+		if err != nil {
+			return err
+		}
+		return organizationController.Webhooks.DeleteByOrganization(ctx, id)
+	})
+	if err == ErrOrganizationHasAccounts {
+		apierror.Abort(c, apierror.ORGANIZATION_HAS_ACCOUNTS)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	// GridFS cannot join the transaction, the avatars follow once it is
+	// committed
+	err = organizationController.Avatars.DeleteByOrganization(c.Request.Context(), id)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if deletedOrganization != nil {
+		organizationController.Auditor.Record(c, model.AuditEventModel{
+			OrgId:   deletedOrganization.Id,
+			Action:  model.AUDIT_ORGANIZATION_DELETE,
+			Target:  organizationTarget(*deletedOrganization),
+			Changes: audit.Diff(*deletedOrganization, nil),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete organization successful"})
+}
+
+func organizationTarget(organization model.OrganizationModel) model.AuditTargetModel {
+	return model.AuditTargetModel{Type: "organization", Id: organization.Id}
+}
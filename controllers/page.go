@@ -0,0 +1,12 @@
+package controllers
+
+// PAGE_DEFAULT_SIZE is the size of a listing page when the request names
+// none, the binding rules of the pagination models cap it at 100.
+const PAGE_DEFAULT_SIZE = 20
+
+func pageSize(size int) int {
+	if size < 1 {
+		return PAGE_DEFAULT_SIZE
+	}
+	return size
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"ima-svc-management/validation"
+	"sort"
+)
+
+// patchedText checks a text field of a patched document, required ones can
+// not be emptied.
+func patchedText(field string, value interface{}, required bool) []validation.Failure {
+	text, ok := value.(string)
+	if !ok {
+		return []validation.Failure{{Field: field, Rule: validation.RULE_TYPE, Param: "string"}}
+	}
+	if required && text == "" {
+		return []validation.Failure{{Field: field, Rule: "required"}}
+	}
+	return nil
+}
+
+// patchFailures adds the required fields the patch removed to failures and
+// reports them sorted by field, nil when there are none.
+func patchFailures(patched map[string]interface{}, failures []validation.Failure, required ...string) error {
+	for _, field := range required {
+		if _, ok := patched[field]; !ok {
+			failures = append(failures, validation.Failure{Field: field, Rule: "required"})
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Field < failures[j].Field
+	})
+	return &validation.Error{Failures: failures}
+}
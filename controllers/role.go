@@ -2,130 +2,148 @@ package controllers
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/hex"
+	"errors"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
+	"ima-svc-management/events"
+	"ima-svc-management/helpers"
 	"ima-svc-management/model"
-	"log"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type RoleController struct {
-	MongoClient *mongo.Client
+	Accounts repository.AccountRepository
+	Roles    repository.RoleRepository
+	Auditor  *audit.Auditor
+	Webhooks *webhook.Dispatcher
+	Outbox   *events.Outbox
 }
 
-func InitRole(mongoClient *mongo.Client) *RoleController {
+func InitRole(accounts repository.AccountRepository, roles repository.RoleRepository, auditor *audit.Auditor, webhooks *webhook.Dispatcher, outbox *events.Outbox) *RoleController {
 	return &RoleController{
-		MongoClient: mongoClient,
+		Accounts: accounts,
+		Roles:    roles,
+		Auditor:  auditor,
+		Webhooks: webhooks,
+		Outbox:   outbox,
 	}
 }
 
 // @Summary Add role
 // @Description create new role
-// @Param body body model.RoleModel true "body"
+// @Param body body model.RoleCreateModel true "body"
 // @Tags Role
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/role/add [post]
 // @Security BearerAuth
 func (roleController RoleController) AddRole(c *gin.Context) {
-	collection := roleController.MongoClient.Database("test").Collection("role")
-
-	role := model.RoleModel{}
-	err := c.BindJSON(&role)
+	request := model.RoleCreateModel{}
+	err := c.ShouldBindJSON(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		validation.Abort(c, err)
 		return
 	}
+	role := request.Model()
 
-	dataRole := bson.M{
-		"name":        role.Name,
-		"role":        role.Role,
-		"description": role.Description,
-		"createdAt":   time.Now().Unix(),
-		"updatedAt":   nil,
+	if role.Role == model.PLATFORM_SUPERADMIN && !helpers.IsPlatformSuperadmin(c) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
 	}
 
-	hashId, err := bson.Marshal(dataRole)
+	orgId, err := helpers.TenantOrgId(c, role.OrgId)
 	if err != nil {
-		log.Fatal(err)
+		apierror.Abort(c, apierror.ORG_ID_REQUIRED)
+		return
 	}
-	hash := md5.Sum(hashId)
-
-	dataRole["_id"] = hex.EncodeToString(hash[:])
 
-	_, err = collection.InsertOne(context.Background(), dataRole)
+	role.OrgId = orgId
+	id, err := roleController.CreateRole(c.Request.Context(), role)
+	if err == ErrRoleRegistered {
+		apierror.Abort(c, apierror.ROLE_REGISTERED)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Internal(c, err)
 		return
 	}
+	role.Id = id
+	roleController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   role.OrgId,
+		Action:  model.AUDIT_ROLE_CREATE,
+		Target:  roleTarget(role),
+		Changes: audit.Diff(nil, role),
+	})
+	roleController.Webhooks.Emit(c, role.OrgId, model.WEBHOOK_ROLE_CREATED, model.NewRoleResponse(role))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Create role successful"})
 }
 
+// CreateRole stores a new role in role.OrgId and returns its id.
+func (roleController RoleController) CreateRole(ctx context.Context, role model.RoleModel) (string, error) {
+	role.Id = helpers.GenerateId()
+	role.Version = 1
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = nil
+
+	err := roleController.Outbox.Transaction(ctx, func(ctx context.Context) error {
+		err := roleController.Roles.Create(ctx, &role)
+		if err != nil {
+			return err
+		}
+		return roleController.Outbox.Add(ctx, model.EVENT_ROLE_CREATED, role.OrgId, role.Id, model.NewRoleResponse(role))
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return "", ErrRoleRegistered
+	}
+	if err != nil {
+		return "", err
+	}
+	return role.Id, nil
+}
+
 // @Summary Get all role
 // @Description get all role with pagination
 // @Param body body model.PaginateRoleModel true "body"
 // @Tags Role
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} object{status=string,datas=[]model.RoleModel} "ok"
+// @Success 200 {object} object{status=string,data=[]model.RoleResponseModel} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/role/getAll [post]
 // @Security BearerAuth
 func (roleController RoleController) GetRole(c *gin.Context) {
 	paginationModel := model.PaginateRoleModel{}
 
-	err := c.BindJSON(&paginationModel)
+	err := c.ShouldBindJSON(&paginationModel)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		validation.Abort(c, err)
 		return
 	}
 
-	collection := roleController.MongoClient.Database("test").Collection("role")
-
-	pageOptions := options.Find()
-	pageOptions.SetLimit(int64(paginationModel.Size))
-	if paginationModel.Order != "" && paginationModel.OrderBy != "" {
-		if paginationModel.Order == "asc" {
-			pageOptions.SetSort(bson.M{paginationModel.OrderBy: 1})
-		} else {
-			pageOptions.SetSort(bson.M{paginationModel.OrderBy: -1})
-		}
-	}
-	pageOptions.SetSkip(int64(paginationModel.Page))
-
-	cursor, err := collection.Find(context.TODO(), bson.D{{}}, pageOptions)
+	roles, err := roleController.Roles.List(c.Request.Context(), helpers.TenantScope(c), repository.Page{
+		Order:   paginationModel.Order,
+		OrderBy: paginationModel.OrderBy,
+		Skip:    paginationModel.Page,
+		Size:    pageSize(paginationModel.Size),
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Internal(c, err)
 		return
 	}
 
-	datas := make([]map[string]interface{}, 0)
-	for cursor.Next(context.TODO()) {
-		role := model.RoleModel{}
-		if err := cursor.Decode(&role); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-			c.Abort()
-			return
-		}
-		data := map[string]interface{}{
-			"id":          role.Id,
-			"name":        role.Name,
-			"role":        role.Role,
-			"description": role.Description,
-			"createdAt":   role.CreatedAt,
-			"updatedAt":   role.UpdatedAt,
-		}
-		datas = append(datas, data)
+	datas := make([]model.RoleResponseModel, 0, len(roles))
+	for _, role := range roles {
+		datas = append(datas, model.NewRoleResponse(role))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
@@ -137,85 +155,287 @@ func (roleController RoleController) GetRole(c *gin.Context) {
 // @Tags Role
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} object{status=string,datas=[]model.RoleModel} "ok"
+// @Success 200 {object} object{status=string,data=[]model.RoleResponseModel} "ok"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/role/getById [get]
 // @Security BearerAuth
 func (roleController RoleController) GetRoleById(c *gin.Context) {
-	role := model.RoleModel{}
-
 	id := c.Query("id")
 
-	collection := roleController.MongoClient.Database("test").Collection("role")
-
-	filter := bson.M{"_id": id}
-
-	err := collection.FindOne(context.TODO(), filter).Decode(&role)
+	role, err := roleController.Roles.FindById(c.Request.Context(), helpers.TenantScope(c), id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Lookup(c, apierror.ROLE_NOT_FOUND, err)
 		return
 	}
 
-	datas := make([]map[string]interface{}, 0)
-	data := map[string]interface{}{
-		"id":          role.Id,
-		"name":        role.Name,
-		"role":        role.Role,
-		"description": role.Description,
-		"createdAt":   role.CreatedAt,
-		"updatedAt":   role.UpdatedAt,
-	}
-	datas = append(datas, data)
+	datas := []model.RoleResponseModel{model.NewRoleResponse(*role)}
 
+	c.Header("ETag", helpers.VersionETag(role.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
 }
 
 // @Summary Update role
-// @Description Update role
-// @Param body body model.RoleModel true "body"
+// @Description Update role, send If-Match or version to reject the update when the role was changed meanwhile
+// @Param body body model.RoleUpdateModel true "body"
+// @Param If-Match header string false "ETag of the role being updated"
 // @Tags Role
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 409 {object} model.ErrorModel{details=model.RoleResponseModel} "version conflict, or a renamed role is assigned to accounts"
+// @Failure 412 {object} model.ErrorModel{details=model.RoleResponseModel} "If-Match does not match"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/role/update [put]
 // @Security BearerAuth
 func (roleController RoleController) UpdateRole(c *gin.Context) {
 
-	collection := roleController.MongoClient.Database("test").Collection("role")
+	role := model.RoleUpdateModel{}
+	err := c.ShouldBindJSON(&role)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
 
-	role := model.RoleModel{}
-	err := c.BindJSON(&role)
+	if role.Role == model.PLATFORM_SUPERADMIN && !helpers.IsPlatformSuperadmin(c) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
+	}
+
+	precondition, err := helpers.ParsePrecondition(c, role.Version)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Abort(c, apierror.INVALID_IF_MATCH)
 		return
 	}
 
-	filter := bson.M{"_id": role.Id}
-	updateRole := bson.M{
-		"updatedAt": time.Now().Unix(),
+	scope := helpers.TenantScope(c)
+	change := repository.RoleChange{
+		UpdatedAt: time.Now(),
 	}
 	if role.Description != "" {
-		updateRole["description"] = role.Description
+		change.Description = &role.Description
 	}
 	if role.Name != "" {
-		updateRole["name"] = role.Name
+		change.Name = &role.Name
 	}
 	if role.Role != "" {
-		updateRole["role"] = role.Role
+		change.Role = &role.Role
+	}
+	var version *int64
+	if precondition != nil {
+		version = &precondition.Version
 	}
-	update := bson.M{"$set": updateRole}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	var previousRole, updatedRole *model.RoleModel
+	err = roleController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		if change.Name != nil {
+			err := roleController.unassigned(ctx, scope, role.Id, change.Name)
+			if err != nil {
+				return err
+			}
+		}
+		previousRole, updatedRole, err = roleController.Roles.Update(ctx, scope, role.Id, version, change)
+		if err != nil {
+			return err
+		}
+		return roleController.Outbox.Add(ctx, model.EVENT_ROLE_UPDATED, updatedRole.OrgId, updatedRole.Id, model.NewRoleResponse(*updatedRole))
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.ROLE_REGISTERED)
+		return
+	}
+	if errors.Is(err, repository.ErrReference) {
+		apierror.Abort(c, apierror.ROLE_IN_USE)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		currentRole, findErr := roleController.Roles.FindById(c.Request.Context(), scope, role.Id)
+		if errors.Is(findErr, repository.ErrNotFound) {
+			apierror.Abort(c, apierror.ROLE_NOT_FOUND)
+			return
+		}
+		if findErr == nil && precondition != nil {
+			roleConflict(c, precondition.Status, *currentRole)
+			return
+		}
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+		apierror.Internal(c, err)
 		return
 	}
+	roleController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   previousRole.OrgId,
+		Action:  model.AUDIT_ROLE_UPDATE,
+		Target:  roleTarget(*previousRole),
+		Changes: audit.Diff(*previousRole, *updatedRole),
+	})
+	roleController.Webhooks.Emit(c, updatedRole.OrgId, model.WEBHOOK_ROLE_UPDATED, model.NewRoleResponse(*updatedRole))
+	c.Header("ETag", helpers.VersionETag(updatedRole.Version))
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Update role successful"})
 
 }
 
+// @Summary Patch role
+// @Description Partially update role with a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json) document. Patchable fields are name, role and description, a null description clears it. Nothing is written when the patch does not change the role.
+// @Param id query string true "id"
+// @Param body body object true "merge patch document or array of json patch operations"
+// @Param If-Match header string false "ETag of the role being patched"
+// @Tags Role
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=model.RoleResponseModel} "ok"
+// @Failure 409 {object} model.ErrorModel{details=model.RoleResponseModel} "version conflict, or a renamed role is assigned to accounts"
+// @Failure 412 {object} model.ErrorModel{details=model.RoleResponseModel} "If-Match does not match"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid patch or fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/role/patch [patch]
+// @Security BearerAuth
+func (roleController RoleController) PatchRole(c *gin.Context) {
+
+	precondition, err := helpers.ParsePrecondition(c, 0)
+	if err != nil {
+		apierror.Abort(c, apierror.INVALID_IF_MATCH)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_REQUEST, err)
+		return
+	}
+
+	scope := helpers.TenantScope(c)
+	currentRole, err := roleController.Roles.FindById(c.Request.Context(), scope, c.Query("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.ROLE_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	if precondition != nil && precondition.Version != currentRole.Version {
+		roleConflict(c, precondition.Status, *currentRole)
+		return
+	}
+
+	document := map[string]interface{}{
+		"name":        currentRole.Name,
+		"role":        string(currentRole.Role),
+		"description": currentRole.Description,
+	}
+	if currentRole.Description == "" {
+		delete(document, "description")
+	}
+
+	patched, err := helpers.ApplyPatch(c.ContentType(), document, body)
+	if errors.Is(err, helpers.ErrPatchTest) {
+		apierror.Abort(c, apierror.PATCH_TEST_FAILED)
+		return
+	}
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_PATCH, err)
+		return
+	}
+	err = validateRolePatch(patched)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	if _, ok := patched["description"]; !ok {
+		patched["description"] = ""
+	}
+	err = validation.Struct(model.RoleUpdateModel{
+		Id:          currentRole.Id,
+		Name:        patched["name"].(string),
+		Role:        model.EnumRole(patched["role"].(string)),
+		Description: patched["description"].(string),
+	})
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	document["description"] = currentRole.Description
+
+	change := repository.RoleChange{}
+	changed := false
+	if name := patched["name"]; name != document["name"] {
+		name := name.(string)
+		change.Name = &name
+		changed = true
+	}
+	if role := patched["role"]; role != document["role"] {
+		role := model.EnumRole(role.(string))
+		change.Role = &role
+		changed = true
+	}
+	if description := patched["description"]; description != document["description"] {
+		description := description.(string)
+		change.Description = &description
+		changed = true
+	}
+
+	if !changed {
+		c.Header("ETag", helpers.VersionETag(currentRole.Version))
+		c.JSON(http.StatusOK, gin.H{"status": "OK", "data": model.NewRoleResponse(*currentRole)})
+		return
+	}
+
+	if change.Role != nil && *change.Role == model.PLATFORM_SUPERADMIN && !helpers.IsPlatformSuperadmin(c) {
+		apierror.Abort(c, apierror.PLATFORM_ROLE_FORBIDDEN)
+		return
+	}
+
+	change.UpdatedAt = time.Now()
+	var updatedRole *model.RoleModel
+	err = roleController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		if change.Name != nil {
+			err := roleController.unassigned(ctx, scope, currentRole.Id, change.Name)
+			if err != nil {
+				return err
+			}
+		}
+		_, updatedRole, err = roleController.Roles.Update(ctx, scope, currentRole.Id, &currentRole.Version, change)
+		if err != nil {
+			return err
+		}
+		return roleController.Outbox.Add(ctx, model.EVENT_ROLE_UPDATED, updatedRole.OrgId, updatedRole.Id, model.NewRoleResponse(*updatedRole))
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.ROLE_REGISTERED)
+		return
+	}
+	if errors.Is(err, repository.ErrReference) {
+		apierror.Abort(c, apierror.ROLE_IN_USE)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		currentRole, err = roleController.Roles.FindById(c.Request.Context(), scope, currentRole.Id)
+		if err == nil {
+			status := http.StatusConflict
+			if precondition != nil {
+				status = precondition.Status
+			}
+			roleConflict(c, status, *currentRole)
+			return
+		}
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	roleController.Auditor.Record(c, model.AuditEventModel{
+		OrgId:   updatedRole.OrgId,
+		Action:  model.AUDIT_ROLE_UPDATE,
+		Target:  roleTarget(*updatedRole),
+		Changes: audit.Diff(*currentRole, *updatedRole),
+	})
+	roleController.Webhooks.Emit(c, updatedRole.OrgId, model.WEBHOOK_ROLE_UPDATED, model.NewRoleResponse(*updatedRole))
+	c.Header("ETag", helpers.VersionETag(updatedRole.Version))
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": model.NewRoleResponse(*updatedRole)})
+}
+
 // @Summary Delete role by id
 // @Description delete role using id
 // @Param id query string true "id"
@@ -223,18 +443,92 @@ func (roleController RoleController) UpdateRole(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 409 {object} model.ErrorModel "role is assigned to accounts"
+// @Failure default {object} model.ErrorModel "error"
 // @Router /api/v1/role/delete [delete]
 // @Security BearerAuth
 func (roleController RoleController) DeleteRole(c *gin.Context) {
 	id := c.Query("id")
+	scope := helpers.TenantScope(c)
 
-	collection := roleController.MongoClient.Database("test").Collection("role")
-
-	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": id})
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		c.Abort()
+	deletedRole := model.RoleModel{}
+	err := roleController.Outbox.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		err := roleController.unassigned(ctx, scope, id, nil)
+		if err != nil {
+			return err
+		}
+		role, err := roleController.Roles.Delete(ctx, scope, id)
+		if err != nil {
+			return err
+		}
+		deletedRole = *role
+		return roleController.Outbox.Add(ctx, model.EVENT_ROLE_DELETED, deletedRole.OrgId, deletedRole.Id, model.NewRoleResponse(deletedRole))
+	})
+	if errors.Is(err, repository.ErrReference) {
+		apierror.Abort(c, apierror.ROLE_IN_USE)
 		return
 	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Internal(c, err)
+		return
+	}
+	if err == nil {
+		roleController.Auditor.Record(c, model.AuditEventModel{
+			OrgId:   deletedRole.OrgId,
+			Action:  model.AUDIT_ROLE_DELETE,
+			Target:  roleTarget(deletedRole),
+			Changes: audit.Diff(deletedRole, nil),
+		})
+		roleController.Webhooks.Emit(c, deletedRole.OrgId, model.WEBHOOK_ROLE_DELETED, model.NewRoleResponse(deletedRole))
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete role successful"})
 }
+
+// unassigned returns repository.ErrReference when the role with id is
+// assigned to accounts and would be renamed to name, or removed when name is
+// nil. Accounts name their role, so either would leave them without one.
+func (roleController RoleController) unassigned(ctx context.Context, scope repository.Scope, id string, name *string) error {
+	role, err := roleController.Roles.FindById(ctx, scope, id)
+	if err != nil {
+		return err
+	}
+	if name != nil && *name == role.Name {
+		return nil
+	}
+	assigned, err := roleController.Accounts.CountWithRole(ctx, role.OrgId, role.Name)
+	if err != nil {
+		return err
+	}
+	if assigned > 0 {
+		return repository.ErrReference
+	}
+	return nil
+}
+
+// roleConflict reports a failed conditional update together with the
+// current server state so the client can merge and retry.
+func roleConflict(c *gin.Context, status int, role model.RoleModel) {
+	c.Header("ETag", helpers.VersionETag(role.Version))
+	apierror.AbortWithDetails(c, conflictCode(status), model.NewRoleResponse(role))
+}
+
+func roleTarget(role model.RoleModel) model.AuditTargetModel {
+	return model.AuditTargetModel{Type: "role", Id: role.Id}
+}
+
+// validateRolePatch checks every field of a patched role document, the patch
+// itself is free-form so nothing else guards these fields.
+func validateRolePatch(patched map[string]interface{}) error {
+	failures := []validation.Failure{}
+	for field, value := range patched {
+		switch field {
+		case "name", "role":
+			failures = append(failures, patchedText(field, value, true)...)
+		case "description":
+			failures = append(failures, patchedText(field, value, false)...)
+		default:
+			failures = append(failures, validation.Failure{Field: field, Rule: validation.RULE_READ_ONLY})
+		}
+	}
+	return patchFailures(patched, failures, "name", "role")
+}
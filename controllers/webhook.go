@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"ima-svc-management/apierror"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const WEBHOOK_SECRET_MIN_LENGTH = 16
+const WEBHOOK_DEFAULT_PAGE_SIZE = 20
+
+type WebhookController struct {
+	Webhooks   repository.WebhookRepository
+	Dispatcher *webhook.Dispatcher
+}
+
+func InitWebhook(webhooks repository.WebhookRepository, dispatcher *webhook.Dispatcher) *WebhookController {
+	return &WebhookController{
+		Webhooks:   webhooks,
+		Dispatcher: dispatcher,
+	}
+}
+
+// @Summary Add webhook
+// @Description subscribe a url to account, role and session events. The secret signs every delivery in the X-Ima-Signature header, a random one is generated when none is given and it is only returned here. A platform superadmin may leave orgId empty to receive the events of every organization.
+// @Param body body model.WebhookModel true "body"
+// @Tags Webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,data=model.WebhookModel} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/add [post]
+// @Security BearerAuth
+func (webhookController WebhookController) AddWebhook(c *gin.Context) {
+	subscription := model.WebhookModel{}
+	err := c.ShouldBindJSON(&subscription)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	if subscription.Secret == "" {
+		subscription.Secret, err = generateWebhookSecret()
+		if err != nil {
+			apierror.Internal(c, err)
+			return
+		}
+	}
+	err = validateWebhook(webhookController.Dispatcher, subscription)
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_WEBHOOK, err)
+		return
+	}
+
+	orgId := helpers.CallerOrgId(c)
+	if helpers.IsPlatformSuperadmin(c) {
+		orgId = subscription.OrgId
+	}
+	active := true
+	if subscription.Active != nil {
+		active = *subscription.Active
+	}
+
+	subscription.Id = helpers.GenerateId()
+	subscription.OrgId = orgId
+	subscription.Active = &active
+	subscription.CreatedAt = time.Now().Unix()
+	err = webhookController.Webhooks.Create(c.Request.Context(), &subscription)
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": subscription})
+}
+
+// @Summary Get all webhook
+// @Description get every webhook of the organization, secrets are not returned
+// @Tags Webhook
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.WebhookModel} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/getAll [get]
+// @Security BearerAuth
+func (webhookController WebhookController) GetWebhook(c *gin.Context) {
+	datas, err := webhookController.Webhooks.List(c.Request.Context(), helpers.TenantScope(c))
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": datas})
+}
+
+// @Summary Update webhook
+// @Description change the url, events or active flag of a webhook or rotate its secret, omitted fields are kept
+// @Param body body model.WebhookModel true "body"
+// @Tags Webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/update [put]
+// @Security BearerAuth
+func (webhookController WebhookController) UpdateWebhook(c *gin.Context) {
+	subscription := model.WebhookModel{}
+	err := c.ShouldBindJSON(&subscription)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+
+	scope := helpers.TenantScope(c)
+	currentWebhook, err := webhookController.Webhooks.FindById(c.Request.Context(), scope, subscription.Id)
+	if err == repository.ErrNotFound {
+		apierror.Abort(c, apierror.WEBHOOK_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+
+	updatedWebhook := *currentWebhook
+	if subscription.Url != "" {
+		updatedWebhook.Url = subscription.Url
+	}
+	if subscription.Events != nil {
+		updatedWebhook.Events = subscription.Events
+	}
+	if subscription.Secret != "" {
+		updatedWebhook.Secret = subscription.Secret
+	}
+	if subscription.Active != nil {
+		updatedWebhook.Active = subscription.Active
+	}
+	err = validateWebhook(webhookController.Dispatcher, updatedWebhook)
+	if err != nil {
+		apierror.AbortWithReason(c, apierror.INVALID_WEBHOOK, err)
+		return
+	}
+
+	updatedWebhook.UpdatedAt = time.Now().Unix()
+	err = webhookController.Webhooks.Update(c.Request.Context(), scope, updatedWebhook)
+	if err == repository.ErrNotFound {
+		apierror.Abort(c, apierror.WEBHOOK_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Update webhook successful"})
+}
+
+// @Summary Delete webhook by id
+// @Description delete webhook using id, its pending deliveries are dead lettered
+// @Param id query string true "id"
+// @Tags Webhook
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/delete [delete]
+// @Security BearerAuth
+func (webhookController WebhookController) DeleteWebhook(c *gin.Context) {
+	id := c.Query("id")
+
+	err := webhookController.Webhooks.Delete(c.Request.Context(), helpers.TenantScope(c), id)
+	if err != nil && err != repository.ErrNotFound {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delete webhook successful"})
+}
+
+// @Summary Get webhook deliveries
+// @Description delivery log of the organization's webhooks, newest first, with every attempt of each delivery
+// @Param webhookId query string false "webhook id"
+// @Param status query string false "pending, succeeded or dead"
+// @Param event query string false "event type, e.g. account.updated"
+// @Param page query int false "page, starting at 1"
+// @Param size query int false "page size, at most 100"
+// @Tags Webhook
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.WebhookDeliveryModel,page=int,size=int,total=int} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/deliveries [get]
+// @Security BearerAuth
+func (webhookController WebhookController) GetDeliveries(c *gin.Context) {
+	webhookController.findDeliveries(c, "")
+}
+
+// @Summary Get dead lettered webhook deliveries
+// @Description deliveries that failed every attempt or whose webhook was deleted or deactivated, newest first
+// @Param webhookId query string false "webhook id"
+// @Param event query string false "event type, e.g. account.updated"
+// @Param page query int false "page, starting at 1"
+// @Param size query int false "page size, at most 100"
+// @Tags Webhook
+// @Produce  json
+// @Success 200 {object} object{status=string,data=[]model.WebhookDeliveryModel,page=int,size=int,total=int} "ok"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/deadLetters [get]
+// @Security BearerAuth
+func (webhookController WebhookController) GetDeadLetters(c *gin.Context) {
+	webhookController.findDeliveries(c, model.DELIVERY_DEAD)
+}
+
+// @Summary Redeliver webhook delivery
+// @Description queue a delivery again with a fresh retry budget, usually one from the dead letter list
+// @Param id query string true "delivery id"
+// @Tags Webhook
+// @Produce  json
+// @Success 200 {object} object{status=string,message=string} "ok"
+// @Failure 422 {object} model.ErrorModel{details=[]model.FieldErrorModel} "invalid fields"
+// @Failure default {object} model.ErrorModel "error"
+// @Router /api/v1/webhook/redeliver [post]
+// @Security BearerAuth
+func (webhookController WebhookController) Redeliver(c *gin.Context) {
+	err := webhookController.Dispatcher.Redeliver(c.Request.Context(), helpers.TenantScope(c), c.Query("id"))
+	if err == repository.ErrNotFound {
+		apierror.Abort(c, apierror.DELIVERY_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "message": "Delivery queued"})
+}
+
+func (webhookController WebhookController) findDeliveries(c *gin.Context, status model.EnumDeliveryStatus) {
+	query := model.PaginateWebhookDeliveryModel{}
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		validation.Abort(c, err)
+		return
+	}
+	if status != "" {
+		query.Status = string(status)
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Size < 1 {
+		query.Size = WEBHOOK_DEFAULT_PAGE_SIZE
+	}
+
+	deliveries, total, err := webhookController.Webhooks.ListDeliveries(c.Request.Context(), helpers.TenantScope(c), repository.DeliveryQuery{
+		WebhookId: query.WebhookId,
+		Status:    model.EnumDeliveryStatus(query.Status),
+		Event:     model.EnumWebhookEvent(query.Event),
+		Skip:      (query.Page - 1) * query.Size,
+		Size:      query.Size,
+	})
+	if err != nil {
+		apierror.Internal(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "OK", "data": deliveries, "page": query.Page, "size": query.Size, "total": total})
+}
+
+func validateWebhook(dispatcher *webhook.Dispatcher, subscription model.WebhookModel) error {
+	err := dispatcher.ValidateUrl(subscription.Url)
+	if err != nil {
+		return err
+	}
+	if len(subscription.Events) == 0 {
+		return fmt.Errorf("events must name at least one event")
+	}
+	for _, event := range subscription.Events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	if len(subscription.Secret) < WEBHOOK_SECRET_MIN_LENGTH {
+		return fmt.Errorf("secret must have at least %d characters", WEBHOOK_SECRET_MIN_LENGTH)
+	}
+	return nil
+}
+
+func isWebhookEvent(event model.EnumWebhookEvent) bool {
+	for _, known := range model.WEBHOOK_EVENTS {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EMIT_TIMEOUT bounds writing an event that is not part of a data change,
+// it does not use the request context so a client hanging up can not drop
+// the event.
+const EMIT_TIMEOUT = time.Second * 5
+
+// ErrStandalone is returned by Check when the mongo backend is a standalone
+// mongod, which has no transactions to write events in.
+var ErrStandalone = errors.New("mongo is a standalone server, the event outbox needs a replica set or sharded cluster")
+
+// Outbox stores domain events in Events. Events written with Add inside
+// Transaction are committed together with the data change that caused them,
+// the relay publishes them to Redis afterwards.
+type Outbox struct {
+	Events repository.OutboxRepository
+	// Storage runs the transactions with the postgres backend and Database
+	// with the mongo one, the other is nil. Events has to be kept by the
+	// same storage.
+	Storage  repository.Transactor
+	Database *mongo.Database
+	wake     chan struct{}
+}
+
+func InitOutbox(database *mongo.Database, events repository.OutboxRepository, storage repository.Transactor) *Outbox {
+	return &Outbox{
+		Events:   events,
+		Storage:  storage,
+		Database: database,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Check fails when Transaction could not run, the mongo backend on a
+// standalone mongod. Call it on startup.
+func (outbox *Outbox) Check(ctx context.Context) error {
+	if outbox.Storage != nil {
+		return nil
+	}
+	hello := bson.M{}
+	err := outbox.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// servers before 4.4.2 only know isMaster
+		err = outbox.Database.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	if err != nil {
+		return err
+	}
+	_, replicaSet := hello["setName"]
+	if !replicaSet && hello["msg"] != "isdbgrid" {
+		return ErrStandalone
+	}
+	return nil
+}
+
+// Transaction runs fn in a Mongo transaction, or in a transaction of Storage
+// when it is set. Events added with the context passed to fn are committed
+// together with the change.
+func (outbox *Outbox) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if outbox.Storage != nil {
+		err := outbox.Storage.Transaction(ctx, fn)
+		if err == nil {
+			outbox.Wake()
+		}
+		return err
+	}
+
+	session, err := outbox.Database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	if err == nil {
+		outbox.Wake()
+	}
+	return err
+}
+
+// Add writes an event to the outbox, pass the context of Transaction to make
+// it part of the data change.
+func (outbox *Outbox) Add(ctx context.Context, eventType model.EnumDomainEvent, orgId string, aggregateId string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return outbox.Events.Add(ctx, &model.OutboxEventModel{
+		Id:          helpers.GenerateId(),
+		Type:        eventType,
+		OrgId:       orgId,
+		AggregateId: aggregateId,
+		Payload:     string(payload),
+		CreatedAt:   time.Now().UTC(),
+	})
+}
+
+// Emit writes an event that has no data change to go with, such as a
+// session held in Redis. A failure is logged and does not fail the request.
+func (outbox *Outbox) Emit(c *gin.Context, eventType model.EnumDomainEvent, orgId string, aggregateId string, data interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), EMIT_TIMEOUT)
+	defer cancel()
+	err := outbox.Add(ctx, eventType, orgId, aggregateId, data)
+	if err != nil {
+		zerolog.Ctx(c.Request.Context()).Error().Err(err).Str("event", string(eventType)).Msg("writing outbox event")
+		return
+	}
+	outbox.Wake()
+}
+
+// Wake lets the relay publish new events before its next poll.
+func (outbox *Outbox) Wake() {
+	select {
+	case outbox.wake <- struct{}{}:
+	default:
+	}
+}
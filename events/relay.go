@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"ima-svc-management/config"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const RELAY_LOCK_KEY = "events:relay:lock"
+
+// RELAY_LOCK_TTL is how long a relay that stopped renewing its lock keeps
+// other instances from publishing.
+const RELAY_LOCK_TTL = time.Second * 30
+
+// OUTBOX_RETENTION is how long published events stay in the outbox, the
+// relay purges older ones every PURGE_INTERVAL.
+const OUTBOX_RETENTION = time.Hour * 24 * 7
+const PURGE_INTERVAL = time.Hour
+
+// renewLock extends the lock only while it is still held by this relay.
+var renewLock = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLock deletes the lock only while it is still held by this relay.
+var releaseLock = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// Relay publishes outbox events to the Redis stream in the order they were
+// written. One relay across all instances holds a lock in Redis so the order
+// is kept, delivery is at least once and consumers should skip event ids
+// they have already seen.
+type Relay struct {
+	Outbox      *Outbox
+	RedisClient redis.UniversalClient
+	Config      config.EventsConfig
+	Logger      zerolog.Logger
+	owner       string
+	purgedAt    time.Time
+}
+
+func InitRelay(outbox *Outbox, redisClient redis.UniversalClient, eventsConfig config.EventsConfig, logger zerolog.Logger) *Relay {
+	return &Relay{
+		Outbox:      outbox,
+		RedisClient: redisClient,
+		Config:      eventsConfig,
+		Logger:      logger.With().Str("component", "relay").Str("stream", eventsConfig.Stream).Logger(),
+		owner:       helpers.GenerateId(),
+	}
+}
+
+// Run publishes until ctx is cancelled. Events stay in the outbox while
+// Redis is unreachable and are published once it is back.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.Config.PollInterval)
+	defer ticker.Stop()
+	defer releaseLock.Run(context.Background(), relay.RedisClient, []string{RELAY_LOCK_KEY}, relay.owner)
+
+	failing := false
+	for {
+		published, err := relay.round(ctx)
+		if err != nil && ctx.Err() == nil && !failing {
+			relay.Logger.Error().Err(err).Int("published", published).Msg("publishing outbox")
+		}
+		failing = err != nil
+		if published == relay.Config.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-relay.Outbox.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// round publishes one batch if this relay holds the lock and returns how
+// many events were published.
+func (relay *Relay) round(ctx context.Context) (int, error) {
+	locked, err := relay.lock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	relay.purge(ctx)
+	pending, err := relay.Outbox.Events.Pending(ctx, relay.Config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for published, event := range pending {
+		streamId, err := relay.RedisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: relay.Config.Stream,
+			MaxLen: int64(relay.Config.MaxLen),
+			Approx: true,
+			Values: []interface{}{
+				"id", event.Id,
+				"type", string(event.Type),
+				"orgId", event.OrgId,
+				"aggregateId", event.AggregateId,
+				"createdAt", event.CreatedAt.Format(time.RFC3339Nano),
+				"payload", event.Payload,
+			},
+		}).Result()
+		if err != nil {
+			return published, err
+		}
+		err = relay.Outbox.Events.MarkPublished(ctx, event.Id, streamId, time.Now().UTC())
+		if err != nil {
+			return published, err
+		}
+	}
+	return len(pending), nil
+}
+
+// purge removes events published longer than OUTBOX_RETENTION ago, at most
+// once every PURGE_INTERVAL. A failure is retried with the next interval.
+func (relay *Relay) purge(ctx context.Context) {
+	if time.Since(relay.purgedAt) < PURGE_INTERVAL {
+		return
+	}
+	relay.purgedAt = time.Now()
+	before := time.Now().UTC().Add(-OUTBOX_RETENTION)
+	purged, err := relay.Outbox.Events.Purge(ctx, before)
+	if err != nil && ctx.Err() == nil {
+		relay.Logger.Error().Err(err).Time("before", before).Msg("purging outbox")
+		return
+	}
+	if purged > 0 {
+		relay.Logger.Debug().Int64("purged", purged).Time("before", before).Msg("purged outbox")
+	}
+}
+
+func (relay *Relay) lock(ctx context.Context) (bool, error) {
+	locked, err := relay.RedisClient.SetNX(ctx, RELAY_LOCK_KEY, relay.owner, RELAY_LOCK_TTL).Result()
+	if err != nil || locked {
+		return locked, err
+	}
+	renewed, err := renewLock.Run(ctx, relay.RedisClient, []string{RELAY_LOCK_KEY}, relay.owner, RELAY_LOCK_TTL.Milliseconds()).Int()
+	return renewed == 1, err
+}
+
+// Replay reads up to count events published after the stream id after, an
+// empty id starts at the oldest event still kept in the stream.
+func Replay(ctx context.Context, redisClient redis.UniversalClient, stream string, after string, count int64) ([]model.StreamEventModel, error) {
+	start, limit := "-", count
+	if after != "" {
+		// the range includes after itself, read one more to make up for it
+		start, limit = after, count+1
+	}
+	messages, err := redisClient.XRangeN(ctx, stream, start, "+", limit).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.StreamEventModel, 0, len(messages))
+	for _, message := range messages {
+		if message.ID == after {
+			continue
+		}
+		entries = append(entries, model.StreamEventModel{StreamId: message.ID, Fields: message.Values})
+	}
+	if int64(len(entries)) > count {
+		entries = entries[:count]
+	}
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"fmt"
+	"ima-svc-management/model"
+	"regexp"
+)
+
+// ValidateAttributes checks account attributes against the organization schema.
+// Unknown attributes are rejected so typos do not silently create new fields.
+func ValidateAttributes(schemas []model.AttributeSchemaModel, attributes map[string]interface{}) error {
+	known := make(map[string]model.AttributeSchemaModel, len(schemas))
+	for _, schema := range schemas {
+		known[schema.Name] = schema
+	}
+	for name, value := range attributes {
+		schema, ok := known[name]
+		if !ok {
+			return fmt.Errorf("attribute %q is not defined", name)
+		}
+		if err := validateAttributeValue(schema, value); err != nil {
+			return err
+		}
+	}
+	for _, schema := range schemas {
+		if _, ok := attributes[schema.Name]; schema.Required && !ok {
+			return fmt.Errorf("attribute %q is required", schema.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateAttributeFilter makes sure an account listing only filters on defined
+// attributes with scalar values, so the filter can not smuggle query operators.
+func ValidateAttributeFilter(schemas []model.AttributeSchemaModel, attributes map[string]interface{}) error {
+	known := make(map[string]model.AttributeSchemaModel, len(schemas))
+	for _, schema := range schemas {
+		known[schema.Name] = schema
+	}
+	for name, value := range attributes {
+		schema, ok := known[name]
+		if !ok {
+			return fmt.Errorf("attribute %q is not defined", name)
+		}
+		if !isAttributeType(schema.Type, value) {
+			return fmt.Errorf("attribute %q must be a %s", name, schema.Type)
+		}
+	}
+	return nil
+}
+
+// TokenAttributes returns the attributes whose schema allows exposing them as token claims.
+func TokenAttributes(schemas []model.AttributeSchemaModel, attributes map[string]interface{}) map[string]interface{} {
+	claims := make(map[string]interface{})
+	for _, schema := range schemas {
+		if value, ok := attributes[schema.Name]; ok && schema.TokenClaim {
+			claims[schema.Name] = value
+		}
+	}
+	return claims
+}
+
+func validateAttributeValue(schema model.AttributeSchemaModel, value interface{}) error {
+	if !isAttributeType(schema.Type, value) {
+		return fmt.Errorf("attribute %q must be a %s", schema.Name, schema.Type)
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	if len(schema.Enum) > 0 {
+		allowed := false
+		for _, enum := range schema.Enum {
+			if enum == text {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("attribute %q must be one of %v", schema.Name, schema.Enum)
+		}
+	}
+	if schema.Regex != "" {
+		matched, err := regexp.MatchString(schema.Regex, text)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("attribute %q does not match %s", schema.Name, schema.Regex)
+		}
+	}
+	return nil
+}
+
+func isAttributeType(attributeType model.EnumAttributeType, value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return attributeType == model.ATTRIBUTE_STRING
+	case float64, float32, int, int32, int64:
+		return attributeType == model.ATTRIBUTE_NUMBER
+	case bool:
+		return attributeType == model.ATTRIBUTE_BOOLEAN
+	}
+	return false
+}
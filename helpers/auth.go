@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 	"ima-svc-management/config"
-	"os"
-	"strconv"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/joho/godotenv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,6 +28,9 @@ type TokenDetail struct {
 type AccessDetail struct {
 	AccessUUID string
 	Email      string
+	OrgId      string
+	Role       string
+	RoleType   model.EnumRole
 }
 
 type Token struct {
@@ -36,63 +38,108 @@ type Token struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
-const ACCESS_TOKEN_EXPIRATION = time.Minute * 15
-const REFRESH_TOKEN_EXPIRATION = time.Hour * 24
+// Auth signs and verifies the access and refresh tokens, Sessions keeps the
+// token ids that were not logged out or revoked yet.
+type Auth struct {
+	Sessions           repository.SessionStore
+	AccessTokenSecret  []byte
+	RefreshTokenSecret []byte
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	CookieDomain       string
+	CookieSecure       bool
+}
 
-type Auth struct{}
+func InitAuth(sessions repository.SessionStore, authConfig config.AuthConfig) *Auth {
+	return &Auth{
+		Sessions:           sessions,
+		AccessTokenSecret:  []byte(authConfig.AccessTokenSecret),
+		RefreshTokenSecret: []byte(authConfig.RefreshTokenSecret),
+		AccessTokenTTL:     authConfig.AccessTokenTTL,
+		RefreshTokenTTL:    authConfig.RefreshTokenTTL,
+		CookieDomain:       authConfig.CookieDomain,
+		CookieSecure:       authConfig.CookieSecure,
+	}
+}
 
-func (auth Auth) CreateToken(email string) (*TokenDetail, error) {
+// SetRefreshCookie hands the refresh token to the browser in an http only
+// cookie that expires together with the token.
+func (auth Auth) SetRefreshCookie(c *gin.Context, refreshToken string) {
+	c.SetCookie("refresh_token", refreshToken, int(auth.RefreshTokenTTL/time.Second), "/", auth.CookieDomain, auth.CookieSecure, true)
+}
 
-	err := godotenv.Load(".env")
-	if err != nil {
-		return nil, err
-	}
+// CreateToken signs the tokens of account. roleType is the EnumRole of the
+// account's role document, the role claim only carries its name.
+func (auth Auth) CreateToken(account *model.AccountModel, roleType model.EnumRole, attributeClaims map[string]interface{}) (*TokenDetail, error) {
 
+	var err error
 	tokenDetail := &TokenDetail{}
-	tokenDetail.ActiveTokenExpires = time.Now().Add(ACCESS_TOKEN_EXPIRATION).Unix()
+	tokenDetail.ActiveTokenExpires = time.Now().Add(auth.AccessTokenTTL).Unix()
 	tokenDetail.AccessUuid = uuid.New().String()
 
-	tokenDetail.RefreshTokenExpires = time.Now().Add(REFRESH_TOKEN_EXPIRATION).Unix()
+	tokenDetail.RefreshTokenExpires = time.Now().Add(auth.RefreshTokenTTL).Unix()
 	tokenDetail.RefreshUuid = uuid.New().String()
 
 	activeTokenClaims := jwt.MapClaims{}
 	activeTokenClaims["authorized"] = true
 	activeTokenClaims["access_uuid"] = tokenDetail.AccessUuid
-	activeTokenClaims["email"] = email
+	activeTokenClaims["email"] = account.Email
+	activeTokenClaims["org_id"] = account.OrgId
+	activeTokenClaims["role"] = account.Role
+	activeTokenClaims["role_type"] = string(roleType)
+	if len(attributeClaims) > 0 {
+		activeTokenClaims["attributes"] = attributeClaims
+	}
 	activeTokenClaims["exp"] = tokenDetail.ActiveTokenExpires
 	activeToken := jwt.NewWithClaims(jwt.SigningMethodHS256, activeTokenClaims)
-	tokenDetail.AccessToken, err = activeToken.SignedString([]byte(os.Getenv("ACCESS_TOKEN_SECRET")))
+	tokenDetail.AccessToken, err = activeToken.SignedString(auth.AccessTokenSecret)
 	if err != nil {
 		return nil, err
 	}
 
 	refreshTokenClaims := jwt.MapClaims{}
 	refreshTokenClaims["refresh_uuid"] = tokenDetail.RefreshUuid
-	refreshTokenClaims["email"] = email
+	refreshTokenClaims["email"] = account.Email
+	refreshTokenClaims["org_id"] = account.OrgId
 	refreshTokenClaims["exp"] = tokenDetail.RefreshTokenExpires
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	tokenDetail.RefreshToken, err = refreshToken.SignedString([]byte(os.Getenv("REFRESH_TOKEN_SECRET")))
+	tokenDetail.RefreshToken, err = refreshToken.SignedString(auth.RefreshTokenSecret)
 	if err != nil {
 		return nil, err
 	}
 	return tokenDetail, nil
 }
 
-func (auth Auth) CreateAuth(ctx context.Context, email string, tokenDetail *TokenDetail) error {
-	activeToken := time.Unix(tokenDetail.ActiveTokenExpires, 0)
-	refreshToken := time.Unix(tokenDetail.RefreshTokenExpires, 0)
-	now := time.Now()
+func (auth Auth) CreateAuth(ctx context.Context, account *model.AccountModel, tokenDetail *TokenDetail) error {
+	return auth.Sessions.Create(ctx, sessionAccount(account.OrgId, account.Email), model.SessionModel{Email: account.Email, OrgId: account.OrgId},
+		tokenDetail.AccessUuid, time.Unix(tokenDetail.ActiveTokenExpires, 0),
+		tokenDetail.RefreshUuid, time.Unix(tokenDetail.RefreshTokenExpires, 0))
+}
 
-	err := config.RedisClient.Set(ctx, tokenDetail.AccessUuid, email, activeToken.Sub(now)).Err()
-	if err != nil {
-		return err
-	}
-	err = config.RedisClient.Set(ctx, tokenDetail.RefreshUuid, email, refreshToken.Sub(now)).Err()
-	if err != nil {
-		return err
+// ListSessions returns the live sessions of one account, or of every account
+// when email is empty.
+func (auth Auth) ListSessions(ctx context.Context, orgId string, email string) ([]model.SessionInfoModel, error) {
+	account := ""
+	if email != "" {
+		account = sessionAccount(orgId, email)
 	}
+	return auth.Sessions.List(ctx, account)
+}
 
-	return nil
+// RevokeSessions deletes every access and refresh token of an account and
+// returns how many were still alive.
+func (auth Auth) RevokeSessions(ctx context.Context, orgId string, email string) (int64, error) {
+	return auth.Sessions.Revoke(ctx, sessionAccount(orgId, email))
+}
+
+// CountActiveSessions returns how many refresh tokens are still alive.
+func (auth Auth) CountActiveSessions(ctx context.Context) (int64, error) {
+	return auth.Sessions.CountActive(ctx)
+}
+
+// sessionAccount is the key the sessions of one account are indexed by.
+func sessionAccount(orgId string, email string) string {
+	return orgId + ":" + NormalizeEmail(email)
 }
 
 func (auth Auth) ExtractToken(c *gin.Context) string {
@@ -110,7 +157,7 @@ func (auth Auth) VerifyToken(c *gin.Context) (*jwt.Token, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(os.Getenv("ACCESS_TOKEN_SECRET")), nil
+		return auth.AccessTokenSecret, nil
 	})
 	if err != nil {
 		return nil, err
@@ -124,7 +171,7 @@ func (auth Auth) VerifyRefreshToken(c *gin.Context, refreshToken string) (*jwt.T
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(os.Getenv("REFRESH_TOKEN_SECRET")), nil
+		return auth.RefreshTokenSecret, nil
 	})
 	if err != nil {
 		return nil, err
@@ -154,27 +201,24 @@ func (auth Auth) ExtractTokenMetadata(c *gin.Context) (*AccessDetail, error) {
 		if !ok {
 			return nil, err
 		}
+		orgId, _ := claims["org_id"].(string)
+		role, _ := claims["role"].(string)
+		roleType, _ := claims["role_type"].(string)
 		return &AccessDetail{
 			AccessUUID: accessUUID,
 			Email:      claims["email"].(string),
+			OrgId:      orgId,
+			Role:       role,
+			RoleType:   model.EnumRole(roleType),
 		}, nil
 	}
 	return nil, err
 }
 
-func (auth Auth) FetchAuth(ctx context.Context, accessDetail *AccessDetail) (int64, error) {
-	userid, err := config.RedisClient.Get(ctx, accessDetail.AccessUUID).Result()
-	if err != nil {
-		return 0, err
-	}
-	userID, _ := strconv.ParseInt(userid, 10, 64)
-	return userID, nil
+func (auth Auth) FetchAuth(ctx context.Context, accessDetail *AccessDetail) (*model.SessionModel, error) {
+	return auth.Sessions.Fetch(ctx, accessDetail.AccessUUID)
 }
 
 func (auth Auth) DeleteAuth(ctx context.Context, uuid string) (int64, error) {
-	deleted, err := config.RedisClient.Del(ctx, uuid).Result()
-	if err != nil {
-		return 0, err
-	}
-	return deleted, nil
+	return auth.Sessions.Delete(ctx, uuid)
 }
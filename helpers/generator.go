@@ -2,10 +2,23 @@ package helpers
 
 import (
 	"log"
+	"strings"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// GenerateId returns a new document id. Ids are ObjectIDs stored as hex
+// strings, so they are unique regardless of the document content.
+func GenerateId() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// NormalizeEmail is the form emails are compared and indexed in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func GeneratePasswordHash(password []byte) string {
 	hashedPassword, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
 
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const AVATAR_MAX_SIZE = 2 << 20
+const AVATAR_MAX_PIXELS = 4096 * 4096
+
+var AVATAR_THUMBNAIL_SIZES = []int{64, 256}
+var AVATAR_CONTENT_TYPES = []string{"image/png", "image/jpeg", "image/gif"}
+
+var ErrUnsupportedImage = errors.New("avatar must be a png, jpeg or gif image")
+var ErrImageTooLarge = errors.New("avatar dimension is too large")
+
+// DetectImageType sniffs the content type of an uploaded image instead of
+// trusting the type declared by the client.
+func DetectImageType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	for _, allowed := range AVATAR_CONTENT_TYPES {
+		if contentType == allowed {
+			return contentType, nil
+		}
+	}
+	return "", ErrUnsupportedImage
+}
+
+// DecodeImage decodes an uploaded image after checking its dimension, so a
+// small compressed file can not expand into a huge bitmap.
+func DecodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > AVATAR_MAX_PIXELS {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// Thumbnail scales img down to fit inside a size x size box, keeping the aspect
+// ratio, and encodes it as png. Every target pixel averages the source pixels it covers.
+func Thumbnail(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, maxInt(1, height*size/width)
+		} else {
+			width, height = maxInt(1, width*size/height), size
+		}
+	}
+
+	thumbnail := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		top := bounds.Min.Y + y*bounds.Dy()/height
+		bottom := maxInt(top+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			left := bounds.Min.X + x*bounds.Dx()/width
+			right := maxInt(left+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, count uint64
+			for sy := top; sy < bottom; sy++ {
+				for sx := left; sx < right; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			thumbnail.Set(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	err := png.Encode(&buffer, thumbnail)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ContentETag returns a strong ETag for the given content.
+func ContentETag(data []byte) string {
+	hash := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,311 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const MERGE_PATCH_CONTENT_TYPE = "application/merge-patch+json"
+const JSON_PATCH_CONTENT_TYPE = "application/json-patch+json"
+
+var ErrPatchTest = errors.New("json patch test operation failed")
+
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ToPatchDocument converts a value into the generic form patches operate on, so
+// numbers and nested documents compare the same way as values decoded from a request.
+func ToPatchDocument(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]interface{}{}
+	err = json.Unmarshal(raw, &document)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// ApplyPatch applies body to document according to the request content type:
+// RFC 6902 JSON Patch for application/json-patch+json, RFC 7396 JSON Merge
+// Patch otherwise. document is left untouched.
+func ApplyPatch(contentType string, document map[string]interface{}, body []byte) (map[string]interface{}, error) {
+	target, err := deepCopy(document)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(contentType, JSON_PATCH_CONTENT_TYPE) {
+		operations := make([]PatchOperation, 0)
+		err = json.Unmarshal(body, &operations)
+		if err != nil {
+			return nil, fmt.Errorf("json patch must be an array of operations: %v", err)
+		}
+		result, err := ApplyJSONPatch(target, operations)
+		if err != nil {
+			return nil, err
+		}
+		patched, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("json patch must keep the document an object")
+		}
+		return patched, nil
+	}
+
+	patch := map[string]interface{}{}
+	err = json.Unmarshal(body, &patch)
+	if err != nil {
+		return nil, fmt.Errorf("merge patch must be a json object: %v", err)
+	}
+	return MergePatch(target, patch).(map[string]interface{}), nil
+}
+
+// MergePatch implements RFC 7396: objects are merged recursively, null removes
+// a member and any other value replaces the target.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = MergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// ApplyJSONPatch implements the RFC 6902 operations add, remove, replace, move,
+// copy and test. Operations are applied in order and the first failure stops the patch.
+func ApplyJSONPatch(document interface{}, operations []PatchOperation) (interface{}, error) {
+	var err error
+	for index, operation := range operations {
+		document, err = applyOperation(document, operation)
+		if err != nil {
+			return nil, fmt.Errorf("json patch operation %d (%s %s): %w", index, operation.Op, operation.Path, err)
+		}
+	}
+	return document, nil
+}
+
+func applyOperation(document interface{}, operation PatchOperation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case "add":
+		return pointerAdd(document, path, operation.Value, false)
+	case "replace":
+		return pointerAdd(document, path, operation.Value, true)
+	case "remove":
+		document, _, err = pointerRemove(document, path)
+		return document, err
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(document, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+				return nil, errors.New("can not move a value into itself")
+			}
+			document, _, err = pointerRemove(document, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value, err = deepCopy(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pointerAdd(document, path, value, false)
+	case "test":
+		value, err := pointerGet(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, operation.Value) {
+			return nil, ErrPatchTest
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("unsupported operation %q", operation.Op)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("json pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for index, token := range tokens {
+		tokens[index] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q does not exist", token)
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, fmt.Errorf("path member %q does not exist", token)
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd sets value at tokens and returns the possibly replaced node, arrays
+// grow on add so their parent has to store the returned slice.
+func pointerAdd(node interface{}, tokens []string, value interface{}, replace bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token := tokens[0]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if len(tokens) == 1 {
+			if replace && !ok {
+				return nil, fmt.Errorf("path member %q does not exist", token)
+			}
+			container[token] = value
+			return container, nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("path member %q does not exist", token)
+		}
+		child, err := pointerAdd(child, tokens[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+		return container, nil
+	case []interface{}:
+		if len(tokens) == 1 && !replace {
+			if token == "-" {
+				return append(container, value), nil
+			}
+			index, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 1 {
+			container[index] = value
+			return container, nil
+		}
+		child, err := pointerAdd(container[index], tokens[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+		return container, nil
+	}
+	return nil, fmt.Errorf("path member %q does not exist", token)
+}
+
+func pointerRemove(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("can not remove the whole document")
+	}
+	token := tokens[0]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q does not exist", token)
+		}
+		if len(tokens) == 1 {
+			delete(container, token)
+			return container, child, nil
+		}
+		child, removed, err := pointerRemove(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		container[token] = child
+		return container, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tokens) == 1 {
+			removed := container[index]
+			return append(container[:index], container[index+1:]...), removed, nil
+		}
+		child, removed, err := pointerRemove(container[index], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		container[index] = child
+		return container, removed, nil
+	}
+	return nil, nil, fmt.Errorf("path member %q does not exist", token)
+}
+
+// arrayIndex reads an RFC 6901 array index, digits only and without leading
+// zeros.
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("array index %q is out of range", token)
+	}
+	return index, nil
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(raw, &copied)
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
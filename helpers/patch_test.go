@@ -0,0 +1,200 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const patchDocument = `{"name":"Ada","role":"user","tags":["a","b","c"],"attributes":{"team":"red","level":3,"a/b":1,"m~n":2},"list":[{"id":1},{"id":2}]}`
+
+func TestApplyJSONPatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{name: "add member", patch: `[{"op":"add","path":"/email","value":"ada@example.com"}]`,
+			want: `{"email":"ada@example.com"}`},
+		{name: "add replaces existing member", patch: `[{"op":"add","path":"/name","value":"Bob"}]`,
+			want: `{"name":"Bob"}`},
+		{name: "add nested member", patch: `[{"op":"add","path":"/attributes/site","value":"north"}]`,
+			want: `{"attributes":{"team":"red","level":3,"a/b":1,"m~n":2,"site":"north"}}`},
+		{name: "add object", patch: `[{"op":"add","path":"/extra","value":{"x":[1,2]}}]`,
+			want: `{"extra":{"x":[1,2]}}`},
+		{name: "add null", patch: `[{"op":"add","path":"/role","value":null}]`,
+			want: `{"role":null}`},
+		{name: "add array index", patch: `[{"op":"add","path":"/tags/1","value":"x"}]`,
+			want: `{"tags":["a","x","b","c"]}`},
+		{name: "add array start", patch: `[{"op":"add","path":"/tags/0","value":"x"}]`,
+			want: `{"tags":["x","a","b","c"]}`},
+		{name: "add array end index", patch: `[{"op":"add","path":"/tags/3","value":"x"}]`,
+			want: `{"tags":["a","b","c","x"]}`},
+		{name: "add array dash", patch: `[{"op":"add","path":"/tags/-","value":"x"}]`,
+			want: `{"tags":["a","b","c","x"]}`},
+		{name: "add into array element", patch: `[{"op":"add","path":"/list/1/name","value":"two"}]`,
+			want: `{"list":[{"id":1},{"id":2,"name":"two"}]}`},
+		{name: "add escaped member", patch: `[{"op":"add","path":"/attributes/a~1b","value":5}]`,
+			want: `{"attributes":{"team":"red","level":3,"a/b":5,"m~n":2}}`},
+		{name: "add array index past end", patch: `[{"op":"add","path":"/tags/4","value":"x"}]`, err: errAny},
+		{name: "add missing parent", patch: `[{"op":"add","path":"/missing/child","value":1}]`, err: errAny},
+		{name: "add into scalar", patch: `[{"op":"add","path":"/name/first","value":"A"}]`, err: errAny},
+
+		{name: "remove member", patch: `[{"op":"remove","path":"/role"}]`,
+			want: `{"role":"-"}`},
+		{name: "remove nested member", patch: `[{"op":"remove","path":"/attributes/team"}]`,
+			want: `{"attributes":{"level":3,"a/b":1,"m~n":2}}`},
+		{name: "remove escaped member", patch: `[{"op":"remove","path":"/attributes/m~0n"}]`,
+			want: `{"attributes":{"team":"red","level":3,"a/b":1}}`},
+		{name: "remove array element", patch: `[{"op":"remove","path":"/tags/1"}]`,
+			want: `{"tags":["a","c"]}`},
+		{name: "remove missing member", patch: `[{"op":"remove","path":"/missing"}]`, err: errAny},
+		{name: "remove array dash", patch: `[{"op":"remove","path":"/tags/-"}]`, err: errAny},
+		{name: "remove array index past end", patch: `[{"op":"remove","path":"/tags/3"}]`, err: errAny},
+		{name: "remove whole document", patch: `[{"op":"remove","path":""}]`, err: errAny},
+
+		{name: "replace member", patch: `[{"op":"replace","path":"/name","value":"Bob"}]`,
+			want: `{"name":"Bob"}`},
+		{name: "replace array element", patch: `[{"op":"replace","path":"/tags/2","value":"z"}]`,
+			want: `{"tags":["a","b","z"]}`},
+		{name: "replace missing member", patch: `[{"op":"replace","path":"/email","value":"x"}]`, err: errAny},
+		{name: "replace array index past end", patch: `[{"op":"replace","path":"/tags/3","value":"x"}]`, err: errAny},
+		{name: "replace array dash", patch: `[{"op":"replace","path":"/tags/-","value":"x"}]`, err: errAny},
+
+		{name: "move member", patch: `[{"op":"move","from":"/attributes/team","path":"/team"}]`,
+			want: `{"team":"red","attributes":{"level":3,"a/b":1,"m~n":2}}`},
+		{name: "move array element", patch: `[{"op":"move","from":"/tags/0","path":"/tags/-"}]`,
+			want: `{"tags":["b","c","a"]}`},
+		{name: "move to itself", patch: `[{"op":"move","from":"/name","path":"/name"}]`,
+			want: `{}`},
+		{name: "move into own child", patch: `[{"op":"move","from":"/attributes","path":"/attributes/inner"}]`, err: errAny},
+		{name: "move missing member", patch: `[{"op":"move","from":"/missing","path":"/name"}]`, err: errAny},
+
+		{name: "copy member", patch: `[{"op":"copy","from":"/name","path":"/nickname"}]`,
+			want: `{"nickname":"Ada"}`},
+		{name: "copy is deep", patch: `[{"op":"copy","from":"/list/0","path":"/first"},{"op":"replace","path":"/first/id","value":9}]`,
+			want: `{"first":{"id":9},"list":[{"id":1},{"id":2}]}`},
+		{name: "copy missing member", patch: `[{"op":"copy","from":"/missing","path":"/name"}]`, err: errAny},
+
+		{name: "test string", patch: `[{"op":"test","path":"/name","value":"Ada"}]`,
+			want: `{}`},
+		{name: "test number", patch: `[{"op":"test","path":"/attributes/level","value":3}]`,
+			want: `{}`},
+		{name: "test object", patch: `[{"op":"test","path":"/list/1","value":{"id":2}}]`,
+			want: `{}`},
+		{name: "test then replace", patch: `[{"op":"test","path":"/role","value":"user"},{"op":"replace","path":"/role","value":"guest"}]`,
+			want: `{"role":"guest"}`},
+		{name: "test mismatch", patch: `[{"op":"test","path":"/name","value":"Bob"}]`, err: ErrPatchTest},
+		{name: "test type mismatch", patch: `[{"op":"test","path":"/attributes/level","value":"3"}]`, err: ErrPatchTest},
+		{name: "test mismatch stops patch", patch: `[{"op":"test","path":"/role","value":"guest"},{"op":"replace","path":"/role","value":"superadmin"}]`, err: ErrPatchTest},
+		{name: "test missing member", patch: `[{"op":"test","path":"/missing","value":1}]`, err: errAny},
+
+		{name: "pointer without slash", patch: `[{"op":"add","path":"name","value":"Bob"}]`, err: errAny},
+		{name: "array index with leading zero", patch: `[{"op":"replace","path":"/tags/01","value":"x"}]`, err: errAny},
+		{name: "array index with sign", patch: `[{"op":"replace","path":"/tags/+1","value":"x"}]`, err: errAny},
+		{name: "negative array index", patch: `[{"op":"replace","path":"/tags/-1","value":"x"}]`, err: errAny},
+		{name: "array index not a number", patch: `[{"op":"replace","path":"/tags/first","value":"x"}]`, err: errAny},
+		{name: "unknown operation", patch: `[{"op":"merge","path":"/name","value":"Bob"}]`, err: errAny},
+		{name: "not an array", patch: `{"op":"add","path":"/name","value":"Bob"}`, err: errAny},
+		{name: "replace whole document", patch: `[{"op":"replace","path":"","value":["a"]}]`, err: errAny},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			document := decodePatchJSON(t, patchDocument).(map[string]interface{})
+			patched, err := ApplyPatch(JSON_PATCH_CONTENT_TYPE, document, []byte(test.patch))
+			checkPatch(t, patched, err, test.want, test.err)
+			if encodePatchJSON(t, document) != encodePatchJSON(t, decodePatchJSON(t, patchDocument)) {
+				t.Errorf("patch changed the original document: %s", encodePatchJSON(t, document))
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{name: "replace member", patch: `{"name":"Bob"}`,
+			want: `{"name":"Bob"}`},
+		{name: "add member", patch: `{"email":"ada@example.com"}`,
+			want: `{"email":"ada@example.com"}`},
+		{name: "null removes member", patch: `{"role":null}`,
+			want: `{"role":"-"}`},
+		{name: "null removes missing member", patch: `{"missing":null}`,
+			want: `{}`},
+		{name: "merge nested object", patch: `{"attributes":{"team":null,"site":"north"}}`,
+			want: `{"attributes":{"level":3,"a/b":1,"m~n":2,"site":"north"}}`},
+		{name: "array replaces array", patch: `{"tags":["z"]}`,
+			want: `{"tags":["z"]}`},
+		{name: "object replaces scalar", patch: `{"name":{"first":"Ada","last":null}}`,
+			want: `{"name":{"first":"Ada"}}`},
+		{name: "empty patch", patch: `{}`,
+			want: `{}`},
+		{name: "not an object", patch: `["name"]`, err: errAny},
+		{name: "malformed", patch: `{"name":`, err: errAny},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			document := decodePatchJSON(t, patchDocument).(map[string]interface{})
+			patched, err := ApplyPatch(MERGE_PATCH_CONTENT_TYPE, document, []byte(test.patch))
+			checkPatch(t, patched, err, test.want, test.err)
+		})
+	}
+}
+
+// errAny marks a case that must fail without a specific error.
+var errAny = errors.New("any error")
+
+// checkPatch compares patched with patchDocument changed by want: members of
+// want replace the members of the document, "-" marks a removed member.
+func checkPatch(t *testing.T, patched map[string]interface{}, err error, want string, wantErr error) {
+	t.Helper()
+	if wantErr != nil {
+		if err == nil {
+			t.Fatalf("got %s, want an error", encodePatchJSON(t, patched))
+		}
+		if wantErr != errAny && !errors.Is(err, wantErr) {
+			t.Fatalf("got error %v, want %v", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := decodePatchJSON(t, patchDocument).(map[string]interface{})
+	for name, value := range decodePatchJSON(t, want).(map[string]interface{}) {
+		if value == "-" {
+			delete(expected, name)
+			continue
+		}
+		expected[name] = value
+	}
+	if got, want := encodePatchJSON(t, patched), encodePatchJSON(t, expected); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func decodePatchJSON(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var value interface{}
+	err := json.Unmarshal([]byte(raw), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// encodePatchJSON encodes value with sorted object keys so documents can be
+// compared as strings.
+func encodePatchJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
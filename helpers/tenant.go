@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"errors"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CONTEXT_EMAIL = "email"
+const CONTEXT_ORG_ID = "orgId"
+const CONTEXT_ROLE = "role"
+
+// CONTEXT_ROLE_TYPE holds the EnumRole the caller's role grants. Role names
+// are chosen freely by tenants, so authorization only ever looks at this.
+const CONTEXT_ROLE_TYPE = "roleType"
+
+var ErrOrganizationRequired = errors.New("orgId is required")
+
+// IsPlatformSuperadmin reports whether the caller may operate across tenants.
+func IsPlatformSuperadmin(c *gin.Context) bool {
+	return c.GetString(CONTEXT_ROLE_TYPE) == string(model.PLATFORM_SUPERADMIN)
+}
+
+// CallerOrgId returns the organization the caller belongs to.
+func CallerOrgId(c *gin.Context) string {
+	return c.GetString(CONTEXT_ORG_ID)
+}
+
+// TenantScope scopes a repository to the caller's organization. A platform
+// superadmin sees every tenant unless the orgId query parameter narrows it to
+// one.
+func TenantScope(c *gin.Context) repository.Scope {
+	if IsPlatformSuperadmin(c) {
+		if orgId := c.Query("orgId"); orgId != "" {
+			return repository.OrganizationScope(orgId)
+		}
+		return repository.AnyScope()
+	}
+	return repository.OrganizationScope(CallerOrgId(c))
+}
+
+// TenantOrgId resolves the organization a new document is created in. Regular
+// callers always write into their own organization, a platform superadmin has
+// to name the target organization explicitly.
+func TenantOrgId(c *gin.Context, requested string) (string, error) {
+	if !IsPlatformSuperadmin(c) {
+		return CallerOrgId(c), nil
+	}
+	if requested == "" {
+		requested = c.Query("orgId")
+	}
+	if requested == "" {
+		return "", ErrOrganizationRequired
+	}
+	return requested, nil
+}
+
+// IsCaller reports whether the account registered with email in orgId is
+// the one signed in.
+func IsCaller(c *gin.Context, orgId string, email string) bool {
+	return orgId == CallerOrgId(c) && NormalizeEmail(email) == NormalizeEmail(c.GetString(CONTEXT_EMAIL))
+}
+
+// IsOrganizationAdmin reports whether the caller administers its organization.
+func IsOrganizationAdmin(c *gin.Context) bool {
+	return c.GetString(CONTEXT_ROLE_TYPE) == string(model.SUPERADMIN) || IsPlatformSuperadmin(c)
+}
+
+// IsAuditor reports whether the caller may read the audit log of its
+// organization, platform superadmins may read every organization's.
+func IsAuditor(c *gin.Context) bool {
+	return c.GetString(CONTEXT_ROLE_TYPE) == string(model.AUDITOR) || IsPlatformSuperadmin(c)
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidIfMatch = errors.New("If-Match header must be an ETag returned by this service")
+
+// Precondition is the version a client expects to overwrite. Status is the
+// response code used when the stored version no longer matches: 412 for an
+// If-Match header, 409 for a version sent in the body.
+type Precondition struct {
+	Version int64
+	Status  int
+}
+
+// VersionETag formats a document version as an ETag.
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParsePrecondition reads the expected version from the If-Match header,
+// falling back to the version in the request body. It returns nil when the
+// client did not ask for a conditional update.
+func ParsePrecondition(c *gin.Context, bodyVersion int64) (*Precondition, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "*" {
+		return nil, nil
+	}
+	if ifMatch != "" {
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+		if err != nil {
+			return nil, ErrInvalidIfMatch
+		}
+		return &Precondition{Version: version, Status: http.StatusPreconditionFailed}, nil
+	}
+	if bodyVersion > 0 {
+		return &Precondition{Version: bodyVersion, Status: http.StatusConflict}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"ima-svc-management/config"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// New builds the service logger. Every line passes the redactor before it is
+// written, so fields added anywhere in the code can not leak credentials.
+func New(logConfig config.LogConfig) (zerolog.Logger, error) {
+	level, err := zerolog.ParseLevel(logConfig.Level)
+	if err != nil {
+		return zerolog.Logger{}, fmt.Errorf("log level: %w", err)
+	}
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	zerolog.DurationFieldUnit = time.Millisecond
+
+	var output io.Writer = os.Stdout
+	if logConfig.Format == "console" {
+		output = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
+	}
+	return zerolog.New(redactingWriter{next: output}).Level(level).With().Timestamp().Logger(), nil
+}
+
+// Writer adapts the logger to an io.Writer, for the standard library logger
+// and gin's recovery output. Each write becomes one line at level.
+func Writer(logger zerolog.Logger, level zerolog.Level) io.Writer {
+	return levelWriter{logger: logger, level: level}
+}
+
+type levelWriter struct {
+	logger zerolog.Logger
+	level  zerolog.Level
+}
+
+func (writer levelWriter) Write(p []byte) (int, error) {
+	writer.logger.WithLevel(writer.level).Msg(string(bytes.TrimSpace(p)))
+	return len(p), nil
+}
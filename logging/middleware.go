@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"ima-svc-management/helpers"
+	"ima-svc-management/tracing"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+const CONTEXT_REQUEST_ID = "requestId"
+
+// BODY_LOG_LIMIT caps how much of a JSON request body is kept for debug logs.
+const BODY_LOG_LIMIT = 4 << 10
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// RequestId keeps a well formed X-Request-ID from the caller or generates one,
+// echoes it in the response and stores it in the gin context.
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(REQUEST_ID_HEADER)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = uuid.New().String()
+		}
+		c.Set(CONTEXT_REQUEST_ID, requestId)
+		c.Header(REQUEST_ID_HEADER, requestId)
+		c.Next()
+	}
+}
+
+func GetRequestId(c *gin.Context) string {
+	return c.GetString(CONTEXT_REQUEST_ID)
+}
+
+// Middleware attaches a request scoped logger to the request context, usable
+// through zerolog.Ctx, and writes one access line per request. Server errors
+// log at error level, client errors at warn. At debug level headers and JSON
+// bodies are included, after redaction.
+func Middleware(logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestLogger := logger.With().
+			Str("requestId", GetRequestId(c)).
+			Str("traceId", c.GetString(tracing.CONTEXT_TRACE_ID)).
+			Logger()
+		c.Request = c.Request.WithContext(requestLogger.WithContext(c.Request.Context()))
+
+		debug := logger.GetLevel() <= zerolog.DebugLevel
+		var body []byte
+		if debug && strings.HasPrefix(c.ContentType(), "application/") && strings.HasSuffix(c.ContentType(), "json") {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, BODY_LOG_LIMIT))
+			c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body), Closer: c.Request.Body}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		event := requestLogger.Info()
+		if status >= 500 {
+			event = requestLogger.Error()
+		} else if status >= 400 {
+			event = requestLogger.Warn()
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		event = event.
+			Str("method", c.Request.Method).
+			Str("route", route).
+			Str("path", c.Request.URL.RequestURI()).
+			Int("status", status).
+			Dur("latencyMs", time.Since(start)).
+			Int("bytes", c.Writer.Size()).
+			Str("clientIp", c.ClientIP()).
+			Str("userAgent", c.Request.UserAgent())
+		if email := c.GetString(helpers.CONTEXT_EMAIL); email != "" {
+			event = event.Dict("actor", zerolog.Dict().
+				Str("email", email).
+				Str("orgId", c.GetString(helpers.CONTEXT_ORG_ID)).
+				Str("role", c.GetString(helpers.CONTEXT_ROLE)))
+		}
+		if len(c.Errors) > 0 {
+			event = event.Strs("errors", c.Errors.Errors())
+		}
+		if debug {
+			event = event.Interface("headers", c.Request.Header)
+			if len(body) > 0 {
+				decoded := interface{}(nil)
+				if json.Unmarshal(body, &decoded) == nil {
+					event = event.Interface("body", decoded)
+				} else {
+					event = event.Str("body", string(body))
+				}
+			}
+		}
+		event.Msg("request")
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
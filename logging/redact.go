@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const REDACTED = "[REDACTED]"
+
+// SENSITIVE_KEYS are field, header and body member names whose value is never
+// logged, compared case-insensitively.
+var SENSITIVE_KEYS = map[string]bool{
+	"password":      true,
+	"newpassword":   true,
+	"oldpassword":   true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"secret":        true,
+}
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+
+// RedactString masks email addresses down to their first letter and domain and
+// removes bearer and JWT tokens from free text.
+func RedactString(value string) string {
+	value = jwtPattern.ReplaceAllString(value, REDACTED)
+	value = bearerPattern.ReplaceAllString(value, "${1}"+REDACTED)
+	return emailPattern.ReplaceAllString(value, "${1}***@${2}")
+}
+
+// RedactValue walks a decoded JSON value, replacing sensitive members and
+// redacting every string.
+func RedactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, member := range typed {
+			if SENSITIVE_KEYS[strings.ToLower(key)] {
+				typed[key] = REDACTED
+				continue
+			}
+			typed[key] = RedactValue(member)
+		}
+		return typed
+	case []interface{}:
+		for index, member := range typed {
+			typed[index] = RedactValue(member)
+		}
+		return typed
+	case string:
+		return RedactString(typed)
+	}
+	return value
+}
+
+type redactingWriter struct {
+	next io.Writer
+}
+
+// Write receives one JSON object per call from zerolog. Lines that are not
+// JSON only get the free text rules.
+func (writer redactingWriter) Write(p []byte) (int, error) {
+	line := map[string]interface{}{}
+	decoder := json.NewDecoder(strings.NewReader(string(p)))
+	decoder.UseNumber()
+	err := decoder.Decode(&line)
+	if err != nil {
+		_, err = io.WriteString(writer.next, RedactString(string(p)))
+		return len(p), err
+	}
+	redacted, err := json.Marshal(RedactValue(line))
+	if err != nil {
+		return 0, err
+	}
+	_, err = writer.next.Write(append(redacted, '\n'))
+	return len(p), err
+}
@@ -2,13 +2,32 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"ima-svc-management/apierror"
+	"ima-svc-management/audit"
 	"ima-svc-management/config"
 	"ima-svc-management/controllers"
 	docs "ima-svc-management/docs"
+	"ima-svc-management/events"
 	"ima-svc-management/helpers"
+	"ima-svc-management/logging"
+	"ima-svc-management/metrics"
+	"ima-svc-management/model"
+	"ima-svc-management/tracing"
+	"ima-svc-management/validation"
+	"ima-svc-management/webhook"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -20,56 +39,310 @@ import (
 // @in header
 // @name Authorization
 
+const usage = `usage: ima-svc-management [command]
+
+commands:
+  serve         start the HTTP server, the default when no command is given
+  admin         bootstrap superadmins, reset passwords and manage sessions
+  seed          create an organization with the default roles and an optional superadmin
+  migrate       apply or roll back database migrations
+  verify-audit  check the hash chain of the audit log
+`
+
 func main() {
 
-	router := gin.Default()
-	router.Use(gin.Logger())
+	command, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = serve(args)
+	case "admin":
+		err = runAdmin(args)
+	case "seed":
+		err = runSeed(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "verify-audit":
+		err = runVerifyAudit(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(args []string) error {
+
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		return err
+	}
+	log.SetFlags(0)
+	log.SetOutput(logging.Writer(logger, zerolog.InfoLevel))
+
+	err = listen(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("server failed")
+	}
+	return nil
+}
+
+func listen(cfg *config.Config, logger zerolog.Logger) error {
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		shutdownTracing(ctx)
+	}()
+
+	serviceMetrics := metrics.New(logger)
+	if cfg.Log.Format == "json" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	err = validation.Register()
+	if err != nil {
+		return err
+	}
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(logging.Writer(logger, zerolog.ErrorLevel), func(c *gin.Context, recovered interface{}) {
+		apierror.Internal(c, fmt.Errorf("panic: %v", recovered))
+	}))
+	router.Use(logging.RequestId())
+	router.Use(tracing.Middleware(cfg.Tracing.ServiceName)...)
+	router.Use(logging.Middleware(logger))
+	router.Use(serviceMetrics.Middleware())
 	router.Use(CORSMiddleware())
+	router.Use(tracing.Action())
 	docs.SwaggerInfo.BasePath = "/"
-	mongoClient, err := config.Mongo()
+	store, err := openStorage(cfg, serviceMetrics.MongoMonitor(), tracing.MongoMonitor())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	runner, err := store.Migrations()
+	if err != nil {
+		return err
+	}
+	if cfg.Migrate.OnStartup {
+		err = runner.Up(context.TODO(), 0, func(format string, args ...interface{}) {
+			logger.Info().Str("database", store.Backend).Msgf(format, args...)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// the unique indexes come with the migrations, serving without them
+		// would let concurrent requests register the same email twice
+		statuses, err := runner.Status(context.TODO())
+		if err != nil {
+			return err
+		}
+		pending := 0
+		for _, status := range statuses {
+			if !status.Applied {
+				pending++
+			}
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d %s migrations are pending, run \"migrate up\" or set MIGRATE_ON_STARTUP=true", pending, store.Backend)
+		}
+	}
+	var redisClient redis.UniversalClient
+	if cfg.Redis.Configured() {
+		redisClient, err = config.Redis(cfg.Redis)
+		if err != nil {
+			return err
+		}
+		defer redisClient.Close()
+		redisClient.AddHook(serviceMetrics.RedisHook())
+		redisClient.AddHook(tracing.RedisHook())
+	} else {
+		logger.Warn().Msg("redis is not configured, domain events stay in the outbox until it is")
+	}
+	sessions, closeSessions, err := openSessions(cfg, redisClient)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer mongoClient.Disconnect(context.TODO())
-	redisClient, err := config.Redis()
+	defer closeSessions()
+	tokenAuth := helpers.InitAuth(sessions, cfg.Auth)
+	auditor := audit.InitAuditor(store.Audit, cfg.Audit)
+	webhooks := webhook.InitDispatcher(store.Webhooks, cfg.Webhook, logger)
+	outbox := events.InitOutbox(store.Mongo, store.Outbox, store.Transactor)
+	err = outbox.Check(context.TODO())
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer redisClient.Close()
-	accountController := controllers.InitAccount(config.MongoClient)
-	roleController := controllers.InitRole(config.MongoClient)
-	authController := controllers.InitAuth(config.RedisClient, config.MongoClient)
+	accountController := controllers.InitAccount(store.Accounts, store.Roles, store.Organizations, store.Attributes, store.Avatars, auditor, webhooks, outbox, cfg.Compat)
+	roleController := controllers.InitRole(store.Accounts, store.Roles, auditor, webhooks, outbox)
+	authController := controllers.InitAuth(store.Accounts, store.Roles, store.Attributes, tokenAuth, serviceMetrics, auditor, webhooks, outbox)
+	organizationController := controllers.InitOrganization(store.Organizations, store.Accounts, store.Roles, store.Attributes, store.Webhooks, store.Avatars, auditor, outbox)
+	attributeController := controllers.InitAttribute(store.Attributes, store.Accounts, auditor, webhooks, outbox)
+	avatarController := controllers.InitAvatar(store.Avatars, store.Accounts, auditor)
+	auditController := controllers.InitAudit(auditor)
+	webhookController := controllers.InitWebhook(store.Webhooks, webhooks)
+	healthController := controllers.InitHealth(cfg.Server.ReadinessTimeout)
+	healthController.AddCheck(store.Backend, store.Ping)
+	if redisClient != nil {
+		healthController.AddCheck("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+	serviceMetrics.WatchState(tokenAuth.CountActiveSessions, accountController.CountAccountsByRole)
+	router.GET("/metrics", gin.WrapH(serviceMetrics.Handler()))
 
-	mainGroup := router.Group("/api/v1")
+	mainGroup := router.Group("/api/v1", TimeoutMiddleware(cfg.Server.RequestTimeout))
 	{
 		account := mainGroup.Group("/account")
 		{
-			account.POST("/add", accountController.AddAccount)
-			account.GET("/getById", AuthMiddleware(), accountController.GetAccountById)
-			account.GET("/getByEmail", AuthMiddleware(), accountController.GetAccountByEmail)
-			account.POST("/getAll", AuthMiddleware(), accountController.GetAccount)
-			account.PUT("/update", AuthMiddleware(), accountController.UpdateAccount)
-			account.DELETE("/delete", AuthMiddleware(), accountController.DeleteAccount)
+			account.POST("/add", AuthMiddleware(tokenAuth), AdminMiddleware(), accountController.AddAccount)
+			account.GET("/getById", AuthMiddleware(tokenAuth), accountController.GetAccountById)
+			account.GET("/getByEmail", AuthMiddleware(tokenAuth), accountController.GetAccountByEmail)
+			account.POST("/getAll", AuthMiddleware(tokenAuth), accountController.GetAccount)
+			account.PUT("/update", AuthMiddleware(tokenAuth), accountController.UpdateAccount)
+			account.PATCH("/patch", AuthMiddleware(tokenAuth), accountController.PatchAccount)
+			account.DELETE("/delete", AuthMiddleware(tokenAuth), AdminMiddleware(), accountController.DeleteAccount)
+			account.GET("/:id/avatar", AuthMiddleware(tokenAuth), avatarController.GetAvatar)
+		}
+
+		me := mainGroup.Group("/me")
+		{
+			me.PUT("/avatar", AuthMiddleware(tokenAuth), avatarController.UploadAvatar)
+			me.DELETE("/avatar", AuthMiddleware(tokenAuth), avatarController.DeleteAvatar)
 		}
 
 		role := mainGroup.Group("/role")
 		{
-			role.POST("/add", AuthMiddleware(), roleController.AddRole)
-			role.GET("/getById", AuthMiddleware(), roleController.GetRoleById)
-			role.POST("/getAll", AuthMiddleware(), roleController.GetRole)
-			role.PUT("/update", AuthMiddleware(), roleController.UpdateRole)
-			role.DELETE("/delete", AuthMiddleware(), roleController.DeleteRole)
+			role.POST("/add", AuthMiddleware(tokenAuth), AdminMiddleware(), roleController.AddRole)
+			role.GET("/getById", AuthMiddleware(tokenAuth), roleController.GetRoleById)
+			role.POST("/getAll", AuthMiddleware(tokenAuth), roleController.GetRole)
+			role.PUT("/update", AuthMiddleware(tokenAuth), AdminMiddleware(), roleController.UpdateRole)
+			role.PATCH("/patch", AuthMiddleware(tokenAuth), AdminMiddleware(), roleController.PatchRole)
+			role.DELETE("/delete", AuthMiddleware(tokenAuth), AdminMiddleware(), roleController.DeleteRole)
+		}
+
+		organization := mainGroup.Group("/organization")
+		{
+			organization.POST("/add", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), organizationController.AddOrganization)
+			organization.GET("/getById", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), organizationController.GetOrganizationById)
+			organization.POST("/getAll", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), organizationController.GetOrganization)
+			organization.PUT("/update", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), organizationController.UpdateOrganization)
+			organization.DELETE("/delete", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), organizationController.DeleteOrganization)
+		}
+
+		attribute := mainGroup.Group("/attribute")
+		{
+			attribute.POST("/add", AuthMiddleware(tokenAuth), AdminMiddleware(), attributeController.AddAttribute)
+			attribute.POST("/getAll", AuthMiddleware(tokenAuth), attributeController.GetAttribute)
+			attribute.PUT("/update", AuthMiddleware(tokenAuth), AdminMiddleware(), attributeController.UpdateAttribute)
+			attribute.DELETE("/delete", AuthMiddleware(tokenAuth), AdminMiddleware(), attributeController.DeleteAttribute)
 		}
 
 		auth := mainGroup.Group("/auth")
 		{
 			auth.POST("/login", authController.Login)
-			auth.POST("/logout", AuthMiddleware(), authController.Logout)
+			auth.POST("/logout", AuthMiddleware(tokenAuth), authController.Logout)
 			auth.GET("/refresh", authController.Refresh)
 		}
+
+		webhookGroup := mainGroup.Group("/webhook")
+		{
+			webhookGroup.POST("/add", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.AddWebhook)
+			webhookGroup.GET("/getAll", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.GetWebhook)
+			webhookGroup.PUT("/update", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.UpdateWebhook)
+			webhookGroup.DELETE("/delete", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.DeleteWebhook)
+			webhookGroup.GET("/deliveries", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.GetDeliveries)
+			webhookGroup.GET("/deadLetters", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.GetDeadLetters)
+			webhookGroup.POST("/redeliver", AuthMiddleware(tokenAuth), AdminMiddleware(), webhookController.Redeliver)
+		}
+
+		if redisClient != nil {
+			eventController := controllers.InitEvent(redisClient, cfg.Events)
+			mainGroup.GET("/events", AuthMiddleware(tokenAuth), PlatformSuperadminMiddleware(), eventController.GetEvents)
+		}
+
+		mainGroup.GET("/audit", AuthMiddleware(tokenAuth), AuditorMiddleware(), auditController.GetAudit)
+		mainGroup.GET("/audit/verify", AuthMiddleware(tokenAuth), AuditorMiddleware(), auditController.VerifyAudit)
 	}
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
-	router.Run(":45541")
+	router.NoRoute(func(c *gin.Context) {
+		apierror.Abort(c, apierror.NOT_FOUND)
+	})
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler: router,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := sync.WaitGroup{}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		webhooks.Run(workerCtx)
+	}()
+	if redisClient != nil {
+		relay := events.InitRelay(outbox, redisClient, cfg.Events, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			relay.Run(workerCtx)
+		}()
+	}
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info().Str("addr", server.Addr).Msg("listening")
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	logger.Info().Dur("timeout", cfg.Server.ShutdownTimeout).Msg("shutting down, draining requests")
+	healthController.ShuttingDown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("graceful shutdown: %w", err)
+	}
+	logger.Info().Msg("server stopped")
+	return nil
 
 }
 
@@ -77,8 +350,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match, traceparent, tracestate, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Trace-Id, X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -89,17 +363,70 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+// TimeoutMiddleware puts a deadline on the request context. Every database
+// call of the handlers derives its context from it, so calls stop once the
+// deadline passes or the client disconnects.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func AuthMiddleware(auth *helpers.Auth) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		auth := helpers.Auth{}
 		err := auth.TokenValid(c)
 		if err != nil && err.Error() == "Token is expired" {
-			c.JSON(http.StatusUnauthorized, "Token is expired")
-			c.Abort()
+			apierror.Abort(c, apierror.TOKEN_EXPIRED)
 			return
 		} else if err != nil {
-			c.JSON(http.StatusUnauthorized, "Invalid Token")
-			c.Abort()
+			apierror.Abort(c, apierror.INVALID_TOKEN)
+			return
+		}
+		accessDetail, err := auth.ExtractTokenMetadata(c)
+		if err != nil || (accessDetail.OrgId == "" && accessDetail.RoleType != model.PLATFORM_SUPERADMIN) {
+			apierror.Abort(c, apierror.INVALID_TOKEN)
+			return
+		}
+		_, err = auth.FetchAuth(c.Request.Context(), accessDetail)
+		if err != nil {
+			apierror.Abort(c, apierror.INVALID_TOKEN)
+			return
+		}
+		c.Set(helpers.CONTEXT_EMAIL, accessDetail.Email)
+		c.Set(helpers.CONTEXT_ORG_ID, accessDetail.OrgId)
+		c.Set(helpers.CONTEXT_ROLE, accessDetail.Role)
+		c.Set(helpers.CONTEXT_ROLE_TYPE, string(accessDetail.RoleType))
+		c.Next()
+	}
+}
+
+func PlatformSuperadminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !helpers.IsPlatformSuperadmin(c) {
+			apierror.Abort(c, apierror.FORBIDDEN)
+			return
+		}
+		c.Next()
+	}
+}
+
+func AuditorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !helpers.IsAuditor(c) {
+			apierror.Abort(c, apierror.FORBIDDEN)
+			return
+		}
+		c.Next()
+	}
+}
+
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !helpers.IsOrganizationAdmin(c) {
+			apierror.Abort(c, apierror.FORBIDDEN)
 			return
 		}
 		c.Next()
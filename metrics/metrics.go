@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+const NAMESPACE = "ima"
+
+const OUTCOME_SUCCESS = "success"
+const OUTCOME_ERROR = "error"
+const OUTCOME_INVALID_CREDENTIALS = "invalid_credentials"
+const OUTCOME_UNKNOWN_EMAIL = "unknown_email"
+const OUTCOME_ORGANIZATION_REQUIRED = "organization_required"
+const OUTCOME_INVALID_TOKEN = "invalid_token"
+const OUTCOME_REUSED_TOKEN = "reused_token"
+
+// STATE_TIMEOUT bounds the Mongo and Redis reads made while a scrape collects
+// the session and account gauges.
+const STATE_TIMEOUT = time.Second * 5
+
+// Metrics holds every collector of the service on its own registry so
+// /metrics only exposes what is registered here.
+type Metrics struct {
+	Registry        *prometheus.Registry
+	HttpRequests    *prometheus.CounterVec
+	HttpDuration    *prometheus.HistogramVec
+	Logins          *prometheus.CounterVec
+	Refreshes       *prometheus.CounterVec
+	Logouts         prometheus.Counter
+	TokenReuse      prometheus.Counter
+	MongoDuration   *prometheus.HistogramVec
+	RedisDuration   *prometheus.HistogramVec
+	activeSessions  *prometheus.Desc
+	accountsByRole  *prometheus.Desc
+	sessionCounter  func(ctx context.Context) (int64, error)
+	accountsCounter func(ctx context.Context) (map[string]int64, error)
+	logger          zerolog.Logger
+}
+
+// New builds the collectors, logger reports the stores that could not be
+// read during a scrape.
+func New(logger zerolog.Logger) *Metrics {
+	metrics := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		logger:   logger.With().Str("component", "metrics").Logger(),
+		HttpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		HttpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "auth_logins_total",
+			Help:      "Login attempts by outcome.",
+		}, []string{"outcome"}),
+		Refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "auth_refreshes_total",
+			Help:      "Token refreshes by outcome.",
+		}, []string{"outcome"}),
+		Logouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "auth_logouts_total",
+			Help:      "Successful logouts.",
+		}),
+		TokenReuse: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "auth_token_reuse_total",
+			Help:      "Refresh tokens presented again after they were rotated or revoked.",
+		}),
+		MongoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "mongo_command_duration_seconds",
+			Help:      "MongoDB command latency by command and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"command", "outcome"}),
+		RedisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Redis command latency by command and outcome.",
+			Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5},
+		}, []string{"command", "outcome"}),
+		activeSessions: prometheus.NewDesc(NAMESPACE+"_sessions_active", "Refresh tokens that are neither expired nor revoked.", nil, nil),
+		accountsByRole: prometheus.NewDesc(NAMESPACE+"_accounts", "Accounts by role.", []string{"role"}, nil),
+	}
+	metrics.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.HttpRequests,
+		metrics.HttpDuration,
+		metrics.Logins,
+		metrics.Refreshes,
+		metrics.Logouts,
+		metrics.TokenReuse,
+		metrics.MongoDuration,
+		metrics.RedisDuration,
+	)
+	return metrics
+}
+
+// WatchState registers the gauges read from the stores at scrape time, the
+// functions are called with a context bounded by STATE_TIMEOUT.
+func (metrics *Metrics) WatchState(sessionCounter func(ctx context.Context) (int64, error), accountsCounter func(ctx context.Context) (map[string]int64, error)) {
+	metrics.sessionCounter = sessionCounter
+	metrics.accountsCounter = accountsCounter
+	metrics.Registry.MustRegister(stateCollector{metrics: metrics})
+}
+
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request under its route template, requests that
+// match no route share the "unmatched" label to keep cardinality bounded.
+func (metrics *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HttpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HttpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+type stateCollector struct {
+	metrics *Metrics
+}
+
+func (collector stateCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.metrics.activeSessions
+	descs <- collector.metrics.accountsByRole
+}
+
+func (collector stateCollector) Collect(values chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), STATE_TIMEOUT)
+	defer cancel()
+
+	sessions, err := collector.metrics.sessionCounter(ctx)
+	if err != nil {
+		collector.metrics.logger.Error().Err(err).Msg("counting active sessions")
+	} else {
+		values <- prometheus.MustNewConstMetric(collector.metrics.activeSessions, prometheus.GaugeValue, float64(sessions))
+	}
+
+	accounts, err := collector.metrics.accountsCounter(ctx)
+	if err != nil {
+		collector.metrics.logger.Error().Err(err).Msg("counting accounts")
+		return
+	}
+	for role, count := range accounts {
+		values <- prometheus.MustNewConstMetric(collector.metrics.accountsByRole, prometheus.GaugeValue, float64(count), role)
+	}
+}
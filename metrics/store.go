@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MongoMonitor observes the latency of every command the driver sends.
+func (metrics *Metrics) MongoMonitor() *event.CommandMonitor {
+	commands := sync.Map{}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+			commands.Store(started.RequestID, started.CommandName)
+		},
+		Succeeded: func(ctx context.Context, succeeded *event.CommandSucceededEvent) {
+			metrics.observeMongo(&commands, succeeded.CommandFinishedEvent, OUTCOME_SUCCESS)
+		},
+		Failed: func(ctx context.Context, failed *event.CommandFailedEvent) {
+			metrics.observeMongo(&commands, failed.CommandFinishedEvent, OUTCOME_ERROR)
+		},
+	}
+}
+
+func (metrics *Metrics) observeMongo(commands *sync.Map, finished event.CommandFinishedEvent, outcome string) {
+	command, ok := commands.LoadAndDelete(finished.RequestID)
+	if !ok {
+		command = finished.CommandName
+	}
+	duration := time.Duration(finished.DurationNanos)
+	metrics.MongoDuration.WithLabelValues(command.(string), outcome).Observe(duration.Seconds())
+}
+
+type redisStartKey struct{}
+
+// RedisHook observes the latency of every command and pipeline, a missing
+// key is a normal answer and counts as success.
+func (metrics *Metrics) RedisHook() redis.Hook {
+	return redisHook{metrics: metrics}
+}
+
+type redisHook struct {
+	metrics *Metrics
+}
+
+func (hook redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (hook redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	hook.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (hook redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (hook redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	hook.observe(ctx, "pipeline", err)
+	return nil
+}
+
+func (hook redisHook) observe(ctx context.Context, command string, err error) {
+	start, ok := ctx.Value(redisStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	outcome := OUTCOME_SUCCESS
+	if err != nil && err != redis.Nil {
+		outcome = OUTCOME_ERROR
+	}
+	hook.metrics.RedisDuration.WithLabelValues(command, outcome).Observe(time.Since(start).Seconds())
+}
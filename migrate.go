@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ima-svc-management/config"
+	"ima-svc-management/migrations"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: ima-svc-management migrate <command> [flags]
+
+commands:
+  status            list every migration and whether it is applied
+  up [-to VERSION]  apply pending migrations, up to VERSION when given
+  down [-steps N]   roll back the last N applied migrations (default 1)
+
+only the migrations of the database named by storage.backend are handled,
+-database mongo or -database postgres fails when it names the other one.
+down only rolls back Postgres migrations with -database postgres, they drop
+the tables with their rows.
+`
+
+// migrationRunner is what the Mongo and the Postgres runner have in common.
+type migrationRunner interface {
+	Status(ctx context.Context) ([]migrations.MigrationStatus, error)
+	Up(ctx context.Context, target int64, log func(format string, args ...interface{})) error
+	Down(ctx context.Context, steps int, log func(format string, args ...interface{})) error
+}
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("migrate command is required")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	loader := config.Bind(flags)
+	to := int64(0)
+	steps := 1
+	only := flags.String("database", "", "only migrate this database, mongo or postgres")
+	switch args[0] {
+	case "status":
+	case "up":
+		flags.Int64Var(&to, "to", 0, "apply migrations up to and including this version, 0 applies all")
+	case "down":
+		flags.IntVar(&steps, "steps", 1, "number of applied migrations to roll back")
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.STORAGE)
+	if err != nil {
+		return err
+	}
+
+	if *only != "" && *only != cfg.Storage.Backend {
+		return fmt.Errorf("-database %q is not in use, storage.backend is %s", *only, cfg.Storage.Backend)
+	}
+	if args[0] == "down" && cfg.Storage.Backend == "postgres" && *only == "" {
+		// rolling back the postgres schema drops the accounts, only on request
+		return fmt.Errorf("down drops postgres tables, pass -database postgres to roll back anyway")
+	}
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	runner, err := store.Migrations()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	name := store.Backend
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if args[0] == "status" {
+		fmt.Fprintln(writer, "DATABASE\tVERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+	}
+	logger := func(format string, args ...interface{}) {
+		log.Printf(name+": "+format, args...)
+	}
+	switch args[0] {
+	case "up":
+		err = runner.Up(ctx, to, logger)
+	case "down":
+		err = runner.Down(ctx, steps, logger)
+	default:
+		err = printMigrationStatus(ctx, writer, name, runner)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return writer.Flush()
+}
+
+func printMigrationStatus(ctx context.Context, writer *tabwriter.Writer, name string, runner migrationRunner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\n", name, status.Version, state, appliedAt, status.Description)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations is the ordered schema history. Append new migrations at the end
+// with the next version, never edit one that has been released.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "backfill normalized account email",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			filter := bson.M{"emailNormalized": bson.M{"$exists": false}, "email": bson.M{"$type": "string"}}
+			update := mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{"emailNormalized": bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}}}},
+			}
+			_, err := database.Collection("account").UpdateMany(ctx, filter, update)
+			return err
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("account").UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"emailNormalized": ""}})
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "create unique indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for collection, indexes := range uniqueIndexes {
+				_, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for collection, indexes := range uniqueIndexes {
+				for _, index := range indexes {
+					_, err := database.Collection(collection).Indexes().DropOne(ctx, *index.Options.Name)
+					if err != nil && !isIndexNotFound(err) {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "store role timestamps as dates instead of unix seconds",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return convertRoleTimestamps(ctx, database, "number", func(field string) interface{} {
+				return bson.M{"$toDate": bson.M{"$multiply": bson.A{"$" + field, 1000}}}
+			})
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			return convertRoleTimestamps(ctx, database, "date", func(field string) interface{} {
+				return bson.M{"$toLong": bson.M{"$divide": bson.A{bson.M{"$toLong": "$" + field}, 1000}}}
+			})
+		},
+	},
+	{
+		Version:     4,
+		Description: "backfill account and role version",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, collection := range []string{"account", "role"} {
+				_, err := database.Collection(collection).UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     5,
+		Description: "create audit log query indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("audit_log").Indexes().CreateMany(ctx, auditIndexes)
+			return err
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for _, index := range auditIndexes {
+				_, err := database.Collection("audit_log").Indexes().DropOne(ctx, *index.Options.Name)
+				if err != nil && !isIndexNotFound(err) {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     6,
+		Description: "create audit chain sequence indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for collection, index := range chainIndexes {
+				_, err := database.Collection(collection).Indexes().CreateOne(ctx, index)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for collection, index := range chainIndexes {
+				_, err := database.Collection(collection).Indexes().DropOne(ctx, *index.Options.Name)
+				if err != nil && !isIndexNotFound(err) {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     7,
+		Description: "create webhook and delivery indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for collection, indexes := range webhookIndexes {
+				_, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for collection, indexes := range webhookIndexes {
+				for _, index := range indexes {
+					_, err := database.Collection(collection).Indexes().DropOne(ctx, *index.Options.Name)
+					if err != nil && !isIndexNotFound(err) {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     8,
+		Description: "create event outbox indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			_, err := database.Collection("outbox").Indexes().CreateMany(ctx, outboxIndexes)
+			return err
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for _, index := range outboxIndexes {
+				_, err := database.Collection("outbox").Indexes().DropOne(ctx, *index.Options.Name)
+				if err != nil && !isIndexNotFound(err) {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// outboxIndexes serve the relay looking for unpublished events in order and
+// expire published events once they are a week old. The collection has to
+// exist before the first transaction writes to it on MongoDB before 4.4,
+// creating the indexes takes care of that.
+var outboxIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "publishedAt", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("outbox_pending"),
+	},
+	{
+		Keys:    bson.D{{Key: "publishedAt", Value: 1}},
+		Options: options.Index().SetName("outbox_published_ttl").SetExpireAfterSeconds(7 * 24 * 60 * 60),
+	},
+}
+
+// webhookIndexes serve the subscription lookup on every event, the workers
+// looking for due deliveries and the delivery log endpoints.
+var webhookIndexes = map[string][]mongo.IndexModel{
+	"webhook": {
+		{
+			Keys:    bson.D{{Key: "orgId", Value: 1}, {Key: "events", Value: 1}},
+			Options: options.Index().SetName("webhook_org_events"),
+		},
+	},
+	"webhook_delivery": {
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+			Options: options.Index().SetName("webhook_delivery_due"),
+		},
+		{
+			Keys:    bson.D{{Key: "orgId", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("webhook_delivery_org_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("webhook_delivery_webhook_created"),
+		},
+	},
+}
+
+// chainIndexes keep one event per sequence number so concurrent writers can
+// not fork the audit hash chain. Events from before the chain have none.
+var chainIndexes = map[string]mongo.IndexModel{
+	"audit_log": {
+		Keys: bson.D{{Key: "sequence", Value: 1}},
+		Options: options.Index().SetName("audit_log_sequence_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"sequence": bson.M{"$exists": true}}),
+	},
+	"audit_checkpoint": {
+		Keys:    bson.D{{Key: "sequence", Value: 1}},
+		Options: options.Index().SetName("audit_checkpoint_sequence_unique").SetUnique(true),
+	},
+}
+
+// auditIndexes serve the audit endpoint, which always sorts by createdAt and
+// usually narrows by organization.
+var auditIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "orgId", Value: 1}, {Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName("audit_log_org_created"),
+	},
+	{
+		Keys:    bson.D{{Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName("audit_log_created"),
+	},
+	{
+		Keys:    bson.D{{Key: "target.id", Value: 1}, {Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName("audit_log_target_created"),
+	},
+	{
+		Keys:    bson.D{{Key: "actor.email", Value: 1}, {Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName("audit_log_actor_created"),
+	},
+}
+
+var uniqueIndexes = map[string][]mongo.IndexModel{
+	"organization": {
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetName("organization_name_unique").SetUnique(true),
+		},
+	},
+	"account": {
+		{
+			Keys: bson.D{{Key: "orgId", Value: 1}, {Key: "emailNormalized", Value: 1}},
+			Options: options.Index().SetName("account_org_email_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"emailNormalized": bson.M{"$exists": true}}),
+		},
+	},
+	"role": {
+		{
+			Keys:    bson.D{{Key: "orgId", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("role_org_name_unique").SetUnique(true),
+		},
+	},
+	"attribute_schema": {
+		{
+			Keys:    bson.D{{Key: "orgId", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("attribute_schema_org_name_unique").SetUnique(true),
+		},
+	},
+}
+
+func convertRoleTimestamps(ctx context.Context, database *mongo.Database, fromType string, convert func(field string) interface{}) error {
+	for _, field := range []string{"createdAt", "updatedAt"} {
+		filter := bson.M{field: bson.M{"$type": fromType}}
+		update := mongo.Pipeline{{{Key: "$set", Value: bson.M{field: convert(field)}}}}
+		_, err := database.Collection("role").UpdateMany(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isIndexNotFound(err error) bool {
+	commandErr, ok := err.(mongo.CommandError)
+	return ok && (commandErr.Code == 27 || commandErr.Name == "IndexNotFound" || commandErr.Name == "NamespaceNotFound")
+}
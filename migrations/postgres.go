@@ -0,0 +1,244 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// POSTGRES_LOCK_ID is the advisory lock held while Postgres migrations run.
+const POSTGRES_LOCK_ID = 4554101
+
+//go:embed postgres/*.sql
+var postgresFiles embed.FS
+
+// SqlMigration is one schema change of the postgres storage backend, read
+// from postgres/<version>_<description>.up.sql and the matching .down.sql.
+type SqlMigration struct {
+	Version     int64
+	Description string
+	Up          string
+	Down        string
+}
+
+// PostgresRunner applies the SQL migrations, each one in a transaction
+// together with its record in schema_migrations.
+type PostgresRunner struct {
+	DB         *sql.DB
+	Migrations []SqlMigration
+}
+
+func InitPostgresRunner(db *sql.DB) (*PostgresRunner, error) {
+	migrations, err := readSqlMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresRunner{
+		DB:         db,
+		Migrations: migrations,
+	}, nil
+}
+
+func (runner PostgresRunner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := runner.applied(ctx, runner.DB)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(runner.Migrations))
+	for _, migration := range runner.Migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet.
+func (runner PostgresRunner) Pending(ctx context.Context) ([]SqlMigration, error) {
+	applied, err := runner.applied(ctx, runner.DB)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]SqlMigration, 0)
+	for _, migration := range runner.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration up to and including target, 0 means all.
+func (runner PostgresRunner) Up(ctx context.Context, target int64, log func(format string, args ...interface{})) error {
+	return runner.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := runner.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range runner.Migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if target > 0 && migration.Version > target {
+				break
+			}
+			log("applying %d %s", migration.Version, migration.Description)
+			err = runner.apply(ctx, conn, migration.Up,
+				"INSERT INTO "+MIGRATION_COLLECTION+" (version, description, applied_at) VALUES ($1, $2, $3)",
+				migration.Version, migration.Description, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("migration %d up: %w", migration.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (runner PostgresRunner) Down(ctx context.Context, steps int, log func(format string, args ...interface{})) error {
+	return runner.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := runner.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for index := len(runner.Migrations) - 1; index >= 0 && steps > 0; index-- {
+			migration := runner.Migrations[index]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d: %w", migration.Version, ErrIrreversible)
+			}
+			log("rolling back %d %s", migration.Version, migration.Description)
+			err = runner.apply(ctx, conn, migration.Down, "DELETE FROM "+MIGRATION_COLLECTION+" WHERE version = $1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d down: %w", migration.Version, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// apply runs script and the statement recording it in one transaction.
+func (runner PostgresRunner) apply(ctx context.Context, conn *sql.Conn, script string, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, script)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, record, args...)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (runner PostgresRunner) applied(ctx context.Context, db execQuerier) (map[int64]time.Time, error) {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+MIGRATION_COLLECTION+" (version BIGINT PRIMARY KEY, description TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM "+MIGRATION_COLLECTION)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		version, appliedAt := int64(0), time.Time{}
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// locked runs fn on one connection holding the advisory lock, which Postgres
+// releases by itself when the connection of a crashed runner goes away.
+func (runner PostgresRunner) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := runner.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	acquired := false
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", POSTGRES_LOCK_ID).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrLocked
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", POSTGRES_LOCK_ID)
+	return fn(conn)
+}
+
+func readSqlMigrations() ([]SqlMigration, error) {
+	entries, err := postgresFiles.ReadDir("postgres")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*SqlMigration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction := strings.TrimSuffix(name, ".sql"), ""
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			base, direction = strings.TrimSuffix(base, ".up"), "up"
+		case strings.HasSuffix(base, ".down"):
+			base, direction = strings.TrimSuffix(base, ".down"), "down"
+		default:
+			return nil, fmt.Errorf("migration file %s must end in .up.sql or .down.sql", name)
+		}
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("migration file %s must be named <version>_<description>", name)
+		}
+		script, err := postgresFiles.ReadFile(path.Join("postgres", name))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &SqlMigration{Version: version, Description: strings.ReplaceAll(parts[1], "_", " ")}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]SqlMigration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
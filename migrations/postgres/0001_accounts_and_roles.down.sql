@@ -0,0 +1,2 @@
+DROP TABLE accounts;
+DROP TABLE roles;
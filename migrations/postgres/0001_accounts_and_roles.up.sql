@@ -0,0 +1,37 @@
+-- roles are unique by name within their organization, accounts reference
+-- them by that name
+CREATE TABLE roles (
+    id          TEXT PRIMARY KEY,
+    org_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    role        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    version     BIGINT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ,
+    CONSTRAINT roles_org_name_unique UNIQUE (org_id, name)
+);
+
+-- org_id is null for platform superadmins, which reference no role
+CREATE TABLE accounts (
+    id               TEXT PRIMARY KEY,
+    org_id           TEXT,
+    name             TEXT NOT NULL DEFAULT '',
+    email            TEXT NOT NULL,
+    email_normalized TEXT NOT NULL,
+    role             TEXT,
+    password         TEXT NOT NULL,
+    attributes       JSONB,
+    version          BIGINT NOT NULL DEFAULT 0,
+    created_at       BIGINT NOT NULL,
+    updated_at       BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT accounts_role_fkey FOREIGN KEY (org_id, role) REFERENCES roles (org_id, name)
+        ON UPDATE RESTRICT ON DELETE RESTRICT
+);
+
+-- coalesce keeps the email of accounts without organization unique as well
+CREATE UNIQUE INDEX accounts_org_email_unique ON accounts (coalesce(org_id, ''), email_normalized);
+CREATE INDEX accounts_email_normalized ON accounts (email_normalized);
+CREATE INDEX accounts_org_created ON accounts (org_id, created_at, id);
+CREATE INDEX accounts_attributes ON accounts USING gin (attributes jsonb_path_ops);
+CREATE INDEX roles_org_created ON roles (org_id, created_at, id);
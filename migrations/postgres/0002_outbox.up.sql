@@ -0,0 +1,15 @@
+-- domain events of account and role changes, written in the transaction of
+-- the change and published by the relay
+CREATE TABLE outbox (
+    id           TEXT PRIMARY KEY,
+    type         TEXT NOT NULL,
+    org_id       TEXT NOT NULL DEFAULT '',
+    aggregate_id TEXT NOT NULL DEFAULT '',
+    payload      TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    published_at TIMESTAMPTZ,
+    stream_id    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_pending ON outbox (created_at, id) WHERE published_at IS NULL;
+CREATE INDEX outbox_published ON outbox (published_at) WHERE published_at IS NOT NULL;
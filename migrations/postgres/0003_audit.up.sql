@@ -0,0 +1,29 @@
+-- the audit hash chain. content is the exact JSON the hash covers and every
+-- event is read back from it, the other columns only serve the queries
+CREATE TABLE audit_log (
+    id          TEXT PRIMARY KEY,
+    sequence    BIGINT NOT NULL,
+    org_id      TEXT,
+    action      TEXT NOT NULL,
+    actor_email TEXT NOT NULL DEFAULT '',
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    hash        TEXT NOT NULL,
+    content     TEXT NOT NULL,
+    CONSTRAINT audit_log_sequence_unique UNIQUE (sequence)
+);
+
+CREATE INDEX audit_log_org_created ON audit_log (org_id, created_at, id);
+CREATE INDEX audit_log_created ON audit_log (created_at, id);
+CREATE INDEX audit_log_target_created ON audit_log (target_id, created_at);
+CREATE INDEX audit_log_actor_created ON audit_log (actor_email, created_at);
+
+CREATE TABLE audit_checkpoint (
+    id         TEXT PRIMARY KEY,
+    sequence   BIGINT NOT NULL,
+    hash       TEXT NOT NULL,
+    signature  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT audit_checkpoint_sequence_unique UNIQUE (sequence)
+);
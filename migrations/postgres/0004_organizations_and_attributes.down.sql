@@ -0,0 +1,2 @@
+DROP TABLE attribute_schemas;
+DROP TABLE organizations;
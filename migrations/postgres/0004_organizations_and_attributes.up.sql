@@ -0,0 +1,30 @@
+-- organizations are unique by name, the org_id of the other tables refers to
+-- their id
+CREATE TABLE organizations (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  BIGINT NOT NULL,
+    updated_at  BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT organizations_name_unique UNIQUE (name)
+);
+
+-- the custom account attributes of an organization, accounts keep their
+-- values in accounts.attributes under the schema name
+CREATE TABLE attribute_schemas (
+    id          TEXT PRIMARY KEY,
+    org_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    required    BOOLEAN NOT NULL DEFAULT false,
+    enum        TEXT[],
+    regex       TEXT NOT NULL DEFAULT '',
+    token_claim BOOLEAN NOT NULL DEFAULT false,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  BIGINT NOT NULL,
+    updated_at  BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT attribute_schemas_org_name_unique UNIQUE (org_id, name)
+);
+
+CREATE INDEX organizations_created ON organizations (created_at, id);
+CREATE INDEX attribute_schemas_org_created ON attribute_schemas (org_id, created_at, id);
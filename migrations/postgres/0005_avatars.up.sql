@@ -0,0 +1,13 @@
+-- one row per variant of an account avatar, an upload replaces all of them
+CREATE TABLE avatars (
+    account_id   TEXT NOT NULL,
+    org_id       TEXT,
+    variant      TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    etag         TEXT NOT NULL,
+    uploaded_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    content      BYTEA NOT NULL,
+    PRIMARY KEY (account_id, variant)
+);
+
+CREATE INDEX avatars_org ON avatars (org_id);
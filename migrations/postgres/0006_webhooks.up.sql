@@ -0,0 +1,32 @@
+-- webhooks without organization receive the events of every organization
+CREATE TABLE webhooks (
+    id         TEXT PRIMARY KEY,
+    org_id     TEXT,
+    url        TEXT NOT NULL,
+    events     TEXT[] NOT NULL,
+    secret     TEXT NOT NULL,
+    active     BOOLEAN NOT NULL DEFAULT true,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL DEFAULT 0
+);
+
+-- attempts is the delivery log, a JSON array appended to by every attempt
+CREATE TABLE webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    webhook_id      TEXT NOT NULL,
+    org_id          TEXT,
+    event           TEXT NOT NULL,
+    payload         TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempt_count   INTEGER NOT NULL DEFAULT 0,
+    attempts        JSONB NOT NULL DEFAULT '[]',
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL,
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX webhooks_org_created ON webhooks (org_id, created_at, id);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_org_created ON webhook_deliveries (org_id, created_at, id);
+CREATE INDEX webhook_deliveries_webhook_created ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MIGRATION_COLLECTION = "schema_migrations"
+const LOCK_COLLECTION = "schema_migrations_lock"
+
+// LOCK_TTL bounds how long a crashed runner can block others.
+const LOCK_TTL = time.Minute * 10
+
+var ErrLocked = errors.New("another migration runner holds the lock")
+var ErrIrreversible = errors.New("migration can not be rolled back")
+
+// Migration is one schema change. Versions are applied in ascending order and
+// must never be reused once released. Down is nil for irreversible changes.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+	Down        func(ctx context.Context, database *mongo.Database) error
+}
+
+type MigrationRecord struct {
+	Version     int64     `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"appliedAt" bson:"appliedAt"`
+}
+
+type MigrationStatus struct {
+	Version     int64      `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+type Runner struct {
+	Database   *mongo.Database
+	Migrations []Migration
+	owner      string
+}
+
+func InitRunner(database *mongo.Database) *Runner {
+	hostname, _ := os.Hostname()
+	migrations := append([]Migration{}, Migrations...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return &Runner{
+		Database:   database,
+		Migrations: migrations,
+		owner:      hostname + "/" + uuid.New().String(),
+	}
+}
+
+func (runner Runner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := runner.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(runner.Migrations))
+	for _, migration := range runner.Migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet.
+func (runner Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := runner.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, migration := range runner.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration up to and including target, 0 means all.
+func (runner Runner) Up(ctx context.Context, target int64, log func(format string, args ...interface{})) error {
+	err := runner.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer runner.unlock(context.Background())
+
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		log("applying %d %s", migration.Version, migration.Description)
+		err = migration.Up(ctx, runner.Database)
+		if err != nil {
+			return fmt.Errorf("migration %d up: %w", migration.Version, err)
+		}
+		record := MigrationRecord{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().UTC()}
+		_, err = runner.Database.Collection(MIGRATION_COLLECTION).InsertOne(ctx, record)
+		if err != nil {
+			return fmt.Errorf("migration %d record: %w", migration.Version, err)
+		}
+	}
+	return nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (runner Runner) Down(ctx context.Context, steps int, log func(format string, args ...interface{})) error {
+	err := runner.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer runner.unlock(context.Background())
+
+	applied, err := runner.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for index := len(runner.Migrations) - 1; index >= 0 && steps > 0; index-- {
+		migration := runner.Migrations[index]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("migration %d: %w", migration.Version, ErrIrreversible)
+		}
+		log("rolling back %d %s", migration.Version, migration.Description)
+		err = migration.Down(ctx, runner.Database)
+		if err != nil {
+			return fmt.Errorf("migration %d down: %w", migration.Version, err)
+		}
+		_, err = runner.Database.Collection(MIGRATION_COLLECTION).DeleteOne(ctx, bson.M{"_id": migration.Version})
+		if err != nil {
+			return fmt.Errorf("migration %d record: %w", migration.Version, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+func (runner Runner) applied(ctx context.Context) (map[int64]MigrationRecord, error) {
+	cursor, err := runner.Database.Collection(MIGRATION_COLLECTION).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]MigrationRecord, 0)
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]MigrationRecord, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// lock takes the single lock document. The upsert only inserts when the lock
+// document does not exist yet, otherwise a held lock ends in a duplicate key error.
+func (runner Runner) lock(ctx context.Context) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": "lock",
+		"$or": bson.A{bson.M{"locked": false}, bson.M{"expiresAt": bson.M{"$lt": now}}},
+	}
+	update := bson.M{"$set": bson.M{"locked": true, "owner": runner.owner, "lockedAt": now, "expiresAt": now.Add(LOCK_TTL)}}
+	_, err := runner.Database.Collection(LOCK_COLLECTION).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	return err
+}
+
+func (runner Runner) unlock(ctx context.Context) error {
+	filter := bson.M{"_id": "lock", "owner": runner.owner}
+	_, err := runner.Database.Collection(LOCK_COLLECTION).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"locked": false}})
+	return err
+}
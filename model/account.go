@@ -1,18 +1,104 @@
 package model
 
 type AccountModel struct {
-	Id        string `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name      string `json:"name,omitempty" bson:"name,omitempty"`
-	Email     string `json:"email,omitempty" bson:"email,omitempty"`
-	Role      string `json:"role,omitempty" bson:"role,omitempty"`
-	Password  string `json:"password,omitempty" bson:"password,omitempty"`
-	CreatedAt int64  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
-	UpdatedAt int64  `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	Id              string                 `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrgId           string                 `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Name            string                 `json:"name,omitempty" bson:"name,omitempty"`
+	Email           string                 `json:"email,omitempty" bson:"email,omitempty"`
+	EmailNormalized string                 `json:"-" bson:"emailNormalized,omitempty"`
+	Role            string                 `json:"role,omitempty" bson:"role,omitempty"`
+	Password        string                 `json:"password,omitempty" bson:"password,omitempty"`
+	Attributes      map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	Version         int64                  `json:"version,omitempty" bson:"version,omitempty"`
+	CreatedAt       int64                  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt       int64                  `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+// AccountCreateModel is the body of a new account, id, version and timestamps
+// are assigned by the service.
+type AccountCreateModel struct {
+	OrgId      string                 `json:"orgId,omitempty"`
+	Name       string                 `json:"name,omitempty" binding:"required,max=100"`
+	Email      string                 `json:"email,omitempty" binding:"required,email,max=254"`
+	Role       string                 `json:"role,omitempty" binding:"max=64"`
+	Password   string                 `json:"password,omitempty" binding:"required,min=8,max=72"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// AccountUpdateModel selects the account by _id, empty fields are kept and
+// Version rejects the update when the account changed meanwhile.
+type AccountUpdateModel struct {
+	Id         string                 `json:"_id,omitempty" binding:"required"`
+	Name       string                 `json:"name,omitempty" binding:"max=100"`
+	Email      string                 `json:"email,omitempty" binding:"omitempty,email,max=254"`
+	Role       string                 `json:"role,omitempty" binding:"max=64"`
+	Password   string                 `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Version    int64                  `json:"version,omitempty"`
+}
+
+// AccountResponseModel is an account as the API answers it, without the
+// password hash.
+type AccountResponseModel struct {
+	Id         string                 `json:"id"`
+	OrgId      string                 `json:"orgId"`
+	Name       string                 `json:"name"`
+	Email      string                 `json:"email"`
+	Role       string                 `json:"role"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Version    int64                  `json:"version"`
+	CreatedAt  int64                  `json:"createdAt"`
+	UpdatedAt  int64                  `json:"updatedAt"`
+}
+
+// AccountV1ResponseModel is the snake_case account of the v1 responses, still
+// served with compat.v1Responses.
+type AccountV1ResponseModel struct {
+	Id         string                 `json:"id"`
+	OrgId      string                 `json:"org_id"`
+	Name       string                 `json:"name"`
+	Email      string                 `json:"email"`
+	Role       string                 `json:"role"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Version    int64                  `json:"version"`
+	CreatedAt  int64                  `json:"created_at"`
+	UpdatedAt  int64                  `json:"updated_at"`
 }
 
 type PaginateAccountModel struct {
-	Order   string `json:"order,omitempty" bson:"order,omitempty"`
-	OrderBy string `json:"orderBy,omitempty" bson:"orderBy,omitempty"`
-	Page    int    `json:"page,omitempty" bson:"page,omitempty"`
-	Size    int    `json:"size,omitempty" bson:"size,omitempty"`
+	Order      string                 `json:"order,omitempty" bson:"order,omitempty" binding:"omitempty,oneof=asc desc"`
+	OrderBy    string                 `json:"orderBy,omitempty" bson:"orderBy,omitempty" binding:"omitempty,oneof=_id orgId name email role version createdAt updatedAt"`
+	Page       int                    `json:"page,omitempty" bson:"page,omitempty" binding:"min=0"`
+	Size       int                    `json:"size,omitempty" bson:"size,omitempty" binding:"omitempty,min=1,max=100"`
+	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+}
+
+// Model is the account the request creates.
+func (request AccountCreateModel) Model() AccountModel {
+	return AccountModel{
+		OrgId:      request.OrgId,
+		Name:       request.Name,
+		Email:      request.Email,
+		Role:       request.Role,
+		Password:   request.Password,
+		Attributes: request.Attributes,
+	}
+}
+
+func NewAccountResponse(account AccountModel) AccountResponseModel {
+	return AccountResponseModel{
+		Id:         account.Id,
+		OrgId:      account.OrgId,
+		Name:       account.Name,
+		Email:      account.Email,
+		Role:       account.Role,
+		Attributes: account.Attributes,
+		Version:    account.Version,
+		CreatedAt:  account.CreatedAt,
+		UpdatedAt:  account.UpdatedAt,
+	}
+}
+
+func NewAccountV1Response(account AccountModel) AccountV1ResponseModel {
+	return AccountV1ResponseModel(NewAccountResponse(account))
 }
@@ -0,0 +1,99 @@
+package model
+
+type EnumAttributeType string
+
+const (
+	ATTRIBUTE_STRING  EnumAttributeType = "string"
+	ATTRIBUTE_NUMBER  EnumAttributeType = "number"
+	ATTRIBUTE_BOOLEAN EnumAttributeType = "boolean"
+)
+
+type AttributeSchemaModel struct {
+	Id          string            `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrgId       string            `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Name        string            `json:"name" bson:"name"`
+	Type        EnumAttributeType `json:"type" bson:"type"`
+	Required    bool              `json:"required" bson:"required"`
+	Enum        []string          `json:"enum,omitempty" bson:"enum,omitempty"`
+	Regex       string            `json:"regex,omitempty" bson:"regex,omitempty"`
+	TokenClaim  bool              `json:"tokenClaim" bson:"tokenClaim"`
+	Description string            `json:"description" bson:"description"`
+	CreatedAt   int64             `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt   int64             `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+// AttributeSchemaCreateModel is the body of a new attribute schema, orgId
+// defaults to the organization of the caller.
+type AttributeSchemaCreateModel struct {
+	OrgId       string            `json:"orgId,omitempty"`
+	Name        string            `json:"name" binding:"required,max=64,attributename"`
+	Type        EnumAttributeType `json:"type" binding:"required,oneof=string number boolean"`
+	Required    bool              `json:"required"`
+	Enum        []string          `json:"enum,omitempty" binding:"omitempty,stringattribute,max=100,dive,required,max=200"`
+	Regex       string            `json:"regex,omitempty" binding:"omitempty,stringattribute,max=500,regexp"`
+	TokenClaim  bool              `json:"tokenClaim"`
+	Description string            `json:"description" binding:"max=500"`
+}
+
+// AttributeSchemaUpdateModel selects the schema by _id and replaces its
+// definition, the name is kept.
+type AttributeSchemaUpdateModel struct {
+	Id          string            `json:"_id,omitempty" binding:"required"`
+	Type        EnumAttributeType `json:"type" binding:"required,oneof=string number boolean"`
+	Required    bool              `json:"required"`
+	Enum        []string          `json:"enum,omitempty" binding:"omitempty,stringattribute,max=100,dive,required,max=200"`
+	Regex       string            `json:"regex,omitempty" binding:"omitempty,stringattribute,max=500,regexp"`
+	TokenClaim  bool              `json:"tokenClaim"`
+	Description string            `json:"description" binding:"max=500"`
+}
+
+type AttributeSchemaResponseModel struct {
+	Id          string            `json:"id"`
+	OrgId       string            `json:"orgId"`
+	Name        string            `json:"name"`
+	Type        EnumAttributeType `json:"type"`
+	Required    bool              `json:"required"`
+	Enum        []string          `json:"enum"`
+	Regex       string            `json:"regex"`
+	TokenClaim  bool              `json:"tokenClaim"`
+	Description string            `json:"description"`
+	CreatedAt   int64             `json:"createdAt"`
+	UpdatedAt   int64             `json:"updatedAt"`
+}
+
+type PaginateAttributeSchemaModel struct {
+	Order   string `json:"order,omitempty" bson:"order,omitempty" binding:"omitempty,oneof=asc desc"`
+	OrderBy string `json:"orderBy,omitempty" bson:"orderBy,omitempty" binding:"omitempty,oneof=_id name type required tokenClaim createdAt updatedAt"`
+	Page    int    `json:"page,omitempty" bson:"page,omitempty" binding:"min=0"`
+	Size    int    `json:"size,omitempty" bson:"size,omitempty" binding:"omitempty,min=1,max=100"`
+}
+
+// Model is the attribute schema the request creates.
+func (request AttributeSchemaCreateModel) Model() AttributeSchemaModel {
+	return AttributeSchemaModel{
+		OrgId:       request.OrgId,
+		Name:        request.Name,
+		Type:        request.Type,
+		Required:    request.Required,
+		Enum:        request.Enum,
+		Regex:       request.Regex,
+		TokenClaim:  request.TokenClaim,
+		Description: request.Description,
+	}
+}
+
+func NewAttributeSchemaResponse(schema AttributeSchemaModel) AttributeSchemaResponseModel {
+	return AttributeSchemaResponseModel{
+		Id:          schema.Id,
+		OrgId:       schema.OrgId,
+		Name:        schema.Name,
+		Type:        schema.Type,
+		Required:    schema.Required,
+		Enum:        schema.Enum,
+		Regex:       schema.Regex,
+		TokenClaim:  schema.TokenClaim,
+		Description: schema.Description,
+		CreatedAt:   schema.CreatedAt,
+		UpdatedAt:   schema.UpdatedAt,
+	}
+}
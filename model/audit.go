@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+type EnumAuditAction string
+
+const (
+	AUDIT_ACCOUNT_CREATE      EnumAuditAction = "account.create"
+	AUDIT_ACCOUNT_UPDATE      EnumAuditAction = "account.update"
+	AUDIT_ACCOUNT_DELETE      EnumAuditAction = "account.delete"
+	AUDIT_ACCOUNT_PASSWORD    EnumAuditAction = "account.password_reset"
+	AUDIT_AVATAR_UPDATE       EnumAuditAction = "avatar.update"
+	AUDIT_AVATAR_DELETE       EnumAuditAction = "avatar.delete"
+	AUDIT_ROLE_CREATE         EnumAuditAction = "role.create"
+	AUDIT_ROLE_UPDATE         EnumAuditAction = "role.update"
+	AUDIT_ROLE_DELETE         EnumAuditAction = "role.delete"
+	AUDIT_ORGANIZATION_CREATE EnumAuditAction = "organization.create"
+	AUDIT_ORGANIZATION_UPDATE EnumAuditAction = "organization.update"
+	AUDIT_ORGANIZATION_DELETE EnumAuditAction = "organization.delete"
+	AUDIT_ATTRIBUTE_CREATE    EnumAuditAction = "attribute.create"
+	AUDIT_ATTRIBUTE_UPDATE    EnumAuditAction = "attribute.update"
+	AUDIT_ATTRIBUTE_DELETE    EnumAuditAction = "attribute.delete"
+	AUDIT_LOGIN               EnumAuditAction = "auth.login"
+	AUDIT_LOGIN_FAILED        EnumAuditAction = "auth.login_failed"
+	AUDIT_LOGOUT              EnumAuditAction = "auth.logout"
+	AUDIT_REFRESH             EnumAuditAction = "auth.refresh"
+	AUDIT_REFRESH_REUSED      EnumAuditAction = "auth.refresh_reused"
+	AUDIT_SESSIONS_REVOKE     EnumAuditAction = "auth.sessions_revoke"
+)
+
+type AuditActorModel struct {
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+	OrgId string `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Role  string `json:"role,omitempty" bson:"role,omitempty"`
+	// System names the CLI command or job acting without an account.
+	System string `json:"system,omitempty" bson:"system,omitempty"`
+}
+
+type AuditTargetModel struct {
+	Type  string `json:"type" bson:"type"`
+	Id    string `json:"id,omitempty" bson:"id,omitempty"`
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+}
+
+type AuditChangeModel struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+type AuditEventModel struct {
+	Id        string             `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrgId     string             `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Action    EnumAuditAction    `json:"action" bson:"action"`
+	Actor     AuditActorModel    `json:"actor" bson:"actor"`
+	Target    AuditTargetModel   `json:"target" bson:"target"`
+	Changes   []AuditChangeModel `json:"changes,omitempty" bson:"changes,omitempty"`
+	Reason    string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	Ip        string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent string             `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+	RequestId string             `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	TraceId   string             `json:"traceId,omitempty" bson:"traceId,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	// Sequence, PrevHash and Hash chain every event to the one before it.
+	Sequence int64  `json:"sequence,omitempty" bson:"sequence,omitempty"`
+	PrevHash string `json:"prevHash,omitempty" bson:"prevHash,omitempty"`
+	Hash     string `json:"hash,omitempty" bson:"hash,omitempty"`
+}
+
+type AuditCheckpointModel struct {
+	Id        string    `json:"_id,omitempty" bson:"_id,omitempty"`
+	Sequence  int64     `json:"sequence" bson:"sequence"`
+	Hash      string    `json:"hash" bson:"hash"`
+	Signature string    `json:"signature" bson:"signature"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type AuditBrokenLinkModel struct {
+	Sequence int64  `json:"sequence"`
+	Id       string `json:"id,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type AuditVerifyModel struct {
+	Valid        bool                  `json:"valid"`
+	Checked      int64                 `json:"checked"`
+	LastSequence int64                 `json:"lastSequence"`
+	LastHash     string                `json:"lastHash,omitempty"`
+	Checkpoints  int64                 `json:"checkpoints"`
+	Unchained    int64                 `json:"unchained"`
+	Broken       *AuditBrokenLinkModel `json:"broken,omitempty"`
+}
+
+type PaginateAuditModel struct {
+	Action     string    `form:"action"`
+	ActorEmail string    `form:"actorEmail"`
+	TargetType string    `form:"targetType"`
+	TargetId   string    `form:"targetId"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Order      string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page       int       `form:"page" binding:"min=0"`
+	Size       int       `form:"size" binding:"omitempty,min=1,max=100"`
+}
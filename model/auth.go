@@ -1,6 +1,7 @@
 package model
 
 type LoginModel struct {
-	Email    string `json:"email,omitempty" bson:"email,omitempty" binding:"required"`
-	Password string `json:"password,omitempty" bson:"password,omitempty" binding:"required"`
+	Email    string `json:"email,omitempty" bson:"email,omitempty" binding:"required,email,max=254"`
+	Password string `json:"password,omitempty" bson:"password,omitempty" binding:"required,max=72"`
+	OrgId    string `json:"orgId,omitempty" bson:"orgId,omitempty"`
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type AvatarMetadataModel struct {
+	AccountId   string `json:"accountId" bson:"accountId"`
+	OrgId       string `json:"orgId" bson:"orgId"`
+	Variant     string `json:"variant" bson:"variant"`
+	ContentType string `json:"contentType" bson:"contentType"`
+	ETag        string `json:"etag" bson:"etag"`
+}
+
+type AvatarFileModel struct {
+	Id         interface{}         `json:"_id" bson:"_id"`
+	Length     int64               `json:"length" bson:"length"`
+	UploadDate time.Time           `json:"uploadDate" bson:"uploadDate"`
+	Metadata   AvatarMetadataModel `json:"metadata" bson:"metadata"`
+}
+
+// AvatarVariantModel is one image of an upload, the original or a thumbnail.
+type AvatarVariantModel struct {
+	Metadata AvatarMetadataModel
+	Content  []byte
+}
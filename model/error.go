@@ -0,0 +1,18 @@
+package model
+
+// ErrorModel is the body of every error response. Code is stable and meant
+// for clients to branch on, Message is localized and may change.
+type ErrorModel struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"requestId"`
+}
+
+// FieldErrorModel is one broken rule of a VALIDATION_FAILED error, Field is
+// the json path of the value and Rule the binding tag it failed.
+type FieldErrorModel struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+type EnumDomainEvent string
+
+const (
+	EVENT_ACCOUNT_CREATED   EnumDomainEvent = "account.created"
+	EVENT_ACCOUNT_UPDATED   EnumDomainEvent = "account.updated"
+	EVENT_ACCOUNT_DELETED   EnumDomainEvent = "account.deleted"
+	EVENT_ROLE_CREATED      EnumDomainEvent = "role.created"
+	EVENT_ROLE_UPDATED      EnumDomainEvent = "role.updated"
+	EVENT_ROLE_DELETED      EnumDomainEvent = "role.deleted"
+	EVENT_SESSION_CREATED   EnumDomainEvent = "session.created"
+	EVENT_SESSION_REFRESHED EnumDomainEvent = "session.refreshed"
+	EVENT_SESSION_ENDED     EnumDomainEvent = "session.ended"
+	EVENT_SESSION_REVOKED   EnumDomainEvent = "session.revoked"
+	EVENT_SESSION_REUSED    EnumDomainEvent = "session.reused"
+)
+
+// OutboxEventModel is a domain event waiting in the outbox collection until
+// the relay has added it to the Redis stream.
+type OutboxEventModel struct {
+	Id          string          `json:"id" bson:"_id"`
+	Type        EnumDomainEvent `json:"type" bson:"type"`
+	OrgId       string          `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	AggregateId string          `json:"aggregateId,omitempty" bson:"aggregateId,omitempty"`
+	Payload     string          `json:"payload" bson:"payload"`
+	CreatedAt   time.Time       `json:"createdAt" bson:"createdAt"`
+	PublishedAt *time.Time      `json:"publishedAt,omitempty" bson:"publishedAt,omitempty"`
+	StreamId    string          `json:"streamId,omitempty" bson:"streamId,omitempty"`
+}
+
+// StreamEventModel is one entry read back from the event stream.
+type StreamEventModel struct {
+	StreamId string                 `json:"streamId"`
+	Fields   map[string]interface{} `json:"fields"`
+}
+
+type ReplayEventModel struct {
+	After string `form:"after"`
+	Count int64  `form:"count"`
+}
@@ -0,0 +1,15 @@
+package model
+
+const HEALTH_OK = "ok"
+const HEALTH_UNAVAILABLE = "unavailable"
+
+type HealthCheckModel struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type HealthModel struct {
+	Status string                      `json:"status"`
+	Checks map[string]HealthCheckModel `json:"checks,omitempty"`
+}
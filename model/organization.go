@@ -0,0 +1,55 @@
+package model
+
+type OrganizationModel struct {
+	Id          string `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name        string `json:"name,omitempty" bson:"name,omitempty"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	CreatedAt   int64  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt   int64  `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+type OrganizationCreateModel struct {
+	Name        string `json:"name,omitempty" binding:"required,max=100"`
+	Description string `json:"description,omitempty" binding:"max=500"`
+}
+
+// OrganizationUpdateModel selects the organization by _id, empty fields are
+// kept.
+type OrganizationUpdateModel struct {
+	Id          string `json:"_id,omitempty" binding:"required"`
+	Name        string `json:"name,omitempty" binding:"max=100"`
+	Description string `json:"description,omitempty" binding:"max=500"`
+}
+
+type OrganizationResponseModel struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+type PaginateOrganizationModel struct {
+	Order   string `json:"order,omitempty" bson:"order,omitempty" binding:"omitempty,oneof=asc desc"`
+	OrderBy string `json:"orderBy,omitempty" bson:"orderBy,omitempty" binding:"omitempty,oneof=_id name createdAt updatedAt"`
+	Page    int    `json:"page,omitempty" bson:"page,omitempty" binding:"min=0"`
+	Size    int    `json:"size,omitempty" bson:"size,omitempty" binding:"omitempty,min=1,max=100"`
+}
+
+// Model is the organization the request creates.
+func (request OrganizationCreateModel) Model() OrganizationModel {
+	return OrganizationModel{
+		Name:        request.Name,
+		Description: request.Description,
+	}
+}
+
+func NewOrganizationResponse(organization OrganizationModel) OrganizationResponseModel {
+	return OrganizationResponseModel{
+		Id:          organization.Id,
+		Name:        organization.Name,
+		Description: organization.Description,
+		CreatedAt:   organization.CreatedAt,
+		UpdatedAt:   organization.UpdatedAt,
+	}
+}
@@ -5,23 +5,83 @@ import "time"
 type EnumRole string
 
 const (
-	SUPERADMIN EnumRole = "superadmin"
-	USER       EnumRole = "user"
-	GUEST      EnumRole = "guest"
+	PLATFORM_SUPERADMIN EnumRole = "platform-superadmin"
+	SUPERADMIN          EnumRole = "superadmin"
+	USER                EnumRole = "user"
+	GUEST               EnumRole = "guest"
+	AUDITOR             EnumRole = "auditor"
 )
 
+// ROLES lists every EnumRole a role can be defined with.
+var ROLES = []EnumRole{PLATFORM_SUPERADMIN, SUPERADMIN, USER, GUEST, AUDITOR}
+
 type RoleModel struct {
 	Id          string     `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrgId       string     `json:"orgId,omitempty" bson:"orgId,omitempty"`
 	Name        string     `json:"name" bson:"name"`
 	Role        EnumRole   `json:"role" bson:"role"`
 	Description string     `json:"description" bson:"description"`
+	Version     int64      `json:"version,omitempty" bson:"version,omitempty"`
 	CreatedAt   time.Time  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
 	UpdatedAt   *time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
 }
 
+// RoleCreateModel is the body of a new role, orgId defaults to the
+// organization of the caller.
+type RoleCreateModel struct {
+	OrgId       string   `json:"orgId,omitempty"`
+	Name        string   `json:"name" binding:"required,max=64"`
+	Role        EnumRole `json:"role" binding:"required,enumrole"`
+	Description string   `json:"description" binding:"max=500"`
+}
+
+// RoleUpdateModel selects the role by _id, empty fields are kept and Version
+// rejects the update when the role changed meanwhile.
+type RoleUpdateModel struct {
+	Id          string   `json:"_id,omitempty" binding:"required"`
+	Name        string   `json:"name" binding:"max=64"`
+	Role        EnumRole `json:"role" binding:"omitempty,enumrole"`
+	Description string   `json:"description" binding:"max=500"`
+	Version     int64    `json:"version,omitempty"`
+}
+
+type RoleResponseModel struct {
+	Id          string     `json:"id"`
+	OrgId       string     `json:"orgId"`
+	Name        string     `json:"name"`
+	Role        EnumRole   `json:"role"`
+	Description string     `json:"description"`
+	Version     int64      `json:"version"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
 type PaginateRoleModel struct {
-	Order   string `json:"order,omitempty" bson:"order,omitempty"`
-	OrderBy string `json:"orderBy,omitempty" bson:"orderBy,omitempty"`
-	Page    int    `json:"page,omitempty" bson:"page,omitempty"`
-	Size    int    `json:"size,omitempty" bson:"size,omitempty"`
+	Order   string `json:"order,omitempty" bson:"order,omitempty" binding:"omitempty,oneof=asc desc"`
+	OrderBy string `json:"orderBy,omitempty" bson:"orderBy,omitempty" binding:"omitempty,oneof=_id orgId name role description version createdAt updatedAt"`
+	Page    int    `json:"page,omitempty" bson:"page,omitempty" binding:"min=0"`
+	Size    int    `json:"size,omitempty" bson:"size,omitempty" binding:"omitempty,min=1,max=100"`
+}
+
+// Model is the role the request creates.
+func (request RoleCreateModel) Model() RoleModel {
+	return RoleModel{
+		OrgId:       request.OrgId,
+		Name:        request.Name,
+		Role:        request.Role,
+		Description: request.Description,
+	}
+}
+
+func NewRoleResponse(role RoleModel) RoleResponseModel {
+	return RoleResponseModel{
+		Id:          role.Id,
+		OrgId:       role.OrgId,
+		Name:        role.Name,
+		Role:        role.Role,
+		Description: role.Description,
+		Version:     role.Version,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
 }
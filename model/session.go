@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// SessionModel is what a session store keeps for every access and refresh
+// token id.
+type SessionModel struct {
+	Email string `json:"email"`
+	OrgId string `json:"orgId"`
+}
+
+type SessionInfoModel struct {
+	Uuid      string        `json:"uuid"`
+	Email     string        `json:"email"`
+	OrgId     string        `json:"orgId"`
+	ExpiresIn time.Duration `json:"expiresIn"`
+}
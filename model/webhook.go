@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+type EnumWebhookEvent string
+
+const (
+	WEBHOOK_ACCOUNT_CREATED      EnumWebhookEvent = "account.created"
+	WEBHOOK_ACCOUNT_UPDATED      EnumWebhookEvent = "account.updated"
+	WEBHOOK_ACCOUNT_ROLE_CHANGED EnumWebhookEvent = "account.role_changed"
+	WEBHOOK_ACCOUNT_DELETED      EnumWebhookEvent = "account.deleted"
+	WEBHOOK_ROLE_CREATED         EnumWebhookEvent = "role.created"
+	WEBHOOK_ROLE_UPDATED         EnumWebhookEvent = "role.updated"
+	WEBHOOK_ROLE_DELETED         EnumWebhookEvent = "role.deleted"
+	WEBHOOK_SESSION_CREATED      EnumWebhookEvent = "session.created"
+	WEBHOOK_SESSION_ENDED        EnumWebhookEvent = "session.ended"
+	WEBHOOK_SESSION_REUSED       EnumWebhookEvent = "session.reused"
+)
+
+// WEBHOOK_EVENTS lists every event a webhook can subscribe to.
+var WEBHOOK_EVENTS = []EnumWebhookEvent{
+	WEBHOOK_ACCOUNT_CREATED,
+	WEBHOOK_ACCOUNT_UPDATED,
+	WEBHOOK_ACCOUNT_ROLE_CHANGED,
+	WEBHOOK_ACCOUNT_DELETED,
+	WEBHOOK_ROLE_CREATED,
+	WEBHOOK_ROLE_UPDATED,
+	WEBHOOK_ROLE_DELETED,
+	WEBHOOK_SESSION_CREATED,
+	WEBHOOK_SESSION_ENDED,
+	WEBHOOK_SESSION_REUSED,
+}
+
+type EnumDeliveryStatus string
+
+const (
+	DELIVERY_PENDING   EnumDeliveryStatus = "pending"
+	DELIVERY_SUCCEEDED EnumDeliveryStatus = "succeeded"
+	DELIVERY_DEAD      EnumDeliveryStatus = "dead"
+)
+
+type WebhookModel struct {
+	Id        string             `json:"_id,omitempty" bson:"_id,omitempty"`
+	OrgId     string             `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Url       string             `json:"url" bson:"url"`
+	Events    []EnumWebhookEvent `json:"events" bson:"events"`
+	Secret    string             `json:"secret,omitempty" bson:"secret"`
+	Active    *bool              `json:"active,omitempty" bson:"active"`
+	CreatedAt int64              `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt int64              `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+// WebhookPayloadModel is the JSON body posted to a webhook url.
+type WebhookPayloadModel struct {
+	Id        string           `json:"id" bson:"id"`
+	Type      EnumWebhookEvent `json:"type" bson:"type"`
+	OrgId     string           `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	CreatedAt time.Time        `json:"createdAt" bson:"createdAt"`
+	Data      interface{}      `json:"data" bson:"data"`
+}
+
+type WebhookAttemptModel struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"durationMs" bson:"durationMs"`
+}
+
+// WebhookDeliveryModel is one event on its way to one webhook, Attempts is
+// its delivery log.
+type WebhookDeliveryModel struct {
+	Id            string                `json:"_id,omitempty" bson:"_id,omitempty"`
+	WebhookId     string                `json:"webhookId" bson:"webhookId"`
+	OrgId         string                `json:"orgId,omitempty" bson:"orgId,omitempty"`
+	Event         EnumWebhookEvent      `json:"event" bson:"event"`
+	Payload       string                `json:"payload" bson:"payload"`
+	Status        EnumDeliveryStatus    `json:"status" bson:"status"`
+	AttemptCount  int                   `json:"attemptCount" bson:"attemptCount"`
+	Attempts      []WebhookAttemptModel `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time             `json:"nextAttemptAt,omitempty" bson:"nextAttemptAt,omitempty"`
+	LockedUntil   time.Time             `json:"-" bson:"lockedUntil,omitempty"`
+	CreatedAt     time.Time             `json:"createdAt" bson:"createdAt"`
+	DeliveredAt   *time.Time            `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+type PaginateWebhookDeliveryModel struct {
+	WebhookId string `form:"webhookId"`
+	Status    string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Event     string `form:"event"`
+	Page      int    `form:"page" binding:"min=0"`
+	Size      int    `form:"size" binding:"omitempty,min=1,max=100"`
+}
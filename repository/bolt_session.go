@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ima-svc-management/model"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SESSION_BUCKET holds every token id of the bolt session store.
+const SESSION_BUCKET = "sessions"
+
+// BOLT_OPEN_TIMEOUT bounds the wait for the file lock, bolt lets one process
+// open the file at a time.
+const BOLT_OPEN_TIMEOUT = time.Second * 2
+
+type boltSession struct {
+	Account string             `json:"account"`
+	Session model.SessionModel `json:"session"`
+	Expires time.Time          `json:"expires"`
+	Refresh bool               `json:"refresh,omitempty"`
+}
+
+// BoltSessions keeps sessions in a bolt file so they survive a restart of a
+// single instance. Tokens expire like MemorySessions, expired ones are
+// dropped whenever sessions are created, listed, revoked or counted.
+type BoltSessions struct {
+	DB *bolt.DB
+}
+
+func InitBoltSessions(path string) (*BoltSessions, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: BOLT_OPEN_TIMEOUT})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("session file %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening session file %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(SESSION_BUCKET))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltSessions{
+		DB: db,
+	}, nil
+}
+
+func (sessions *BoltSessions) Close() error {
+	return sessions.DB.Close()
+}
+
+func (sessions *BoltSessions) Create(ctx context.Context, account string, session model.SessionModel, access string, accessExpires time.Time, refresh string, refreshExpires time.Time) error {
+	return sessions.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SESSION_BUCKET))
+		err := pruneSessions(bucket)
+		if err != nil {
+			return err
+		}
+		err = putSession(bucket, access, boltSession{Account: account, Session: session, Expires: accessExpires})
+		if err != nil {
+			return err
+		}
+		return putSession(bucket, refresh, boltSession{Account: account, Session: session, Expires: refreshExpires, Refresh: true})
+	})
+}
+
+func (sessions *BoltSessions) Fetch(ctx context.Context, uuid string) (*model.SessionModel, error) {
+	var session *model.SessionModel
+	err := sessions.DB.View(func(tx *bolt.Tx) error {
+		entry, err := getSession(tx.Bucket([]byte(SESSION_BUCKET)), uuid)
+		if err != nil {
+			return err
+		}
+		if entry == nil || !entry.Expires.After(time.Now()) {
+			return ErrNotFound
+		}
+		session = &entry.Session
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (sessions *BoltSessions) Delete(ctx context.Context, uuid string) (int64, error) {
+	deleted := int64(0)
+	err := sessions.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SESSION_BUCKET))
+		entry, err := getSession(bucket, uuid)
+		if err != nil || entry == nil {
+			return err
+		}
+		if entry.Expires.After(time.Now()) {
+			deleted = 1
+		}
+		return bucket.Delete([]byte(uuid))
+	})
+	return deleted, err
+}
+
+func (sessions *BoltSessions) List(ctx context.Context, account string) ([]model.SessionInfoModel, error) {
+	list := make([]model.SessionInfoModel, 0)
+	err := sessions.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SESSION_BUCKET))
+		err := pruneSessions(bucket)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		return bucket.ForEach(func(key []byte, value []byte) error {
+			entry := boltSession{}
+			err := json.Unmarshal(value, &entry)
+			if err != nil {
+				return err
+			}
+			if account != "" && entry.Account != account {
+				return nil
+			}
+			list = append(list, model.SessionInfoModel{
+				Uuid:      string(key),
+				Email:     entry.Session.Email,
+				OrgId:     entry.Session.OrgId,
+				ExpiresIn: entry.Expires.Sub(now).Truncate(time.Second),
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Uuid < list[j].Uuid
+	})
+	return list, nil
+}
+
+func (sessions *BoltSessions) Revoke(ctx context.Context, account string) (int64, error) {
+	deleted := int64(0)
+	err := sessions.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SESSION_BUCKET))
+		err := pruneSessions(bucket)
+		if err != nil {
+			return err
+		}
+		return deleteSessions(bucket, func(entry boltSession) bool {
+			if entry.Account != account {
+				return false
+			}
+			deleted++
+			return true
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (sessions *BoltSessions) CountActive(ctx context.Context) (int64, error) {
+	count := int64(0)
+	err := sessions.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SESSION_BUCKET))
+		err := pruneSessions(bucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(key []byte, value []byte) error {
+			entry := boltSession{}
+			err := json.Unmarshal(value, &entry)
+			if err == nil && entry.Refresh {
+				count++
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func putSession(bucket *bolt.Bucket, uuid string, entry boltSession) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(uuid), value)
+}
+
+// getSession returns nil when uuid is not stored.
+func getSession(bucket *bolt.Bucket, uuid string) (*boltSession, error) {
+	value := bucket.Get([]byte(uuid))
+	if value == nil {
+		return nil, nil
+	}
+	entry := &boltSession{}
+	err := json.Unmarshal(value, entry)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// pruneSessions drops every expired token.
+func pruneSessions(bucket *bolt.Bucket) error {
+	now := time.Now()
+	return deleteSessions(bucket, func(entry boltSession) bool {
+		return !entry.Expires.After(now)
+	})
+}
+
+// deleteSessions collects the matching keys first, bolt does not allow
+// changing a bucket while iterating over it.
+func deleteSessions(bucket *bolt.Bucket, match func(entry boltSession) bool) error {
+	keys := make([][]byte, 0)
+	err := bucket.ForEach(func(key []byte, value []byte) error {
+		entry := boltSession{}
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			return err
+		}
+		if match(entry) {
+			keys = append(keys, append([]byte(nil), key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = bucket.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,2344 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"ima-svc-management/migrations"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The contract every implementation of AccountRepository, RoleRepository,
+// OrganizationRepository, AttributeSchemaRepository, AvatarStore,
+// WebhookRepository, SessionStore, OutboxRepository and AuditRepository has
+// to keep. The in-process stores
+// always run. Mongo runs with MONGO_URI set, in a database of its own, and
+// Postgres with POSTGRES_URL, in a schema of its own, both are dropped
+// afterwards. Redis runs with REDIS_ADDR.
+
+var mongoDatabase *mongo.Database
+var postgresDB *sql.DB
+var redisClient *redis.Client
+
+func TestMain(m *testing.M) {
+	code, err := runContract(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func runContract(m *testing.M) (int, error) {
+	ctx := context.Background()
+	if uri := os.Getenv("MONGO_URI"); uri != "" {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			return 0, err
+		}
+		defer client.Disconnect(ctx)
+		mongoDatabase = client.Database("ima_contract_" + nextId())
+		defer mongoDatabase.Drop(ctx)
+		err = migrations.InitRunner(mongoDatabase).Up(ctx, 0, func(format string, args ...interface{}) {})
+		if err != nil {
+			return 0, fmt.Errorf("mongo migrations: %w", err)
+		}
+	}
+	if postgresUrl := os.Getenv("POSTGRES_URL"); postgresUrl != "" {
+		admin, err := sql.Open("postgres", postgresUrl)
+		if err != nil {
+			return 0, err
+		}
+		defer admin.Close()
+		schema := "ima_contract_" + nextId()
+		_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+		if err != nil {
+			return 0, err
+		}
+		defer admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		postgresDB, err = sql.Open("postgres", withSearchPath(postgresUrl, schema))
+		if err != nil {
+			return 0, err
+		}
+		defer postgresDB.Close()
+		runner, err := migrations.InitPostgresRunner(postgresDB)
+		if err != nil {
+			return 0, err
+		}
+		err = runner.Up(ctx, 0, func(format string, args ...interface{}) {})
+		if err != nil {
+			return 0, fmt.Errorf("postgres migrations: %w", err)
+		}
+	}
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		redisClient = redis.NewClient(&redis.Options{Addr: addr})
+		defer redisClient.Close()
+		err := redisClient.Ping(ctx).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return m.Run(), nil
+}
+
+// withSearchPath points the connections of postgresUrl, a URL or key=value
+// string, at schema.
+func withSearchPath(postgresUrl string, schema string) string {
+	parsed, err := url.Parse(postgresUrl)
+	if err != nil || (parsed.Scheme != "postgres" && parsed.Scheme != "postgresql") {
+		return postgresUrl + " search_path=" + schema
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// storageBackend opens empty account and role repositories sharing one
+// store.
+type storageBackend struct {
+	name string
+	open func(t *testing.T) (repository.AccountRepository, repository.RoleRepository)
+}
+
+func storageBackends() []storageBackend {
+	backends := []storageBackend{{
+		name: "memory",
+		open: func(t *testing.T) (repository.AccountRepository, repository.RoleRepository) {
+			return repository.InitMemoryAccounts(), repository.InitMemoryRoles()
+		},
+	}}
+	if mongoDatabase != nil {
+		backends = append(backends, storageBackend{
+			name: "mongo",
+			open: func(t *testing.T) (repository.AccountRepository, repository.RoleRepository) {
+				for _, collection := range []string{repository.ACCOUNT_COLLECTION, repository.ROLE_COLLECTION} {
+					_, err := mongoDatabase.Collection(collection).DeleteMany(context.Background(), bson.M{})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				return repository.InitMongoAccounts(mongoDatabase), repository.InitMongoRoles(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, storageBackend{
+			name: "postgres",
+			open: func(t *testing.T) (repository.AccountRepository, repository.RoleRepository) {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.ACCOUNT_TABLE+", "+repository.ROLE_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresAccounts(postgresDB), repository.InitPostgresRoles(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// outboxBackend opens an empty outbox, only the database backed stores have
+// one.
+type outboxBackend struct {
+	name string
+	open func(t *testing.T) repository.OutboxRepository
+}
+
+func outboxBackends() []outboxBackend {
+	backends := []outboxBackend{}
+	if mongoDatabase != nil {
+		backends = append(backends, outboxBackend{
+			name: "mongo",
+			open: func(t *testing.T) repository.OutboxRepository {
+				_, err := mongoDatabase.Collection(repository.OUTBOX_COLLECTION).DeleteMany(context.Background(), bson.M{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitMongoOutbox(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, outboxBackend{
+			name: "postgres",
+			open: func(t *testing.T) repository.OutboxRepository {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.OUTBOX_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresOutbox(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// auditBackend opens an empty audit chain.
+type auditBackend struct {
+	name string
+	open func(t *testing.T) repository.AuditRepository
+}
+
+func auditBackends() []auditBackend {
+	backends := []auditBackend{{
+		name: "memory",
+		open: func(t *testing.T) repository.AuditRepository {
+			return repository.InitMemoryAudit()
+		},
+	}}
+	if mongoDatabase != nil {
+		backends = append(backends, auditBackend{
+			name: "mongo",
+			open: func(t *testing.T) repository.AuditRepository {
+				for _, collection := range []string{repository.AUDIT_COLLECTION, repository.AUDIT_CHECKPOINT_COLLECTION} {
+					_, err := mongoDatabase.Collection(collection).DeleteMany(context.Background(), bson.M{})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				return repository.InitMongoAudit(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, auditBackend{
+			name: "postgres",
+			open: func(t *testing.T) repository.AuditRepository {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.AUDIT_TABLE+", "+repository.AUDIT_CHECKPOINT_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresAudit(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// organizationBackend opens empty organization and attribute schema
+// repositories.
+type organizationBackend struct {
+	name string
+	open func(t *testing.T) (repository.OrganizationRepository, repository.AttributeSchemaRepository)
+}
+
+func organizationBackends() []organizationBackend {
+	backends := []organizationBackend{{
+		name: "memory",
+		open: func(t *testing.T) (repository.OrganizationRepository, repository.AttributeSchemaRepository) {
+			return repository.InitMemoryOrganizations(), repository.InitMemoryAttributeSchemas()
+		},
+	}}
+	if mongoDatabase != nil {
+		backends = append(backends, organizationBackend{
+			name: "mongo",
+			open: func(t *testing.T) (repository.OrganizationRepository, repository.AttributeSchemaRepository) {
+				for _, collection := range []string{repository.ORGANIZATION_COLLECTION, repository.ATTRIBUTE_SCHEMA_COLLECTION} {
+					_, err := mongoDatabase.Collection(collection).DeleteMany(context.Background(), bson.M{})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				return repository.InitMongoOrganizations(mongoDatabase), repository.InitMongoAttributeSchemas(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, organizationBackend{
+			name: "postgres",
+			open: func(t *testing.T) (repository.OrganizationRepository, repository.AttributeSchemaRepository) {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.ORGANIZATION_TABLE+", "+repository.ATTRIBUTE_SCHEMA_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresOrganizations(postgresDB), repository.InitPostgresAttributeSchemas(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// avatarBackend opens an empty avatar store.
+type avatarBackend struct {
+	name string
+	open func(t *testing.T) repository.AvatarStore
+}
+
+func avatarBackends() []avatarBackend {
+	backends := []avatarBackend{{
+		name: "memory",
+		open: func(t *testing.T) repository.AvatarStore {
+			return repository.InitMemoryAvatars()
+		},
+	}}
+	if mongoDatabase != nil {
+		backends = append(backends, avatarBackend{
+			name: "mongo",
+			open: func(t *testing.T) repository.AvatarStore {
+				for _, collection := range []string{repository.AVATAR_BUCKET + ".files", repository.AVATAR_BUCKET + ".chunks"} {
+					_, err := mongoDatabase.Collection(collection).DeleteMany(context.Background(), bson.M{})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				return repository.InitMongoAvatars(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, avatarBackend{
+			name: "postgres",
+			open: func(t *testing.T) repository.AvatarStore {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.AVATAR_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresAvatars(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// webhookBackend opens an empty webhook repository.
+type webhookBackend struct {
+	name string
+	open func(t *testing.T) repository.WebhookRepository
+}
+
+func webhookBackends() []webhookBackend {
+	backends := []webhookBackend{{
+		name: "memory",
+		open: func(t *testing.T) repository.WebhookRepository {
+			return repository.InitMemoryWebhooks()
+		},
+	}}
+	if mongoDatabase != nil {
+		backends = append(backends, webhookBackend{
+			name: "mongo",
+			open: func(t *testing.T) repository.WebhookRepository {
+				for _, collection := range []string{repository.WEBHOOK_COLLECTION, repository.WEBHOOK_DELIVERY_COLLECTION} {
+					_, err := mongoDatabase.Collection(collection).DeleteMany(context.Background(), bson.M{})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				return repository.InitMongoWebhooks(mongoDatabase)
+			},
+		})
+	}
+	if postgresDB != nil {
+		backends = append(backends, webhookBackend{
+			name: "postgres",
+			open: func(t *testing.T) repository.WebhookRepository {
+				_, err := postgresDB.ExecContext(context.Background(), "TRUNCATE "+repository.WEBHOOK_TABLE+", "+repository.WEBHOOK_DELIVERY_TABLE)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repository.InitPostgresWebhooks(postgresDB)
+			},
+		})
+	}
+	return backends
+}
+
+// sessionBackend opens a session store, it may hold sessions of other
+// tests so cases use accounts and token ids of their own.
+type sessionBackend struct {
+	name string
+	open func(t *testing.T) repository.SessionStore
+}
+
+func sessionBackends() []sessionBackend {
+	backends := []sessionBackend{{
+		name: "memory",
+		open: func(t *testing.T) repository.SessionStore {
+			return repository.InitMemorySessions()
+		},
+	}, {
+		name: "bolt",
+		open: func(t *testing.T) repository.SessionStore {
+			sessions, err := repository.InitBoltSessions(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { sessions.Close() })
+			return sessions
+		},
+	}}
+	if redisClient != nil {
+		backends = append(backends, sessionBackend{
+			name: "redis",
+			open: func(t *testing.T) repository.SessionStore {
+				return repository.InitRedisSessions(redisClient)
+			},
+		})
+	}
+	return backends
+}
+
+var sequence int64
+
+// nextId returns ids unique within and across test runs.
+func nextId() string {
+	return fmt.Sprintf("%x%06d", time.Now().UnixNano(), atomic.AddInt64(&sequence, 1))
+}
+
+// created returns increasing creation times so listings have a known order.
+func created() int64 {
+	return time.Now().UnixMilli() + atomic.AddInt64(&sequence, 1)
+}
+
+func newAccount(orgId string, name string, email string) *model.AccountModel {
+	return &model.AccountModel{
+		Id:              nextId(),
+		OrgId:           orgId,
+		Name:            name,
+		Email:           email,
+		EmailNormalized: strings.ToLower(email),
+		Password:        "hash",
+		CreatedAt:       created(),
+	}
+}
+
+// newRole keeps the creation time to milliseconds, all Mongo stores.
+func newRole(orgId string, name string, roleType model.EnumRole) *model.RoleModel {
+	return &model.RoleModel{
+		Id:          nextId(),
+		OrgId:       orgId,
+		Name:        name,
+		Role:        roleType,
+		Description: name + " role",
+		CreatedAt:   time.UnixMilli(created()).UTC(),
+	}
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+func mustCreateAccount(t *testing.T, accounts repository.AccountRepository, account *model.AccountModel) *model.AccountModel {
+	t.Helper()
+	err := accounts.Create(context.Background(), account)
+	if err != nil {
+		t.Fatalf("creating account %s: %v", account.Email, err)
+	}
+	return account
+}
+
+func mustCreateRole(t *testing.T, roles repository.RoleRepository, role *model.RoleModel) *model.RoleModel {
+	t.Helper()
+	err := roles.Create(context.Background(), role)
+	if err != nil {
+		t.Fatalf("creating role %s: %v", role.Name, err)
+	}
+	return role
+}
+
+func expectError(t *testing.T, err error, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+func expectAccount(t *testing.T, got *model.AccountModel, want *model.AccountModel) {
+	t.Helper()
+	if got.Id != want.Id || got.OrgId != want.OrgId || got.Name != want.Name || got.Email != want.Email ||
+		got.EmailNormalized != want.EmailNormalized || got.Role != want.Role || got.Password != want.Password ||
+		got.Version != want.Version || got.CreatedAt != want.CreatedAt || got.UpdatedAt != want.UpdatedAt {
+		t.Fatalf("got account %+v, want %+v", *got, *want)
+	}
+	if fmt.Sprint(got.Attributes) != fmt.Sprint(want.Attributes) {
+		t.Fatalf("got attributes %v, want %v", got.Attributes, want.Attributes)
+	}
+}
+
+func expectRole(t *testing.T, got *model.RoleModel, want *model.RoleModel) {
+	t.Helper()
+	if got.Id != want.Id || got.OrgId != want.OrgId || got.Name != want.Name || got.Role != want.Role ||
+		got.Description != want.Description || got.Version != want.Version || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("got role %+v, want %+v", *got, *want)
+	}
+}
+
+func accountIds(accounts []model.AccountModel) []string {
+	ids := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		ids = append(ids, account.Id)
+	}
+	return ids
+}
+
+func roleIds(roles []model.RoleModel) []string {
+	ids := make([]string, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	return ids
+}
+
+func expectIds(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+}
+
+var accountCases = []struct {
+	name string
+	run  func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository)
+}{
+	{"create and find", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := newAccount("org-a", "Ada", "Ada@Example.com")
+		account.Attributes = map[string]interface{}{"team": "red"}
+		mustCreateAccount(t, accounts, account)
+
+		found, err := accounts.FindById(ctx, repository.OrganizationScope("org-a"), account.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, account)
+		found, err = accounts.FindByEmail(ctx, repository.OrganizationScope("org-a"), "ada@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, account)
+		found, err = accounts.FindById(ctx, repository.AnyScope(), account.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, account)
+
+		_, err = accounts.FindById(ctx, repository.OrganizationScope("org-b"), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = accounts.FindById(ctx, repository.OrganizationScope(""), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = accounts.FindByEmail(ctx, repository.OrganizationScope("org-b"), "ada@example.com")
+		expectError(t, err, repository.ErrNotFound)
+		_, err = accounts.FindById(ctx, repository.AnyScope(), "missing")
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"account without organization", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := mustCreateAccount(t, accounts, newAccount("", "Root", "root@example.com"))
+
+		found, err := accounts.FindByEmail(ctx, repository.OrganizationScope(""), "root@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, account)
+		_, err = accounts.FindById(ctx, repository.OrganizationScope("org-a"), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+		err = accounts.Create(ctx, newAccount("", "Root", "root@example.com"))
+		expectError(t, err, repository.ErrDuplicate)
+	}},
+	{"email unique per organization", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := mustCreateAccount(t, accounts, newAccount("org-a", "Ada", "ada@example.com"))
+
+		err := accounts.Create(ctx, newAccount("org-a", "Other", "ada@example.com"))
+		expectError(t, err, repository.ErrDuplicate)
+		duplicateId := newAccount("org-c", "Other", "other@example.com")
+		duplicateId.Id = account.Id
+		err = accounts.Create(ctx, duplicateId)
+		expectError(t, err, repository.ErrDuplicate)
+		mustCreateAccount(t, accounts, newAccount("org-b", "Ada", "ada@example.com"))
+
+		count, err := accounts.CountByEmail(ctx, "ada@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatalf("got %d organizations, want 2", count)
+		}
+	}},
+	{"list pages in order", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		first := mustCreateAccount(t, accounts, newAccount("org-a", "b", "first@example.com"))
+		second := mustCreateAccount(t, accounts, newAccount("org-a", "c", "second@example.com"))
+		third := mustCreateAccount(t, accounts, newAccount("org-a", "a", "third@example.com"))
+		other := mustCreateAccount(t, accounts, newAccount("org-b", "d", "other@example.com"))
+
+		tests := []struct {
+			scope repository.Scope
+			page  repository.Page
+			want  []string
+		}{
+			{repository.OrganizationScope("org-a"), repository.Page{}, []string{first.Id, second.Id, third.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{Skip: 1, Size: 1}, []string{second.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{Skip: 5}, []string{}},
+			{repository.OrganizationScope("org-a"), repository.Page{OrderBy: "name", Order: "asc"}, []string{third.Id, first.Id, second.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{OrderBy: "name", Order: "desc", Size: 2}, []string{second.Id, first.Id}},
+			{repository.OrganizationScope("org-b"), repository.Page{}, []string{other.Id}},
+			{repository.OrganizationScope("org-c"), repository.Page{}, []string{}},
+			{repository.AnyScope(), repository.Page{}, []string{first.Id, second.Id, third.Id, other.Id}},
+		}
+		for _, test := range tests {
+			list, err := accounts.List(ctx, test.scope, repository.AccountQuery{Page: test.page})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, accountIds(list), test.want...)
+		}
+	}},
+	{"list by attributes", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		red := newAccount("org-a", "Red", "red@example.com")
+		red.Attributes = map[string]interface{}{"team": "red", "site": "north"}
+		mustCreateAccount(t, accounts, red)
+		blue := newAccount("org-a", "Blue", "blue@example.com")
+		blue.Attributes = map[string]interface{}{"team": "blue", "site": "north"}
+		mustCreateAccount(t, accounts, blue)
+		mustCreateAccount(t, accounts, newAccount("org-a", "None", "none@example.com"))
+
+		tests := []struct {
+			attributes map[string]interface{}
+			want       []string
+		}{
+			{map[string]interface{}{"team": "red"}, []string{red.Id}},
+			{map[string]interface{}{"site": "north"}, []string{red.Id, blue.Id}},
+			{map[string]interface{}{"site": "north", "team": "blue"}, []string{blue.Id}},
+			{map[string]interface{}{"team": "green"}, []string{}},
+		}
+		for _, test := range tests {
+			list, err := accounts.List(ctx, repository.OrganizationScope("org-a"), repository.AccountQuery{Attributes: test.attributes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, accountIds(list), test.want...)
+		}
+	}},
+	{"update counts the version", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := mustCreateAccount(t, accounts, newAccount("org-a", "Ada", "ada@example.com"))
+		change := repository.AccountChange{Name: stringPointer("Ada Lovelace"), UpdatedAt: created()}
+
+		previous, updated, err := accounts.Update(ctx, repository.OrganizationScope("org-a"), account.Id, int64Pointer(0), change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, previous, account)
+		want := *account
+		change.Apply(&want)
+		expectAccount(t, updated, &want)
+		if updated.Version != 1 {
+			t.Fatalf("got version %d, want 1", updated.Version)
+		}
+		found, err := accounts.FindById(ctx, repository.OrganizationScope("org-a"), account.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, &want)
+
+		_, _, err = accounts.Update(ctx, repository.OrganizationScope("org-a"), account.Id, int64Pointer(0), change)
+		expectError(t, err, repository.ErrNotFound)
+		_, _, err = accounts.Update(ctx, repository.OrganizationScope("org-b"), account.Id, nil, change)
+		expectError(t, err, repository.ErrNotFound)
+		_, updated, err = accounts.Update(ctx, repository.OrganizationScope("org-a"), account.Id, nil, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != 2 {
+			t.Fatalf("got version %d, want 2", updated.Version)
+		}
+	}},
+	{"update to a taken email", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		mustCreateAccount(t, accounts, newAccount("org-a", "Ada", "ada@example.com"))
+		account := mustCreateAccount(t, accounts, newAccount("org-a", "Bob", "bob@example.com"))
+		change := repository.AccountChange{Email: stringPointer("ada@example.com"), EmailNormalized: stringPointer("ada@example.com")}
+
+		_, _, err := accounts.Update(ctx, repository.OrganizationScope("org-a"), account.Id, nil, change)
+		expectError(t, err, repository.ErrDuplicate)
+		found, err := accounts.FindById(ctx, repository.OrganizationScope("org-a"), account.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, found, account)
+	}},
+	{"replace attributes", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := newAccount("org-a", "Ada", "ada@example.com")
+		account.Attributes = map[string]interface{}{"team": "red"}
+		mustCreateAccount(t, accounts, account)
+
+		tests := []struct {
+			attributes map[string]interface{}
+			want       map[string]interface{}
+		}{
+			{map[string]interface{}{"team": "blue", "site": "north"}, map[string]interface{}{"team": "blue", "site": "north"}},
+			{map[string]interface{}{}, nil},
+		}
+		for _, test := range tests {
+			change := repository.AccountChange{Attributes: test.attributes, ReplaceAttributes: true}
+			_, updated, err := accounts.Update(ctx, repository.OrganizationScope("org-a"), account.Id, nil, change)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, err := accounts.FindById(ctx, repository.OrganizationScope("org-a"), account.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range []*model.AccountModel{updated, found} {
+				if fmt.Sprint(got.Attributes) != fmt.Sprint(test.want) || len(got.Attributes) != len(test.want) {
+					t.Fatalf("got attributes %v, want %v", got.Attributes, test.want)
+				}
+			}
+		}
+	}},
+	{"update by email", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := mustCreateAccount(t, accounts, newAccount("org-a", "Ada", "ada@example.com"))
+		change := repository.AccountChange{Password: stringPointer("new hash")}
+
+		_, _, err := accounts.UpdateByEmail(ctx, repository.OrganizationScope("org-b"), "ada@example.com", change)
+		expectError(t, err, repository.ErrNotFound)
+		previous, updated, err := accounts.UpdateByEmail(ctx, repository.OrganizationScope("org-a"), "ada@example.com", change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, previous, account)
+		if updated.Password != "new hash" || updated.Version != 1 {
+			t.Fatalf("got account %+v, want the new password at version 1", *updated)
+		}
+	}},
+	{"delete", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		account := mustCreateAccount(t, accounts, newAccount("org-a", "Ada", "ada@example.com"))
+
+		_, err := accounts.Delete(ctx, repository.OrganizationScope("org-b"), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+		deleted, err := accounts.Delete(ctx, repository.OrganizationScope("org-a"), account.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAccount(t, deleted, account)
+		_, err = accounts.FindById(ctx, repository.AnyScope(), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = accounts.Delete(ctx, repository.AnyScope(), account.Id)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"count by organization and role", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		for _, orgId := range []string{"org-a", "org-b"} {
+			mustCreateRole(t, roles, newRole(orgId, "member", model.USER))
+			mustCreateRole(t, roles, newRole(orgId, "admin", model.SUPERADMIN))
+		}
+		for index, account := range []*model.AccountModel{
+			newAccount("org-a", "Ada", "ada@example.com"),
+			newAccount("org-a", "Bob", "bob@example.com"),
+			newAccount("org-a", "Cyd", "cyd@example.com"),
+			newAccount("org-b", "Dee", "dee@example.com"),
+		} {
+			account.Role = "member"
+			if index == 0 {
+				account.Role = "admin"
+			}
+			mustCreateAccount(t, accounts, account)
+		}
+
+		counts, err := accounts.CountByRole(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts["member"] != 3 || counts["admin"] != 1 {
+			t.Fatalf("got counts %v, want 3 members and 1 admin", counts)
+		}
+		for orgId, want := range map[string]int64{"org-a": 3, "org-b": 1, "org-c": 0} {
+			count, err := accounts.CountByOrganization(ctx, orgId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != want {
+				t.Fatalf("got %d accounts in %s, want %d", count, orgId, want)
+			}
+		}
+	}},
+	{"count with role", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		for _, orgId := range []string{"org-a", "org-b"} {
+			mustCreateRole(t, roles, newRole(orgId, "member", model.USER))
+			mustCreateRole(t, roles, newRole(orgId, "guest", model.GUEST))
+		}
+		for _, account := range []*model.AccountModel{
+			newAccount("org-a", "Ada", "ada@example.com"),
+			newAccount("org-a", "Bob", "bob@example.com"),
+			newAccount("org-b", "Cyd", "cyd@example.com"),
+		} {
+			account.Role = "member"
+			mustCreateAccount(t, accounts, account)
+		}
+		mustCreateAccount(t, accounts, newAccount("", "Root", "root@example.com"))
+
+		tests := []struct {
+			orgId string
+			role  string
+			want  int64
+		}{
+			{"org-a", "member", 2},
+			{"org-b", "member", 1},
+			{"org-a", "guest", 0},
+			{"org-c", "member", 0},
+			{"", "member", 0},
+		}
+		for _, test := range tests {
+			count, err := accounts.CountWithRole(ctx, test.orgId, test.role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.want {
+				t.Fatalf("got %d accounts of %s holding %s, want %d", count, test.orgId, test.role, test.want)
+			}
+		}
+	}},
+	{"unset attribute", func(t *testing.T, accounts repository.AccountRepository, roles repository.RoleRepository) {
+		ctx := context.Background()
+		holders := []*model.AccountModel{
+			newAccount("org-a", "Ada", "ada@example.com"),
+			newAccount("org-a", "Bob", "bob@example.com"),
+			newAccount("org-b", "Cyd", "cyd@example.com"),
+		}
+		for _, account := range holders {
+			account.Attributes = map[string]interface{}{"team": "red", "site": "north"}
+			mustCreateAccount(t, accounts, account)
+		}
+		without := mustCreateAccount(t, accounts, newAccount("org-a", "Dee", "dee@example.com"))
+
+		updates, err := accounts.UnsetAttribute(ctx, "org-a", "team")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != 2 {
+			t.Fatalf("got %d accounts modified, want 2", len(updates))
+		}
+		for _, update := range updates {
+			if update.Previous.Id != update.Updated.Id || (update.Updated.Id != holders[0].Id && update.Updated.Id != holders[1].Id) {
+				t.Fatalf("got update of %s to %s, want the org-a holders", update.Previous.Id, update.Updated.Id)
+			}
+			if fmt.Sprint(update.Previous.Attributes) != "map[site:north team:red]" || update.Previous.Version != 0 {
+				t.Fatalf("got previous %v at version %d, want the attribute still set at version 0", update.Previous.Attributes, update.Previous.Version)
+			}
+			if fmt.Sprint(update.Updated.Attributes) != "map[site:north]" || update.Updated.Version != 1 {
+				t.Fatalf("got updated %v at version %d, want it removed at version 1", update.Updated.Attributes, update.Updated.Version)
+			}
+		}
+		tests := []struct {
+			account    *model.AccountModel
+			attributes string
+			version    int64
+		}{
+			{holders[0], "map[site:north]", 1},
+			{holders[1], "map[site:north]", 1},
+			{holders[2], "map[site:north team:red]", 0},
+			{without, "map[]", 0},
+		}
+		for _, test := range tests {
+			found, err := accounts.FindById(ctx, repository.AnyScope(), test.account.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(found.Attributes) != test.attributes || found.Version != test.version {
+				t.Fatalf("got %v at version %d for %s, want %s at version %d", found.Attributes, found.Version, found.Email, test.attributes, test.version)
+			}
+		}
+	}},
+}
+
+var roleCases = []struct {
+	name string
+	run  func(t *testing.T, roles repository.RoleRepository)
+}{
+	{"create and find", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		role := mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+
+		found, err := roles.FindById(ctx, repository.OrganizationScope("org-a"), role.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, found, role)
+		found, err = roles.FindByName(ctx, repository.OrganizationScope("org-a"), "member")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, found, role)
+		found, err = roles.FindById(ctx, repository.AnyScope(), role.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, found, role)
+
+		_, err = roles.FindById(ctx, repository.OrganizationScope("org-b"), role.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = roles.FindByName(ctx, repository.OrganizationScope("org-b"), "member")
+		expectError(t, err, repository.ErrNotFound)
+		_, err = roles.FindByName(ctx, repository.OrganizationScope("org-a"), "missing")
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"name unique per organization", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		role := mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+
+		err := roles.Create(ctx, newRole("org-a", "member", model.GUEST))
+		expectError(t, err, repository.ErrDuplicate)
+		duplicateId := newRole("org-c", "guest", model.GUEST)
+		duplicateId.Id = role.Id
+		err = roles.Create(ctx, duplicateId)
+		expectError(t, err, repository.ErrDuplicate)
+		mustCreateRole(t, roles, newRole("org-b", "member", model.USER))
+	}},
+	{"list pages in order", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		first := mustCreateRole(t, roles, newRole("org-a", "b", model.USER))
+		second := mustCreateRole(t, roles, newRole("org-a", "c", model.GUEST))
+		third := mustCreateRole(t, roles, newRole("org-a", "a", model.AUDITOR))
+		other := mustCreateRole(t, roles, newRole("org-b", "d", model.USER))
+
+		tests := []struct {
+			scope repository.Scope
+			page  repository.Page
+			want  []string
+		}{
+			{repository.OrganizationScope("org-a"), repository.Page{}, []string{first.Id, second.Id, third.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{Skip: 2, Size: 5}, []string{third.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{OrderBy: "name", Order: "asc"}, []string{third.Id, first.Id, second.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{OrderBy: "name", Order: "desc", Size: 1}, []string{second.Id}},
+			{repository.OrganizationScope("org-c"), repository.Page{}, []string{}},
+			{repository.AnyScope(), repository.Page{}, []string{first.Id, second.Id, third.Id, other.Id}},
+		}
+		for _, test := range tests {
+			list, err := roles.List(ctx, test.scope, test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, roleIds(list), test.want...)
+		}
+	}},
+	{"update counts the version", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		role := mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+		roleType := model.GUEST
+		change := repository.RoleChange{Name: stringPointer("visitor"), Role: &roleType, UpdatedAt: time.UnixMilli(created()).UTC()}
+
+		previous, updated, err := roles.Update(ctx, repository.OrganizationScope("org-a"), role.Id, int64Pointer(0), change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, previous, role)
+		want := *role
+		change.Apply(&want)
+		expectRole(t, updated, &want)
+		found, err := roles.FindByName(ctx, repository.OrganizationScope("org-a"), "visitor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, found, &want)
+		if found.UpdatedAt == nil || !found.UpdatedAt.Equal(change.UpdatedAt) {
+			t.Fatalf("got updatedAt %v, want %v", found.UpdatedAt, change.UpdatedAt)
+		}
+
+		_, _, err = roles.Update(ctx, repository.OrganizationScope("org-a"), role.Id, int64Pointer(0), change)
+		expectError(t, err, repository.ErrNotFound)
+		_, _, err = roles.Update(ctx, repository.OrganizationScope("org-b"), role.Id, nil, change)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"rename to a taken name", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+		role := mustCreateRole(t, roles, newRole("org-a", "guest", model.GUEST))
+
+		_, _, err := roles.Update(ctx, repository.OrganizationScope("org-a"), role.Id, nil, repository.RoleChange{Name: stringPointer("member"), UpdatedAt: time.Now()})
+		expectError(t, err, repository.ErrDuplicate)
+		found, err := roles.FindById(ctx, repository.OrganizationScope("org-a"), role.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, found, role)
+	}},
+	{"delete", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		role := mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+
+		_, err := roles.Delete(ctx, repository.OrganizationScope("org-b"), role.Id)
+		expectError(t, err, repository.ErrNotFound)
+		deleted, err := roles.Delete(ctx, repository.OrganizationScope("org-a"), role.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRole(t, deleted, role)
+		_, err = roles.FindById(ctx, repository.AnyScope(), role.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = roles.Delete(ctx, repository.AnyScope(), role.Id)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"delete by organization", func(t *testing.T, roles repository.RoleRepository) {
+		ctx := context.Background()
+		first := mustCreateRole(t, roles, newRole("org-a", "member", model.USER))
+		second := mustCreateRole(t, roles, newRole("org-a", "guest", model.GUEST))
+		other := mustCreateRole(t, roles, newRole("org-b", "member", model.USER))
+
+		deleted, err := roles.DeleteByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := roleIds(deleted)
+		sort.Strings(ids)
+		want := []string{first.Id, second.Id}
+		sort.Strings(want)
+		expectIds(t, ids, want...)
+		list, err := roles.List(ctx, repository.AnyScope(), repository.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, roleIds(list), other.Id)
+	}},
+}
+
+var sessionCases = []struct {
+	name string
+	run  func(t *testing.T, sessions repository.SessionStore)
+}{
+	{"create and fetch", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		account, access, refresh := nextId(), nextId(), nextId()
+		session := model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}
+		err := sessions.Create(ctx, account, session, access, time.Now().Add(time.Minute), refresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, uuid := range []string{access, refresh} {
+			found, err := sessions.Fetch(ctx, uuid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *found != session {
+				t.Fatalf("got session %+v, want %+v", *found, session)
+			}
+		}
+		_, err = sessions.Fetch(ctx, nextId())
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"delete", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		account, access, refresh := nextId(), nextId(), nextId()
+		session := model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}
+		err := sessions.Create(ctx, account, session, access, time.Now().Add(time.Minute), refresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []int64{1, 0} {
+			deleted, err := sessions.Delete(ctx, access)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != want {
+				t.Fatalf("got %d deleted, want %d", deleted, want)
+			}
+		}
+		_, err = sessions.Fetch(ctx, access)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = sessions.Fetch(ctx, refresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{"expiry", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		account, access, refresh := nextId(), nextId(), nextId()
+		session := model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}
+		err := sessions.Create(ctx, account, session, access, time.Now().Add(time.Second), refresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second * 3 / 2)
+
+		_, err = sessions.Fetch(ctx, access)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = sessions.Fetch(ctx, refresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := sessions.List(ctx, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Uuid != refresh {
+			t.Fatalf("got sessions %+v, want the refresh token only", list)
+		}
+	}},
+	{"list by account", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		account, access, refresh := nextId(), nextId(), nextId()
+		other, otherAccess, otherRefresh := nextId(), nextId(), nextId()
+		err := sessions.Create(ctx, account, model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}, access, time.Now().Add(time.Minute), refresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sessions.Create(ctx, other, model.SessionModel{Email: "bob@example.com", OrgId: "org-b"}, otherAccess, time.Now().Add(time.Minute), otherRefresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := sessions.List(ctx, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expires := map[string]time.Duration{}
+		for _, info := range list {
+			if info.Email != "ada@example.com" || info.OrgId != "org-a" {
+				t.Fatalf("got session %+v of another account", info)
+			}
+			expires[info.Uuid] = info.ExpiresIn
+		}
+		if len(list) != 2 {
+			t.Fatalf("got %d sessions, want 2", len(list))
+		}
+		if expires[access] <= 0 || expires[access] > time.Minute || expires[refresh] <= time.Minute || expires[refresh] > time.Hour {
+			t.Fatalf("got expiries %v, want a minute for %s and an hour for %s", expires, access, refresh)
+		}
+
+		all, err := sessions.List(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed := map[string]bool{}
+		for _, info := range all {
+			listed[info.Uuid] = true
+		}
+		for _, uuid := range []string{access, refresh, otherAccess, otherRefresh} {
+			if !listed[uuid] {
+				t.Fatalf("token %s missing from the sessions of all accounts", uuid)
+			}
+		}
+	}},
+	{"revoke", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		account, other := nextId(), nextId()
+		tokens := []string{nextId(), nextId(), nextId(), nextId()}
+		otherAccess, otherRefresh := nextId(), nextId()
+		for index := 0; index < len(tokens); index += 2 {
+			err := sessions.Create(ctx, account, model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}, tokens[index], time.Now().Add(time.Minute), tokens[index+1], time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := sessions.Create(ctx, other, model.SessionModel{Email: "bob@example.com", OrgId: "org-a"}, otherAccess, time.Now().Add(time.Minute), otherRefresh, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []int64{4, 0} {
+			revoked, err := sessions.Revoke(ctx, account)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != want {
+				t.Fatalf("got %d revoked, want %d", revoked, want)
+			}
+		}
+		for _, uuid := range tokens {
+			_, err = sessions.Fetch(ctx, uuid)
+			expectError(t, err, repository.ErrNotFound)
+		}
+		list, err := sessions.List(ctx, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 0 {
+			t.Fatalf("got sessions %+v after revoking", list)
+		}
+		_, err = sessions.Fetch(ctx, otherRefresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{"count active", func(t *testing.T, sessions repository.SessionStore) {
+		ctx := context.Background()
+		before, err := sessions.CountActive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		account, refresh := nextId(), nextId()
+		for _, uuid := range []string{refresh, nextId()} {
+			err = sessions.Create(ctx, account, model.SessionModel{Email: "ada@example.com", OrgId: "org-a"}, nextId(), time.Now().Add(time.Minute), uuid, time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			change func() error
+			want   int64
+		}{
+			{func() error { return nil }, 2},
+			{func() error { _, err := sessions.Delete(ctx, refresh); return err }, 1},
+			{func() error { _, err := sessions.Revoke(ctx, account); return err }, 0},
+		}
+		for _, test := range tests {
+			err = test.change()
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := sessions.CountActive(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count-before != test.want {
+				t.Fatalf("got %d more active sessions, want %d", count-before, test.want)
+			}
+		}
+	}},
+}
+
+var outboxCases = []struct {
+	name string
+	run  func(t *testing.T, outbox repository.OutboxRepository)
+}{
+	{"pending in order until published", func(t *testing.T, outbox repository.OutboxRepository) {
+		ctx := context.Background()
+		events := make([]*model.OutboxEventModel, 0, 3)
+		for _, eventType := range []model.EnumDomainEvent{model.EVENT_ROLE_CREATED, model.EVENT_ACCOUNT_CREATED, model.EVENT_ACCOUNT_UPDATED} {
+			event := &model.OutboxEventModel{
+				Id:          nextId(),
+				Type:        eventType,
+				OrgId:       "org-a",
+				AggregateId: nextId(),
+				Payload:     `{"name":"Ada"}`,
+				CreatedAt:   time.UnixMilli(created()).UTC(),
+			}
+			err := outbox.Add(ctx, event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+
+		pending, err := outbox.Pending(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 2 {
+			t.Fatalf("got %d pending events, want 2", len(pending))
+		}
+		for index, event := range pending {
+			want := events[index]
+			if event.Id != want.Id || event.Type != want.Type || event.OrgId != want.OrgId || event.AggregateId != want.AggregateId ||
+				event.Payload != want.Payload || !event.CreatedAt.Equal(want.CreatedAt) || event.PublishedAt != nil {
+				t.Fatalf("got event %+v, want %+v", event, *want)
+			}
+		}
+
+		err = outbox.MarkPublished(ctx, events[0].Id, "1-0", time.Now().UTC())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending, err = outbox.Pending(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, 0, len(pending))
+		for _, event := range pending {
+			ids = append(ids, event.Id)
+		}
+		expectIds(t, ids, events[1].Id, events[2].Id)
+	}},
+}
+
+// appendAuditEvent chains a new event to the head like the auditor does,
+// with a stand-in for the hash.
+func appendAuditEvent(t *testing.T, audit repository.AuditRepository, orgId string, action model.EnumAuditAction, createdAt time.Time) model.AuditEventModel {
+	t.Helper()
+	ctx := context.Background()
+	sequence, hash, err := audit.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := model.AuditEventModel{
+		Id:        nextId(),
+		OrgId:     orgId,
+		Action:    action,
+		Actor:     model.AuditActorModel{Email: "ada@example.com"},
+		Target:    model.AuditTargetModel{Type: "account", Id: "account-" + orgId},
+		Changes:   []model.AuditChangeModel{{Field: "name", Before: "Ada", After: "Bob"}},
+		CreatedAt: createdAt,
+		Sequence:  sequence + 1,
+		PrevHash:  hash,
+	}
+	content, err := audit.Content(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Hash = fmt.Sprintf("hash-%d-%d", event.Sequence, len(content))
+	err = audit.Append(ctx, event, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func auditIds(events []model.AuditEventModel) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	return ids
+}
+
+var auditCases = []struct {
+	name string
+	run  func(t *testing.T, audit repository.AuditRepository)
+}{
+	{"append to the head", func(t *testing.T, audit repository.AuditRepository) {
+		ctx := context.Background()
+		sequence, hash, err := audit.Head(ctx)
+		if err != nil || sequence != 0 || hash != "" {
+			t.Fatalf("got head %d %q %v of an empty chain", sequence, hash, err)
+		}
+		appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_CREATE, time.UnixMilli(created()).UTC())
+		second := appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_UPDATE, time.UnixMilli(created()).UTC())
+		sequence, hash, err = audit.Head(ctx)
+		if err != nil || sequence != 2 || hash != second.Hash {
+			t.Fatalf("got head %d %q %v, want 2 %q", sequence, hash, err, second.Hash)
+		}
+
+		racing := second
+		racing.Id = nextId()
+		content, err := audit.Content(racing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, audit.Append(ctx, racing, content), repository.ErrDuplicate)
+	}},
+	{"walk reads the content back", func(t *testing.T, audit repository.AuditRepository) {
+		ctx := context.Background()
+		appended := []model.AuditEventModel{
+			appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_CREATE, time.UnixMilli(created()).UTC()),
+			appendAuditEvent(t, audit, "", model.AUDIT_ORGANIZATION_CREATE, time.UnixMilli(created()).UTC()),
+			appendAuditEvent(t, audit, "org-b", model.AUDIT_ROLE_DELETE, time.UnixMilli(created()).UTC()),
+		}
+		walked := 0
+		err := audit.Walk(ctx, func(event model.AuditEventModel, content []byte) error {
+			want := appended[walked]
+			walked++
+			if event.Id != want.Id || event.Sequence != want.Sequence || event.PrevHash != want.PrevHash || event.Hash != want.Hash ||
+				event.Action != want.Action || !event.CreatedAt.Equal(want.CreatedAt) {
+				t.Fatalf("got event %+v, want %+v", event, want)
+			}
+			wantContent, err := audit.Content(want)
+			if err != nil {
+				return err
+			}
+			if string(content) != string(wantContent) {
+				t.Fatalf("content of %d read back as %q, want %q", event.Sequence, content, wantContent)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if walked != len(appended) {
+			t.Fatalf("walked %d events, want %d", walked, len(appended))
+		}
+		unchained, err := audit.CountUnchained(ctx)
+		if err != nil || unchained != 0 {
+			t.Fatalf("got %d unchained events, %v", unchained, err)
+		}
+	}},
+	{"checkpoints by sequence", func(t *testing.T, audit repository.AuditRepository) {
+		ctx := context.Background()
+		for _, sequence := range []int64{20, 10} {
+			err := audit.AddCheckpoint(ctx, model.AuditCheckpointModel{
+				Id:        nextId(),
+				Sequence:  sequence,
+				Hash:      fmt.Sprintf("hash-%d", sequence),
+				Signature: fmt.Sprintf("signature-%d", sequence),
+				CreatedAt: time.UnixMilli(created()).UTC(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := audit.AddCheckpoint(ctx, model.AuditCheckpointModel{Id: nextId(), Sequence: 10, CreatedAt: time.UnixMilli(created()).UTC()})
+		expectError(t, err, repository.ErrDuplicate)
+
+		checkpoints, err := audit.Checkpoints(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(checkpoints) != 2 || checkpoints[0].Sequence != 10 || checkpoints[1].Sequence != 20 ||
+			checkpoints[0].Hash != "hash-10" || checkpoints[1].Signature != "signature-20" {
+			t.Fatalf("got checkpoints %+v", checkpoints)
+		}
+	}},
+	{"list narrows and pages", func(t *testing.T, audit repository.AuditRepository) {
+		ctx := context.Background()
+		start := time.UnixMilli(created()).UTC()
+		first := appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_CREATE, start)
+		second := appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_UPDATE, start.Add(time.Minute))
+		appendAuditEvent(t, audit, "org-b", model.AUDIT_ACCOUNT_UPDATE, start.Add(time.Minute*2))
+		fourth := appendAuditEvent(t, audit, "org-a", model.AUDIT_ACCOUNT_UPDATE, start.Add(time.Minute*3))
+
+		cases := []struct {
+			scope repository.Scope
+			query repository.AuditQuery
+			want  []string
+			total int64
+		}{
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{}, []string{first.Id, second.Id, fourth.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{Descending: true, Size: 2}, []string{fourth.Id, second.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{Descending: true, Skip: 2, Size: 2}, []string{first.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{Action: string(model.AUDIT_ACCOUNT_UPDATE)}, []string{second.Id, fourth.Id}, 2},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{From: start.Add(time.Minute), To: start.Add(time.Minute * 3)}, []string{second.Id}, 1},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{ActorEmail: "ada@example.com", TargetType: "account", TargetId: "account-org-a", Size: 1}, []string{first.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.AuditQuery{TargetId: "account-org-b"}, []string{}, 0},
+			{repository.AnyScope(), repository.AuditQuery{Action: string(model.AUDIT_ACCOUNT_UPDATE)}, nil, 3},
+		}
+		for _, test := range cases {
+			events, total, err := audit.List(ctx, test.scope, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != test.total {
+				t.Fatalf("query %+v counted %d events, want %d", test.query, total, test.total)
+			}
+			if test.want != nil {
+				expectIds(t, auditIds(events), test.want...)
+			}
+		}
+		events, _, err := audit.List(ctx, repository.OrganizationScope("org-a"), repository.AuditQuery{Size: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Hash != first.Hash || len(events[0].Changes) != 1 || events[0].Changes[0].Field != "name" {
+			t.Fatalf("got events %+v", events)
+		}
+	}},
+}
+
+func newOrganization(name string) *model.OrganizationModel {
+	return &model.OrganizationModel{
+		Id:          nextId(),
+		Name:        name,
+		Description: name + " organization",
+		CreatedAt:   created(),
+	}
+}
+
+func newAttributeSchema(orgId string, name string, attributeType model.EnumAttributeType) *model.AttributeSchemaModel {
+	return &model.AttributeSchemaModel{
+		Id:          nextId(),
+		OrgId:       orgId,
+		Name:        name,
+		Type:        attributeType,
+		Description: name + " attribute",
+		CreatedAt:   created(),
+	}
+}
+
+func mustCreateOrganization(t *testing.T, organizations repository.OrganizationRepository, organization *model.OrganizationModel) *model.OrganizationModel {
+	t.Helper()
+	err := organizations.Create(context.Background(), organization)
+	if err != nil {
+		t.Fatalf("creating organization %s: %v", organization.Name, err)
+	}
+	return organization
+}
+
+func mustCreateAttributeSchema(t *testing.T, schemas repository.AttributeSchemaRepository, schema *model.AttributeSchemaModel) *model.AttributeSchemaModel {
+	t.Helper()
+	err := schemas.Create(context.Background(), schema)
+	if err != nil {
+		t.Fatalf("creating attribute schema %s: %v", schema.Name, err)
+	}
+	return schema
+}
+
+func expectOrganization(t *testing.T, got *model.OrganizationModel, want *model.OrganizationModel) {
+	t.Helper()
+	if *got != *want {
+		t.Fatalf("got organization %+v, want %+v", *got, *want)
+	}
+}
+
+func expectAttributeSchema(t *testing.T, got *model.AttributeSchemaModel, want *model.AttributeSchemaModel) {
+	t.Helper()
+	if got.Id != want.Id || got.OrgId != want.OrgId || got.Name != want.Name || got.Type != want.Type ||
+		got.Required != want.Required || got.Regex != want.Regex || got.TokenClaim != want.TokenClaim ||
+		got.Description != want.Description || got.CreatedAt != want.CreatedAt || got.UpdatedAt != want.UpdatedAt ||
+		strings.Join(got.Enum, ",") != strings.Join(want.Enum, ",") {
+		t.Fatalf("got attribute schema %+v, want %+v", *got, *want)
+	}
+}
+
+func organizationIds(organizations []model.OrganizationModel) []string {
+	ids := make([]string, 0, len(organizations))
+	for _, organization := range organizations {
+		ids = append(ids, organization.Id)
+	}
+	return ids
+}
+
+func attributeSchemaIds(schemas []model.AttributeSchemaModel) []string {
+	ids := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		ids = append(ids, schema.Id)
+	}
+	return ids
+}
+
+var organizationCases = []struct {
+	name string
+	run  func(t *testing.T, organizations repository.OrganizationRepository)
+}{
+	{"create and find", func(t *testing.T, organizations repository.OrganizationRepository) {
+		ctx := context.Background()
+		organization := mustCreateOrganization(t, organizations, newOrganization("acme"))
+
+		found, err := organizations.FindById(ctx, organization.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOrganization(t, found, organization)
+		found, err = organizations.FindByName(ctx, "acme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOrganization(t, found, organization)
+
+		_, err = organizations.FindById(ctx, "missing")
+		expectError(t, err, repository.ErrNotFound)
+		_, err = organizations.FindByName(ctx, "missing")
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"name unique", func(t *testing.T, organizations repository.OrganizationRepository) {
+		ctx := context.Background()
+		organization := mustCreateOrganization(t, organizations, newOrganization("acme"))
+
+		err := organizations.Create(ctx, newOrganization("acme"))
+		expectError(t, err, repository.ErrDuplicate)
+		duplicateId := newOrganization("globex")
+		duplicateId.Id = organization.Id
+		err = organizations.Create(ctx, duplicateId)
+		expectError(t, err, repository.ErrDuplicate)
+	}},
+	{"list pages in order", func(t *testing.T, organizations repository.OrganizationRepository) {
+		ctx := context.Background()
+		first := mustCreateOrganization(t, organizations, newOrganization("b"))
+		second := mustCreateOrganization(t, organizations, newOrganization("c"))
+		third := mustCreateOrganization(t, organizations, newOrganization("a"))
+
+		tests := []struct {
+			page repository.Page
+			want []string
+		}{
+			{repository.Page{}, []string{first.Id, second.Id, third.Id}},
+			{repository.Page{Skip: 1, Size: 1}, []string{second.Id}},
+			{repository.Page{OrderBy: "name", Order: "asc"}, []string{third.Id, first.Id, second.Id}},
+			{repository.Page{OrderBy: "name", Order: "desc", Size: 2}, []string{second.Id, first.Id}},
+		}
+		for _, test := range tests {
+			list, err := organizations.List(ctx, test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, organizationIds(list), test.want...)
+		}
+	}},
+	{"update returns both versions", func(t *testing.T, organizations repository.OrganizationRepository) {
+		ctx := context.Background()
+		organization := mustCreateOrganization(t, organizations, newOrganization("acme"))
+		mustCreateOrganization(t, organizations, newOrganization("globex"))
+
+		previous, updated, err := organizations.Update(ctx, organization.Id, repository.OrganizationChange{Description: stringPointer("renamed"), UpdatedAt: 42})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOrganization(t, previous, organization)
+		want := *organization
+		want.Description, want.UpdatedAt = "renamed", 42
+		expectOrganization(t, updated, &want)
+		found, err := organizations.FindById(ctx, organization.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOrganization(t, found, &want)
+
+		_, _, err = organizations.Update(ctx, organization.Id, repository.OrganizationChange{Name: stringPointer("globex"), UpdatedAt: 43})
+		expectError(t, err, repository.ErrDuplicate)
+		_, _, err = organizations.Update(ctx, "missing", repository.OrganizationChange{UpdatedAt: 43})
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"delete returns the organization", func(t *testing.T, organizations repository.OrganizationRepository) {
+		ctx := context.Background()
+		organization := mustCreateOrganization(t, organizations, newOrganization("acme"))
+
+		deleted, err := organizations.Delete(ctx, organization.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOrganization(t, deleted, organization)
+		_, err = organizations.FindById(ctx, organization.Id)
+		expectError(t, err, repository.ErrNotFound)
+		_, err = organizations.Delete(ctx, organization.Id)
+		expectError(t, err, repository.ErrNotFound)
+		mustCreateOrganization(t, organizations, newOrganization("acme"))
+	}},
+}
+
+var attributeSchemaCases = []struct {
+	name string
+	run  func(t *testing.T, schemas repository.AttributeSchemaRepository)
+}{
+	{"create and find", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		schema := newAttributeSchema("org-a", "team", model.ATTRIBUTE_STRING)
+		schema.Enum = []string{"red", "blue"}
+		schema.Required = true
+		schema.TokenClaim = true
+		mustCreateAttributeSchema(t, schemas, schema)
+		plain := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "level", model.ATTRIBUTE_NUMBER))
+
+		found, err := schemas.FindById(ctx, repository.OrganizationScope("org-a"), schema.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAttributeSchema(t, found, schema)
+		found, err = schemas.FindById(ctx, repository.AnyScope(), plain.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAttributeSchema(t, found, plain)
+		if found.Enum != nil {
+			t.Fatalf("got enum %v, want none", found.Enum)
+		}
+		_, err = schemas.FindById(ctx, repository.OrganizationScope("org-b"), schema.Id)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"name unique per organization", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "team", model.ATTRIBUTE_STRING))
+
+		err := schemas.Create(ctx, newAttributeSchema("org-a", "team", model.ATTRIBUTE_NUMBER))
+		expectError(t, err, repository.ErrDuplicate)
+		mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-b", "team", model.ATTRIBUTE_STRING))
+	}},
+	{"find by organization and list", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		first := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "b", model.ATTRIBUTE_STRING))
+		second := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "a", model.ATTRIBUTE_BOOLEAN))
+		other := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-b", "c", model.ATTRIBUTE_NUMBER))
+
+		list, err := schemas.FindByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, attributeSchemaIds(list), first.Id, second.Id)
+
+		tests := []struct {
+			scope repository.Scope
+			page  repository.Page
+			want  []string
+		}{
+			{repository.OrganizationScope("org-a"), repository.Page{OrderBy: "name", Order: "asc"}, []string{second.Id, first.Id}},
+			{repository.OrganizationScope("org-a"), repository.Page{Skip: 1, Size: 5}, []string{second.Id}},
+			{repository.OrganizationScope("org-c"), repository.Page{}, []string{}},
+			{repository.AnyScope(), repository.Page{}, []string{first.Id, second.Id, other.Id}},
+		}
+		for _, test := range tests {
+			list, err := schemas.List(ctx, test.scope, test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, attributeSchemaIds(list), test.want...)
+		}
+	}},
+	{"update keeps name and organization", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		schema := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "team", model.ATTRIBUTE_STRING))
+		change := repository.AttributeSchemaChange{Type: model.ATTRIBUTE_STRING, Required: true, Enum: []string{"red"}, Regex: "^r", TokenClaim: true, Description: "changed", UpdatedAt: 42}
+
+		_, _, err := schemas.Update(ctx, repository.OrganizationScope("org-b"), schema.Id, change)
+		expectError(t, err, repository.ErrNotFound)
+		previous, updated, err := schemas.Update(ctx, repository.OrganizationScope("org-a"), schema.Id, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAttributeSchema(t, previous, schema)
+		want := *schema
+		change.Apply(&want)
+		expectAttributeSchema(t, updated, &want)
+		found, err := schemas.FindById(ctx, repository.OrganizationScope("org-a"), schema.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAttributeSchema(t, found, &want)
+	}},
+	{"delete is scoped", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		schema := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "team", model.ATTRIBUTE_STRING))
+
+		_, err := schemas.Delete(ctx, repository.OrganizationScope("org-b"), schema.Id)
+		expectError(t, err, repository.ErrNotFound)
+		deleted, err := schemas.Delete(ctx, repository.OrganizationScope("org-a"), schema.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAttributeSchema(t, deleted, schema)
+		_, err = schemas.FindById(ctx, repository.AnyScope(), schema.Id)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"delete by organization", func(t *testing.T, schemas repository.AttributeSchemaRepository) {
+		ctx := context.Background()
+		mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "team", model.ATTRIBUTE_STRING))
+		mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-a", "level", model.ATTRIBUTE_NUMBER))
+		other := mustCreateAttributeSchema(t, schemas, newAttributeSchema("org-b", "team", model.ATTRIBUTE_STRING))
+
+		err := schemas.DeleteByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := schemas.FindByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, attributeSchemaIds(list))
+		list, err = schemas.FindByOrganization(ctx, "org-b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, attributeSchemaIds(list), other.Id)
+	}},
+}
+
+func avatarVariant(accountId string, variant string, content string) model.AvatarVariantModel {
+	return model.AvatarVariantModel{
+		Metadata: model.AvatarMetadataModel{
+			AccountId:   accountId,
+			OrgId:       "org-a",
+			Variant:     variant,
+			ContentType: "image/png",
+			ETag:        `"` + content + `"`,
+		},
+		Content: []byte(content),
+	}
+}
+
+// readAvatar returns the metadata and content of variant, an empty content
+// when there is none.
+func readAvatar(t *testing.T, avatars repository.AvatarStore, accountId string, variant string) (model.AvatarMetadataModel, string) {
+	t.Helper()
+	file, content, err := avatars.Open(context.Background(), accountId, variant)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.AvatarMetadataModel{}, ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	raw, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Length != int64(len(raw)) || file.UploadDate.IsZero() {
+		t.Fatalf("got file %+v for %d bytes", *file, len(raw))
+	}
+	return file.Metadata, string(raw)
+}
+
+var avatarCases = []struct {
+	name string
+	run  func(t *testing.T, avatars repository.AvatarStore)
+}{
+	{"replace and open", func(t *testing.T, avatars repository.AvatarStore) {
+		ctx := context.Background()
+		err := avatars.Replace(ctx, "account-a", []model.AvatarVariantModel{avatarVariant("account-a", "original", "big"), avatarVariant("account-a", "64", "small")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata, content := readAvatar(t, avatars, "account-a", "original")
+		if content != "big" || metadata != avatarVariant("account-a", "original", "big").Metadata {
+			t.Fatalf("got %+v with %q", metadata, content)
+		}
+		_, content = readAvatar(t, avatars, "account-a", "64")
+		if content != "small" {
+			t.Fatalf("got %q, want small", content)
+		}
+		_, _, err = avatars.Open(ctx, "account-a", "256")
+		expectError(t, err, repository.ErrNotFound)
+		_, _, err = avatars.Open(ctx, "account-b", "original")
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"replace removes the previous variants", func(t *testing.T, avatars repository.AvatarStore) {
+		ctx := context.Background()
+		err := avatars.Replace(ctx, "account-a", []model.AvatarVariantModel{avatarVariant("account-a", "original", "first"), avatarVariant("account-a", "64", "first")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = avatars.Replace(ctx, "account-a", []model.AvatarVariantModel{avatarVariant("account-a", "original", "second")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, content := readAvatar(t, avatars, "account-a", "original")
+		if content != "second" {
+			t.Fatalf("got %q, want second", content)
+		}
+		_, content = readAvatar(t, avatars, "account-a", "64")
+		if content != "" {
+			t.Fatalf("got %q, want the old thumbnail removed", content)
+		}
+	}},
+	{"delete removes one account", func(t *testing.T, avatars repository.AvatarStore) {
+		ctx := context.Background()
+		for _, accountId := range []string{"account-a", "account-b"} {
+			err := avatars.Replace(ctx, accountId, []model.AvatarVariantModel{avatarVariant(accountId, "original", accountId)})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := avatars.Delete(ctx, "account-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = avatars.Delete(ctx, "account-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, content := readAvatar(t, avatars, "account-a", "original")
+		if content != "" {
+			t.Fatalf("got %q, want no avatar", content)
+		}
+		_, content = readAvatar(t, avatars, "account-b", "original")
+		if content != "account-b" {
+			t.Fatalf("got %q, want account-b", content)
+		}
+	}},
+	{"delete by organization", func(t *testing.T, avatars repository.AvatarStore) {
+		ctx := context.Background()
+		for _, accountId := range []string{"account-a", "account-b"} {
+			err := avatars.Replace(ctx, accountId, []model.AvatarVariantModel{avatarVariant(accountId, "original", accountId), avatarVariant(accountId, "64", accountId)})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		other := avatarVariant("account-c", "original", "account-c")
+		other.Metadata.OrgId = "org-b"
+		err := avatars.Replace(ctx, "account-c", []model.AvatarVariantModel{other})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = avatars.DeleteByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, accountId := range []string{"account-a", "account-b"} {
+			for _, variant := range []string{"original", "64"} {
+				_, content := readAvatar(t, avatars, accountId, variant)
+				if content != "" {
+					t.Fatalf("got %q for %s %s, want no avatar", content, accountId, variant)
+				}
+			}
+		}
+		_, content := readAvatar(t, avatars, "account-c", "original")
+		if content != "account-c" {
+			t.Fatalf("got %q, want account-c", content)
+		}
+	}},
+}
+
+func newWebhook(orgId string, active bool, events ...model.EnumWebhookEvent) *model.WebhookModel {
+	return &model.WebhookModel{
+		Id:        nextId(),
+		OrgId:     orgId,
+		Url:       "https://example.com/hook",
+		Events:    events,
+		Secret:    "0123456789abcdef",
+		Active:    &active,
+		CreatedAt: created(),
+	}
+}
+
+// newDelivery returns a pending delivery due at due, times are whole
+// seconds so every store keeps them exactly.
+func newDelivery(webhook *model.WebhookModel, event model.EnumWebhookEvent, due time.Time) model.WebhookDeliveryModel {
+	return model.WebhookDeliveryModel{
+		Id:            nextId(),
+		WebhookId:     webhook.Id,
+		OrgId:         webhook.OrgId,
+		Event:         event,
+		Payload:       `{"type":"` + string(event) + `"}`,
+		Status:        model.DELIVERY_PENDING,
+		Attempts:      []model.WebhookAttemptModel{},
+		NextAttemptAt: due,
+		CreatedAt:     due,
+	}
+}
+
+func mustCreateWebhook(t *testing.T, webhooks repository.WebhookRepository, webhook *model.WebhookModel) *model.WebhookModel {
+	t.Helper()
+	err := webhooks.Create(context.Background(), webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func mustAddDeliveries(t *testing.T, webhooks repository.WebhookRepository, deliveries ...model.WebhookDeliveryModel) {
+	t.Helper()
+	err := webhooks.AddDeliveries(context.Background(), deliveries)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func webhookIds(webhooks []model.WebhookModel) []string {
+	ids := make([]string, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		ids = append(ids, webhook.Id)
+	}
+	return ids
+}
+
+func deliveryIds(deliveries []model.WebhookDeliveryModel) []string {
+	ids := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.Id)
+	}
+	return ids
+}
+
+// findDelivery returns the delivery with id, whatever its status.
+func findDelivery(t *testing.T, webhooks repository.WebhookRepository, id string) model.WebhookDeliveryModel {
+	t.Helper()
+	deliveries, _, err := webhooks.ListDeliveries(context.Background(), repository.AnyScope(), repository.DeliveryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range deliveries {
+		if delivery.Id == id {
+			return delivery
+		}
+	}
+	t.Fatalf("delivery %s not found", id)
+	return model.WebhookDeliveryModel{}
+}
+
+var webhookCases = []struct {
+	name string
+	run  func(t *testing.T, webhooks repository.WebhookRepository)
+}{
+	{"create, find and list", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		first := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		second := mustCreateWebhook(t, webhooks, newWebhook("org-a", false, model.WEBHOOK_ROLE_CREATED, model.WEBHOOK_ROLE_DELETED))
+		global := mustCreateWebhook(t, webhooks, newWebhook("", true, model.WEBHOOK_ACCOUNT_CREATED))
+
+		err := webhooks.Create(ctx, first)
+		expectError(t, err, repository.ErrDuplicate)
+
+		found, err := webhooks.FindById(ctx, repository.OrganizationScope("org-a"), second.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Url != second.Url || found.Secret != second.Secret || *found.Active || len(found.Events) != 2 || found.Events[1] != model.WEBHOOK_ROLE_DELETED {
+			t.Fatalf("got %+v, want %+v", *found, *second)
+		}
+		_, err = webhooks.FindById(ctx, repository.OrganizationScope("org-b"), second.Id)
+		expectError(t, err, repository.ErrNotFound)
+
+		list, err := webhooks.List(ctx, repository.OrganizationScope("org-a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, webhookIds(list), first.Id, second.Id)
+		for _, webhook := range list {
+			if webhook.Secret != "" {
+				t.Fatalf("list returned the secret of %s", webhook.Id)
+			}
+		}
+		list, err = webhooks.List(ctx, repository.AnyScope())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, webhookIds(list), first.Id, second.Id, global.Id)
+	}},
+	{"update and delete are scoped", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		webhook := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+
+		inactive := false
+		changed := *webhook
+		changed.Url = "https://example.com/changed"
+		changed.Events = []model.EnumWebhookEvent{model.WEBHOOK_SESSION_ENDED}
+		changed.Secret = "fedcba9876543210"
+		changed.Active = &inactive
+		changed.UpdatedAt = 42
+		err := webhooks.Update(ctx, repository.OrganizationScope("org-b"), changed)
+		expectError(t, err, repository.ErrNotFound)
+		err = webhooks.Update(ctx, repository.OrganizationScope("org-a"), changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := webhooks.FindById(ctx, repository.AnyScope(), webhook.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Url != changed.Url || found.Secret != changed.Secret || *found.Active || found.UpdatedAt != 42 ||
+			len(found.Events) != 1 || found.Events[0] != model.WEBHOOK_SESSION_ENDED || found.CreatedAt != webhook.CreatedAt {
+			t.Fatalf("got %+v, want %+v", *found, changed)
+		}
+
+		err = webhooks.Delete(ctx, repository.OrganizationScope("org-b"), webhook.Id)
+		expectError(t, err, repository.ErrNotFound)
+		err = webhooks.Delete(ctx, repository.OrganizationScope("org-a"), webhook.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = webhooks.FindById(ctx, repository.AnyScope(), webhook.Id)
+		expectError(t, err, repository.ErrNotFound)
+	}},
+	{"delete by organization", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		now := time.Now().UTC().Truncate(time.Second)
+		own := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		global := mustCreateWebhook(t, webhooks, newWebhook("", true, model.WEBHOOK_ACCOUNT_CREATED))
+		other := mustCreateWebhook(t, webhooks, newWebhook("org-b", true, model.WEBHOOK_ACCOUNT_CREATED))
+		forOrganization := newDelivery(global, model.WEBHOOK_ACCOUNT_CREATED, now)
+		forOrganization.OrgId = "org-a"
+		foreign := newDelivery(other, model.WEBHOOK_ACCOUNT_CREATED, now)
+		mustAddDeliveries(t, webhooks, newDelivery(own, model.WEBHOOK_ACCOUNT_CREATED, now), forOrganization, foreign)
+
+		err := webhooks.DeleteByOrganization(ctx, "org-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := webhooks.List(ctx, repository.AnyScope())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, webhookIds(list), global.Id, other.Id)
+		deliveries, _, err := webhooks.ListDeliveries(ctx, repository.AnyScope(), repository.DeliveryQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, deliveryIds(deliveries), foreign.Id)
+	}},
+	{"subscribed", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		own := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED, model.WEBHOOK_ACCOUNT_DELETED))
+		global := mustCreateWebhook(t, webhooks, newWebhook("", true, model.WEBHOOK_ACCOUNT_CREATED))
+		mustCreateWebhook(t, webhooks, newWebhook("org-a", false, model.WEBHOOK_ACCOUNT_CREATED))
+		mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ROLE_CREATED))
+		mustCreateWebhook(t, webhooks, newWebhook("org-b", true, model.WEBHOOK_ACCOUNT_CREATED))
+
+		subscribed, err := webhooks.Subscribed(ctx, "org-a", model.WEBHOOK_ACCOUNT_CREATED)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, webhookIds(subscribed), own.Id, global.Id)
+		if subscribed[0].Secret == "" {
+			t.Fatal("subscribed webhooks need their secret to sign deliveries")
+		}
+		subscribed, err = webhooks.Subscribed(ctx, "org-c", model.WEBHOOK_ACCOUNT_DELETED)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectIds(t, webhookIds(subscribed))
+	}},
+	{"claim the most overdue delivery", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		webhook := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		now := time.Now().UTC().Truncate(time.Second)
+		late := newDelivery(webhook, model.WEBHOOK_ACCOUNT_CREATED, now.Add(-time.Minute))
+		later := newDelivery(webhook, model.WEBHOOK_ACCOUNT_CREATED, now.Add(-2*time.Minute))
+		future := newDelivery(webhook, model.WEBHOOK_ACCOUNT_CREATED, now.Add(time.Minute))
+		mustAddDeliveries(t, webhooks, late, later, future)
+
+		expected := []string{later.Id, late.Id}
+		for _, want := range expected {
+			claimed, err := webhooks.Claim(ctx, now, now.Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claimed == nil || claimed.Id != want {
+				t.Fatalf("claimed %+v, want %s", claimed, want)
+			}
+		}
+		claimed, err := webhooks.Claim(ctx, now, now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != nil {
+			t.Fatalf("claimed %s while every due delivery is locked", claimed.Id)
+		}
+		claimed, err = webhooks.Claim(ctx, now.Add(2*time.Minute), now.Add(3*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed == nil || claimed.Id != later.Id {
+			t.Fatalf("claimed %+v, want the expired lock of %s released", claimed, later.Id)
+		}
+	}},
+	{"finish records attempts", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		webhook := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		now := time.Now().UTC().Truncate(time.Second)
+		delivery := newDelivery(webhook, model.WEBHOOK_ACCOUNT_CREATED, now)
+		mustAddDeliveries(t, webhooks, delivery)
+
+		_, err := webhooks.Claim(ctx, now, now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		failed := model.WebhookAttemptModel{At: now, StatusCode: 500, DurationMs: 12}
+		err = webhooks.Finish(ctx, delivery.Id, failed, model.DELIVERY_PENDING, now.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := findDelivery(t, webhooks, delivery.Id)
+		if found.Status != model.DELIVERY_PENDING || found.AttemptCount != 1 || len(found.Attempts) != 1 ||
+			found.Attempts[0].StatusCode != 500 || !found.NextAttemptAt.Equal(now.Add(time.Hour)) || found.DeliveredAt != nil {
+			t.Fatalf("got %+v after a failed attempt", found)
+		}
+		claimed, err := webhooks.Claim(ctx, now.Add(time.Minute), now.Add(2*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != nil {
+			t.Fatalf("claimed %s before it is due again", claimed.Id)
+		}
+
+		succeeded := model.WebhookAttemptModel{At: now.Add(time.Hour), StatusCode: 204, DurationMs: 3}
+		err = webhooks.Finish(ctx, delivery.Id, succeeded, model.DELIVERY_SUCCEEDED, now.Add(2*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = findDelivery(t, webhooks, delivery.Id)
+		if found.Status != model.DELIVERY_SUCCEEDED || found.AttemptCount != 2 || len(found.Attempts) != 2 ||
+			found.Attempts[1].StatusCode != 204 || found.DeliveredAt == nil || !found.DeliveredAt.Equal(succeeded.At) {
+			t.Fatalf("got %+v after a successful attempt", found)
+		}
+	}},
+	{"redeliver", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		webhook := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		now := time.Now().UTC().Truncate(time.Second)
+		delivery := newDelivery(webhook, model.WEBHOOK_ACCOUNT_CREATED, now)
+		mustAddDeliveries(t, webhooks, delivery)
+
+		err := webhooks.Redeliver(ctx, repository.OrganizationScope("org-a"), delivery.Id, now)
+		expectError(t, err, repository.ErrNotFound)
+		err = webhooks.Finish(ctx, delivery.Id, model.WebhookAttemptModel{At: now, Error: "refused"}, model.DELIVERY_DEAD, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = webhooks.Redeliver(ctx, repository.OrganizationScope("org-b"), delivery.Id, now)
+		expectError(t, err, repository.ErrNotFound)
+		err = webhooks.Redeliver(ctx, repository.OrganizationScope("org-a"), delivery.Id, now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := findDelivery(t, webhooks, delivery.Id)
+		if found.Status != model.DELIVERY_PENDING || found.AttemptCount != 0 || len(found.Attempts) != 1 || !found.NextAttemptAt.Equal(now.Add(time.Minute)) {
+			t.Fatalf("got %+v after redelivery", found)
+		}
+	}},
+	{"list deliveries", func(t *testing.T, webhooks repository.WebhookRepository) {
+		ctx := context.Background()
+		first := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED, model.WEBHOOK_ROLE_CREATED))
+		second := mustCreateWebhook(t, webhooks, newWebhook("org-a", true, model.WEBHOOK_ACCOUNT_CREATED))
+		other := mustCreateWebhook(t, webhooks, newWebhook("org-b", true, model.WEBHOOK_ACCOUNT_CREATED))
+		now := time.Now().UTC().Truncate(time.Second)
+		oldest := newDelivery(first, model.WEBHOOK_ACCOUNT_CREATED, now.Add(-3*time.Minute))
+		role := newDelivery(first, model.WEBHOOK_ROLE_CREATED, now.Add(-2*time.Minute))
+		newest := newDelivery(second, model.WEBHOOK_ACCOUNT_CREATED, now.Add(-time.Minute))
+		foreign := newDelivery(other, model.WEBHOOK_ACCOUNT_CREATED, now)
+		mustAddDeliveries(t, webhooks, oldest, role, newest, foreign)
+		err := webhooks.Finish(ctx, oldest.Id, model.WebhookAttemptModel{At: now, Error: "refused"}, model.DELIVERY_DEAD, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			scope repository.Scope
+			query repository.DeliveryQuery
+			want  []string
+			total int64
+		}{
+			{repository.OrganizationScope("org-a"), repository.DeliveryQuery{}, []string{newest.Id, role.Id, oldest.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.DeliveryQuery{Skip: 1, Size: 1}, []string{role.Id}, 3},
+			{repository.OrganizationScope("org-a"), repository.DeliveryQuery{WebhookId: first.Id}, []string{role.Id, oldest.Id}, 2},
+			{repository.OrganizationScope("org-a"), repository.DeliveryQuery{Status: model.DELIVERY_DEAD}, []string{oldest.Id}, 1},
+			{repository.OrganizationScope("org-a"), repository.DeliveryQuery{Event: model.WEBHOOK_ROLE_CREATED}, []string{role.Id}, 1},
+			{repository.AnyScope(), repository.DeliveryQuery{Size: 2}, []string{foreign.Id, newest.Id}, 4},
+		}
+		for _, test := range tests {
+			deliveries, total, err := webhooks.ListDeliveries(ctx, test.scope, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectIds(t, deliveryIds(deliveries), test.want...)
+			if total != test.total {
+				t.Fatalf("got total %d, want %d", total, test.total)
+			}
+		}
+	}},
+}
+
+func TestAccountRepository(t *testing.T) {
+	for _, backend := range storageBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range accountCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					accounts, roles := backend.open(t)
+					test.run(t, accounts, roles)
+				})
+			}
+		})
+	}
+}
+
+func TestRoleRepository(t *testing.T) {
+	for _, backend := range storageBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range roleCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					_, roles := backend.open(t)
+					test.run(t, roles)
+				})
+			}
+		})
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	for _, backend := range sessionBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range sessionCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func TestOutboxRepository(t *testing.T) {
+	backends := outboxBackends()
+	if len(backends) == 0 {
+		t.Skip("set MONGO_URI or POSTGRES_URL to run the outbox contract")
+	}
+	for _, backend := range backends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range outboxCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	for _, backend := range auditBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range auditCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func TestOrganizationRepository(t *testing.T) {
+	for _, backend := range organizationBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range organizationCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					organizations, _ := backend.open(t)
+					test.run(t, organizations)
+				})
+			}
+		})
+	}
+}
+
+func TestAttributeSchemaRepository(t *testing.T) {
+	for _, backend := range organizationBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range attributeSchemaCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					_, schemas := backend.open(t)
+					test.run(t, schemas)
+				})
+			}
+		})
+	}
+}
+
+func TestAvatarStore(t *testing.T) {
+	for _, backend := range avatarBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range avatarCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func TestWebhookRepository(t *testing.T) {
+	for _, backend := range webhookBackends() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range webhookCases {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"ima-svc-management/model"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+func sortedAccounts(accounts map[string]model.AccountModel, page Page) []model.AccountModel {
+	list := make([]model.AccountModel, 0, len(accounts))
+	for _, account := range accounts {
+		list = append(list, account)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(list[i], list[j], list[i].CreatedAt, list[j].CreatedAt, list[i].Id, list[j].Id, page)
+	})
+	return list
+}
+
+func sortedRoles(roles map[string]model.RoleModel, page Page) []model.RoleModel {
+	list := make([]model.RoleModel, 0, len(roles))
+	for _, role := range roles {
+		list = append(list, role)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(list[i], list[j], list[i].CreatedAt.UnixNano(), list[j].CreatedAt.UnixNano(), list[i].Id, list[j].Id, page)
+	})
+	return list
+}
+
+func sortedOrganizations(organizations map[string]model.OrganizationModel, page Page) []model.OrganizationModel {
+	list := make([]model.OrganizationModel, 0, len(organizations))
+	for _, organization := range organizations {
+		list = append(list, organization)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(list[i], list[j], list[i].CreatedAt, list[j].CreatedAt, list[i].Id, list[j].Id, page)
+	})
+	return list
+}
+
+func sortedAttributeSchemas(schemas map[string]model.AttributeSchemaModel, page Page) []model.AttributeSchemaModel {
+	list := make([]model.AttributeSchemaModel, 0, len(schemas))
+	for _, schema := range schemas {
+		list = append(list, schema)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(list[i], list[j], list[i].CreatedAt, list[j].CreatedAt, list[i].Id, list[j].Id, page)
+	})
+	return list
+}
+
+// less orders two documents by the page's OrderBy field like Mongo would,
+// documents without ordering or with equal values keep the order they were
+// created in.
+func less(left interface{}, right interface{}, leftCreated int64, rightCreated int64, leftId string, rightId string, page Page) bool {
+	if page.Order != "" && page.OrderBy != "" {
+		compared := compare(fieldValue(left, page.OrderBy), fieldValue(right, page.OrderBy))
+		if compared != 0 {
+			if page.Order == "asc" {
+				return compared < 0
+			}
+			return compared > 0
+		}
+	}
+	if leftCreated != rightCreated {
+		return leftCreated < rightCreated
+	}
+	return leftId < rightId
+}
+
+// fieldValue returns the field of document stored under the bson name field,
+// nil when there is none.
+func fieldValue(document interface{}, field string) interface{} {
+	value := reflect.ValueOf(document)
+	for index := 0; index < value.NumField(); index++ {
+		name := strings.Split(value.Type().Field(index).Tag.Get("bson"), ",")[0]
+		if name != field {
+			continue
+		}
+		fieldValue := value.Field(index)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				return nil
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		return fieldValue.Interface()
+	}
+	return nil
+}
+
+// compare orders missing values first like Mongo does and compares values of
+// the same kind, anything else counts as equal.
+func compare(left interface{}, right interface{}) int {
+	if left == nil || right == nil {
+		switch {
+		case left == nil && right == nil:
+			return 0
+		case left == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch left := left.(type) {
+	case time.Time:
+		if right, ok := right.(time.Time); ok {
+			return sign(left.Sub(right).Nanoseconds())
+		}
+	case model.EnumRole:
+		if right, ok := right.(model.EnumRole); ok {
+			return strings.Compare(string(left), string(right))
+		}
+	case model.EnumAttributeType:
+		if right, ok := right.(model.EnumAttributeType); ok {
+			return strings.Compare(string(left), string(right))
+		}
+	case string:
+		if right, ok := right.(string); ok {
+			return strings.Compare(left, right)
+		}
+	case bool:
+		if right, ok := right.(bool); ok && left != right {
+			if left {
+				return 1
+			}
+			return -1
+		}
+	case int64:
+		if right, ok := right.(int64); ok {
+			return sign(left - right)
+		}
+	}
+	return 0
+}
+
+func sign(value int64) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
+
+// pageBounds returns the slice of length documents selected by page.
+func pageBounds(length int, page Page) (int, int) {
+	start := page.Skip
+	if start < 0 {
+		start = 0
+	}
+	if start > length {
+		start = length
+	}
+	end := length
+	if page.Size > 0 && start+page.Size < end {
+		end = start + page.Size
+	}
+	return start, end
+}
+
+func pageOf(accounts []model.AccountModel, page Page) []model.AccountModel {
+	start, end := pageBounds(len(accounts), page)
+	return accounts[start:end]
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"reflect"
+	"sync"
+)
+
+// MemoryAccounts keeps accounts in a map. It behaves like MongoAccounts,
+// including the unique email per organization, and suits tests and single
+// node development setups.
+type MemoryAccounts struct {
+	mutex    sync.RWMutex
+	accounts map[string]model.AccountModel
+}
+
+func InitMemoryAccounts() *MemoryAccounts {
+	return &MemoryAccounts{
+		accounts: map[string]model.AccountModel{},
+	}
+}
+
+func (accounts *MemoryAccounts) Create(ctx context.Context, account *model.AccountModel) error {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+
+	if _, ok := accounts.accounts[account.Id]; ok {
+		return ErrDuplicate
+	}
+	if accounts.emailTaken(account.OrgId, account.EmailNormalized, "") {
+		return ErrDuplicate
+	}
+	accounts.accounts[account.Id] = copyAccount(*account)
+	return nil
+}
+
+func (accounts *MemoryAccounts) FindById(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	account, ok := accounts.accounts[id]
+	if !ok || !scope.matches(account.OrgId) {
+		return nil, ErrNotFound
+	}
+	account = copyAccount(account)
+	return &account, nil
+}
+
+func (accounts *MemoryAccounts) FindByEmail(ctx context.Context, scope Scope, emailNormalized string) (*model.AccountModel, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	for _, account := range sortedAccounts(accounts.accounts, Page{}) {
+		if account.EmailNormalized == emailNormalized && scope.matches(account.OrgId) {
+			account = copyAccount(account)
+			return &account, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (accounts *MemoryAccounts) CountByEmail(ctx context.Context, emailNormalized string) (int64, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	count := int64(0)
+	for _, account := range accounts.accounts {
+		if account.EmailNormalized == emailNormalized {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (accounts *MemoryAccounts) List(ctx context.Context, scope Scope, query AccountQuery) ([]model.AccountModel, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	matching := make([]model.AccountModel, 0)
+	for _, account := range sortedAccounts(accounts.accounts, query.Page) {
+		if !scope.matches(account.OrgId) || !hasAttributes(account.Attributes, query.Attributes) {
+			continue
+		}
+		matching = append(matching, copyAccount(account))
+	}
+	return pageOf(matching, query.Page), nil
+}
+
+func (accounts *MemoryAccounts) Update(ctx context.Context, scope Scope, id string, version *int64, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+
+	account, ok := accounts.accounts[id]
+	if !ok || !scope.matches(account.OrgId) || (version != nil && account.Version != *version) {
+		return nil, nil, ErrNotFound
+	}
+	return accounts.update(account, change)
+}
+
+func (accounts *MemoryAccounts) UpdateByEmail(ctx context.Context, scope Scope, emailNormalized string, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+
+	for _, account := range sortedAccounts(accounts.accounts, Page{}) {
+		if account.EmailNormalized == emailNormalized && scope.matches(account.OrgId) {
+			return accounts.update(account, change)
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
+func (accounts *MemoryAccounts) Delete(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+
+	account, ok := accounts.accounts[id]
+	if !ok || !scope.matches(account.OrgId) {
+		return nil, ErrNotFound
+	}
+	delete(accounts.accounts, id)
+	return &account, nil
+}
+
+func (accounts *MemoryAccounts) CountByOrganization(ctx context.Context, orgId string) (int64, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	count := int64(0)
+	for _, account := range accounts.accounts {
+		if account.OrgId == orgId {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (accounts *MemoryAccounts) CountByRole(ctx context.Context) (map[string]int64, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	counts := map[string]int64{}
+	for _, account := range accounts.accounts {
+		counts[account.Role]++
+	}
+	return counts, nil
+}
+
+func (accounts *MemoryAccounts) CountWithRole(ctx context.Context, orgId string, role string) (int64, error) {
+	accounts.mutex.RLock()
+	defer accounts.mutex.RUnlock()
+
+	count := int64(0)
+	for _, account := range accounts.accounts {
+		if account.OrgId == orgId && account.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (accounts *MemoryAccounts) UnsetAttribute(ctx context.Context, orgId string, name string) ([]AccountUpdate, error) {
+	accounts.mutex.Lock()
+	defer accounts.mutex.Unlock()
+
+	updates := make([]AccountUpdate, 0)
+	for id, account := range accounts.accounts {
+		if account.OrgId != orgId {
+			continue
+		}
+		if _, ok := account.Attributes[name]; !ok {
+			continue
+		}
+		previous := copyAccount(account)
+		account.Attributes = copyAttributes(account.Attributes)
+		delete(account.Attributes, name)
+		account.Version++
+		accounts.accounts[id] = account
+		updates = append(updates, AccountUpdate{Previous: previous, Updated: copyAccount(account)})
+	}
+	return updates, nil
+}
+
+// update stores account changed by change, the caller holds the lock.
+func (accounts *MemoryAccounts) update(account model.AccountModel, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	previous := copyAccount(account)
+	updated := copyAccount(account)
+	change.Apply(&updated)
+	updated = copyAccount(updated)
+	if updated.EmailNormalized != previous.EmailNormalized && accounts.emailTaken(updated.OrgId, updated.EmailNormalized, updated.Id) {
+		return nil, nil, ErrDuplicate
+	}
+	accounts.accounts[updated.Id] = updated
+	updated = copyAccount(updated)
+	return &previous, &updated, nil
+}
+
+// emailTaken reports whether another account than except holds the email in
+// orgId, the caller holds the lock.
+func (accounts *MemoryAccounts) emailTaken(orgId string, emailNormalized string, except string) bool {
+	if emailNormalized == "" {
+		return false
+	}
+	for id, account := range accounts.accounts {
+		if id != except && account.OrgId == orgId && account.EmailNormalized == emailNormalized {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttributes(attributes map[string]interface{}, wanted map[string]interface{}) bool {
+	for name, value := range wanted {
+		held, ok := attributes[name]
+		if !ok || !reflect.DeepEqual(held, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyAccount keeps callers from changing a stored account through its
+// attributes map.
+func copyAccount(account model.AccountModel) model.AccountModel {
+	account.Attributes = copyAttributes(account.Attributes)
+	return account
+}
+
+func copyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		copied[name] = value
+	}
+	return copied
+}
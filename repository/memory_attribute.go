@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"sync"
+)
+
+// MemoryAttributeSchemas keeps attribute schemas in a map, with the unique
+// name per organization of MongoAttributeSchemas.
+type MemoryAttributeSchemas struct {
+	mutex   sync.RWMutex
+	schemas map[string]model.AttributeSchemaModel
+}
+
+func InitMemoryAttributeSchemas() *MemoryAttributeSchemas {
+	return &MemoryAttributeSchemas{
+		schemas: map[string]model.AttributeSchemaModel{},
+	}
+}
+
+func (schemas *MemoryAttributeSchemas) Create(ctx context.Context, schema *model.AttributeSchemaModel) error {
+	schemas.mutex.Lock()
+	defer schemas.mutex.Unlock()
+
+	if _, ok := schemas.schemas[schema.Id]; ok {
+		return ErrDuplicate
+	}
+	for _, existing := range schemas.schemas {
+		if existing.OrgId == schema.OrgId && existing.Name == schema.Name {
+			return ErrDuplicate
+		}
+	}
+	schemas.schemas[schema.Id] = *schema
+	return nil
+}
+
+func (schemas *MemoryAttributeSchemas) FindById(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	schemas.mutex.RLock()
+	defer schemas.mutex.RUnlock()
+
+	schema, ok := schemas.schemas[id]
+	if !ok || !scope.matches(schema.OrgId) {
+		return nil, ErrNotFound
+	}
+	return &schema, nil
+}
+
+func (schemas *MemoryAttributeSchemas) FindByOrganization(ctx context.Context, orgId string) ([]model.AttributeSchemaModel, error) {
+	schemas.mutex.RLock()
+	defer schemas.mutex.RUnlock()
+
+	list := make([]model.AttributeSchemaModel, 0)
+	for _, schema := range sortedAttributeSchemas(schemas.schemas, Page{}) {
+		if schema.OrgId == orgId {
+			list = append(list, schema)
+		}
+	}
+	return list, nil
+}
+
+func (schemas *MemoryAttributeSchemas) List(ctx context.Context, scope Scope, page Page) ([]model.AttributeSchemaModel, error) {
+	schemas.mutex.RLock()
+	defer schemas.mutex.RUnlock()
+
+	matching := make([]model.AttributeSchemaModel, 0)
+	for _, schema := range sortedAttributeSchemas(schemas.schemas, page) {
+		if scope.matches(schema.OrgId) {
+			matching = append(matching, schema)
+		}
+	}
+	start, end := pageBounds(len(matching), page)
+	return matching[start:end], nil
+}
+
+func (schemas *MemoryAttributeSchemas) Update(ctx context.Context, scope Scope, id string, change AttributeSchemaChange) (*model.AttributeSchemaModel, *model.AttributeSchemaModel, error) {
+	schemas.mutex.Lock()
+	defer schemas.mutex.Unlock()
+
+	schema, ok := schemas.schemas[id]
+	if !ok || !scope.matches(schema.OrgId) {
+		return nil, nil, ErrNotFound
+	}
+	previous := schema
+	change.Apply(&schema)
+	schemas.schemas[id] = schema
+	return &previous, &schema, nil
+}
+
+func (schemas *MemoryAttributeSchemas) Delete(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	schemas.mutex.Lock()
+	defer schemas.mutex.Unlock()
+
+	schema, ok := schemas.schemas[id]
+	if !ok || !scope.matches(schema.OrgId) {
+		return nil, ErrNotFound
+	}
+	delete(schemas.schemas, id)
+	return &schema, nil
+}
+
+func (schemas *MemoryAttributeSchemas) DeleteByOrganization(ctx context.Context, orgId string) error {
+	schemas.mutex.Lock()
+	defer schemas.mutex.Unlock()
+
+	for id, schema := range schemas.schemas {
+		if schema.OrgId == orgId {
+			delete(schemas.schemas, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"ima-svc-management/model"
+	"sort"
+	"sync"
+)
+
+type memoryAuditRecord struct {
+	event   model.AuditEventModel
+	content []byte
+}
+
+// MemoryAudit keeps the audit chain in a slice, events are hashed over their
+// JSON encoding like in PostgresAudit.
+type MemoryAudit struct {
+	mutex       sync.RWMutex
+	records     []memoryAuditRecord
+	checkpoints []model.AuditCheckpointModel
+}
+
+func InitMemoryAudit() *MemoryAudit {
+	return &MemoryAudit{}
+}
+
+func (audit *MemoryAudit) Head(ctx context.Context) (int64, string, error) {
+	audit.mutex.RLock()
+	defer audit.mutex.RUnlock()
+
+	if len(audit.records) == 0 {
+		return 0, "", nil
+	}
+	head := audit.records[len(audit.records)-1].event
+	return head.Sequence, head.Hash, nil
+}
+
+func (audit *MemoryAudit) Content(event model.AuditEventModel) ([]byte, error) {
+	event.Hash = ""
+	return json.Marshal(event)
+}
+
+func (audit *MemoryAudit) Append(ctx context.Context, event model.AuditEventModel, content []byte) error {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+
+	for _, record := range audit.records {
+		if record.event.Sequence == event.Sequence || record.event.Id == event.Id {
+			return ErrDuplicate
+		}
+	}
+	audit.records = append(audit.records, memoryAuditRecord{event: event, content: append([]byte(nil), content...)})
+	sort.SliceStable(audit.records, func(i, j int) bool {
+		return audit.records[i].event.Sequence < audit.records[j].event.Sequence
+	})
+	return nil
+}
+
+func (audit *MemoryAudit) AddCheckpoint(ctx context.Context, checkpoint model.AuditCheckpointModel) error {
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+
+	for _, existing := range audit.checkpoints {
+		if existing.Sequence == checkpoint.Sequence {
+			return ErrDuplicate
+		}
+	}
+	audit.checkpoints = append(audit.checkpoints, checkpoint)
+	sort.SliceStable(audit.checkpoints, func(i, j int) bool {
+		return audit.checkpoints[i].Sequence < audit.checkpoints[j].Sequence
+	})
+	return nil
+}
+
+func (audit *MemoryAudit) Checkpoints(ctx context.Context) ([]model.AuditCheckpointModel, error) {
+	audit.mutex.RLock()
+	defer audit.mutex.RUnlock()
+
+	return append([]model.AuditCheckpointModel{}, audit.checkpoints...), nil
+}
+
+func (audit *MemoryAudit) CountUnchained(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (audit *MemoryAudit) Walk(ctx context.Context, fn func(event model.AuditEventModel, content []byte) error) error {
+	audit.mutex.RLock()
+	records := append([]memoryAuditRecord{}, audit.records...)
+	audit.mutex.RUnlock()
+
+	for _, record := range records {
+		err := fn(record.event, append([]byte(nil), record.content...))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (audit *MemoryAudit) List(ctx context.Context, scope Scope, query AuditQuery) ([]model.AuditEventModel, int64, error) {
+	audit.mutex.RLock()
+	defer audit.mutex.RUnlock()
+
+	matching := make([]model.AuditEventModel, 0)
+	for _, record := range audit.records {
+		event := record.event
+		switch {
+		case !scope.matches(event.OrgId):
+		case query.Action != "" && string(event.Action) != query.Action:
+		case query.ActorEmail != "" && event.Actor.Email != query.ActorEmail:
+		case query.TargetType != "" && event.Target.Type != query.TargetType:
+		case query.TargetId != "" && event.Target.Id != query.TargetId:
+		case !query.From.IsZero() && event.CreatedAt.Before(query.From):
+		case !query.To.IsZero() && !event.CreatedAt.Before(query.To):
+		default:
+			matching = append(matching, event)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		left, right := matching[i], matching[j]
+		if query.Descending {
+			left, right = right, left
+		}
+		if !left.CreatedAt.Equal(right.CreatedAt) {
+			return left.CreatedAt.Before(right.CreatedAt)
+		}
+		return left.Id < right.Id
+	})
+	start, end := pageBounds(len(matching), Page{Skip: query.Skip, Size: query.Size})
+	return matching[start:end], int64(len(matching)), nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"ima-svc-management/model"
+	"io"
+	"sync"
+	"time"
+)
+
+type memoryAvatarFile struct {
+	file    model.AvatarFileModel
+	content []byte
+}
+
+// MemoryAvatars keeps the variants of every avatar in a map.
+type MemoryAvatars struct {
+	mutex   sync.RWMutex
+	avatars map[string][]memoryAvatarFile
+}
+
+func InitMemoryAvatars() *MemoryAvatars {
+	return &MemoryAvatars{
+		avatars: map[string][]memoryAvatarFile{},
+	}
+}
+
+func (avatars *MemoryAvatars) Replace(ctx context.Context, accountId string, variants []model.AvatarVariantModel) error {
+	avatars.mutex.Lock()
+	defer avatars.mutex.Unlock()
+
+	files := make([]memoryAvatarFile, 0, len(variants))
+	uploadDate := time.Now().UTC()
+	for _, variant := range variants {
+		files = append(files, memoryAvatarFile{
+			file: model.AvatarFileModel{
+				Id:         accountId + "-" + variant.Metadata.Variant,
+				Length:     int64(len(variant.Content)),
+				UploadDate: uploadDate,
+				Metadata:   variant.Metadata,
+			},
+			content: append([]byte(nil), variant.Content...),
+		})
+	}
+	avatars.avatars[accountId] = files
+	return nil
+}
+
+func (avatars *MemoryAvatars) Open(ctx context.Context, accountId string, variant string) (*model.AvatarFileModel, io.ReadCloser, error) {
+	avatars.mutex.RLock()
+	defer avatars.mutex.RUnlock()
+
+	for _, file := range avatars.avatars[accountId] {
+		if file.file.Metadata.Variant == variant {
+			found := file.file
+			return &found, io.NopCloser(bytes.NewReader(file.content)), nil
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
+func (avatars *MemoryAvatars) Delete(ctx context.Context, accountId string) error {
+	avatars.mutex.Lock()
+	defer avatars.mutex.Unlock()
+
+	delete(avatars.avatars, accountId)
+	return nil
+}
+
+func (avatars *MemoryAvatars) DeleteByOrganization(ctx context.Context, orgId string) error {
+	avatars.mutex.Lock()
+	defer avatars.mutex.Unlock()
+
+	for accountId, files := range avatars.avatars {
+		if len(files) > 0 && files[0].file.Metadata.OrgId == orgId {
+			delete(avatars.avatars, accountId)
+		}
+	}
+	return nil
+}
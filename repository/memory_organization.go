@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"sync"
+)
+
+// MemoryOrganizations keeps organizations in a map, with the unique name of
+// MongoOrganizations.
+type MemoryOrganizations struct {
+	mutex         sync.RWMutex
+	organizations map[string]model.OrganizationModel
+}
+
+func InitMemoryOrganizations() *MemoryOrganizations {
+	return &MemoryOrganizations{
+		organizations: map[string]model.OrganizationModel{},
+	}
+}
+
+func (organizations *MemoryOrganizations) Create(ctx context.Context, organization *model.OrganizationModel) error {
+	organizations.mutex.Lock()
+	defer organizations.mutex.Unlock()
+
+	if _, ok := organizations.organizations[organization.Id]; ok {
+		return ErrDuplicate
+	}
+	if organizations.nameTaken(organization.Name, "") {
+		return ErrDuplicate
+	}
+	organizations.organizations[organization.Id] = *organization
+	return nil
+}
+
+func (organizations *MemoryOrganizations) FindById(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	organizations.mutex.RLock()
+	defer organizations.mutex.RUnlock()
+
+	organization, ok := organizations.organizations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &organization, nil
+}
+
+func (organizations *MemoryOrganizations) FindByName(ctx context.Context, name string) (*model.OrganizationModel, error) {
+	organizations.mutex.RLock()
+	defer organizations.mutex.RUnlock()
+
+	for _, organization := range organizations.organizations {
+		if organization.Name == name {
+			return &organization, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (organizations *MemoryOrganizations) List(ctx context.Context, page Page) ([]model.OrganizationModel, error) {
+	organizations.mutex.RLock()
+	defer organizations.mutex.RUnlock()
+
+	list := sortedOrganizations(organizations.organizations, page)
+	start, end := pageBounds(len(list), page)
+	return list[start:end], nil
+}
+
+func (organizations *MemoryOrganizations) Update(ctx context.Context, id string, change OrganizationChange) (*model.OrganizationModel, *model.OrganizationModel, error) {
+	organizations.mutex.Lock()
+	defer organizations.mutex.Unlock()
+
+	organization, ok := organizations.organizations[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	previous := organization
+	change.Apply(&organization)
+	if organization.Name != previous.Name && organizations.nameTaken(organization.Name, id) {
+		return nil, nil, ErrDuplicate
+	}
+	organizations.organizations[id] = organization
+	return &previous, &organization, nil
+}
+
+func (organizations *MemoryOrganizations) Delete(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	organizations.mutex.Lock()
+	defer organizations.mutex.Unlock()
+
+	organization, ok := organizations.organizations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(organizations.organizations, id)
+	return &organization, nil
+}
+
+// nameTaken reports whether another organization than except holds name,
+// the caller holds the lock.
+func (organizations *MemoryOrganizations) nameTaken(name string, except string) bool {
+	for id, organization := range organizations.organizations {
+		if id != except && organization.Name == name {
+			return true
+		}
+	}
+	return false
+}
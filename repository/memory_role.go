@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"sync"
+)
+
+// MemoryRoles keeps roles in a map, with the unique name per organization of
+// MongoRoles.
+type MemoryRoles struct {
+	mutex sync.RWMutex
+	roles map[string]model.RoleModel
+}
+
+func InitMemoryRoles() *MemoryRoles {
+	return &MemoryRoles{
+		roles: map[string]model.RoleModel{},
+	}
+}
+
+func (roles *MemoryRoles) Create(ctx context.Context, role *model.RoleModel) error {
+	roles.mutex.Lock()
+	defer roles.mutex.Unlock()
+
+	if _, ok := roles.roles[role.Id]; ok {
+		return ErrDuplicate
+	}
+	if roles.nameTaken(role.OrgId, role.Name, "") {
+		return ErrDuplicate
+	}
+	roles.roles[role.Id] = *role
+	return nil
+}
+
+func (roles *MemoryRoles) FindById(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	roles.mutex.RLock()
+	defer roles.mutex.RUnlock()
+
+	role, ok := roles.roles[id]
+	if !ok || !scope.matches(role.OrgId) {
+		return nil, ErrNotFound
+	}
+	return &role, nil
+}
+
+func (roles *MemoryRoles) FindByName(ctx context.Context, scope Scope, name string) (*model.RoleModel, error) {
+	roles.mutex.RLock()
+	defer roles.mutex.RUnlock()
+
+	for _, role := range roles.roles {
+		if role.Name == name && scope.matches(role.OrgId) {
+			return &role, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (roles *MemoryRoles) List(ctx context.Context, scope Scope, page Page) ([]model.RoleModel, error) {
+	roles.mutex.RLock()
+	defer roles.mutex.RUnlock()
+
+	matching := make([]model.RoleModel, 0)
+	for _, role := range sortedRoles(roles.roles, page) {
+		if scope.matches(role.OrgId) {
+			matching = append(matching, role)
+		}
+	}
+	start, end := pageBounds(len(matching), page)
+	return matching[start:end], nil
+}
+
+func (roles *MemoryRoles) Update(ctx context.Context, scope Scope, id string, version *int64, change RoleChange) (*model.RoleModel, *model.RoleModel, error) {
+	roles.mutex.Lock()
+	defer roles.mutex.Unlock()
+
+	role, ok := roles.roles[id]
+	if !ok || !scope.matches(role.OrgId) || (version != nil && role.Version != *version) {
+		return nil, nil, ErrNotFound
+	}
+	previous := role
+	change.Apply(&role)
+	if role.Name != previous.Name && roles.nameTaken(role.OrgId, role.Name, role.Id) {
+		return nil, nil, ErrDuplicate
+	}
+	roles.roles[id] = role
+	return &previous, &role, nil
+}
+
+func (roles *MemoryRoles) Delete(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	roles.mutex.Lock()
+	defer roles.mutex.Unlock()
+
+	role, ok := roles.roles[id]
+	if !ok || !scope.matches(role.OrgId) {
+		return nil, ErrNotFound
+	}
+	delete(roles.roles, id)
+	return &role, nil
+}
+
+func (roles *MemoryRoles) DeleteByOrganization(ctx context.Context, orgId string) ([]model.RoleModel, error) {
+	roles.mutex.Lock()
+	defer roles.mutex.Unlock()
+
+	deleted := make([]model.RoleModel, 0)
+	for _, role := range sortedRoles(roles.roles, Page{}) {
+		if role.OrgId == orgId {
+			deleted = append(deleted, role)
+			delete(roles.roles, role.Id)
+		}
+	}
+	return deleted, nil
+}
+
+// nameTaken reports whether another role than except holds name in orgId,
+// the caller holds the lock.
+func (roles *MemoryRoles) nameTaken(orgId string, name string, except string) bool {
+	for id, role := range roles.roles {
+		if id != except && role.OrgId == orgId && role.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memorySession struct {
+	account string
+	session model.SessionModel
+	expires time.Time
+	refresh bool
+}
+
+// MemorySessions keeps sessions in the process. Tokens expire like Redis
+// keys do, expired ones are dropped whenever a session is created. Sessions
+// are lost on restart and not shared between instances.
+type MemorySessions struct {
+	mutex    sync.Mutex
+	sessions map[string]memorySession
+}
+
+func InitMemorySessions() *MemorySessions {
+	return &MemorySessions{
+		sessions: map[string]memorySession{},
+	}
+}
+
+func (sessions *MemorySessions) Create(ctx context.Context, account string, session model.SessionModel, access string, accessExpires time.Time, refresh string, refreshExpires time.Time) error {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	sessions.prune()
+	sessions.sessions[access] = memorySession{account: account, session: session, expires: accessExpires}
+	sessions.sessions[refresh] = memorySession{account: account, session: session, expires: refreshExpires, refresh: true}
+	return nil
+}
+
+func (sessions *MemorySessions) Fetch(ctx context.Context, uuid string) (*model.SessionModel, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	entry, ok := sessions.live(uuid)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session := entry.session
+	return &session, nil
+}
+
+func (sessions *MemorySessions) Delete(ctx context.Context, uuid string) (int64, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	_, ok := sessions.live(uuid)
+	if !ok {
+		return 0, nil
+	}
+	delete(sessions.sessions, uuid)
+	return 1, nil
+}
+
+func (sessions *MemorySessions) List(ctx context.Context, account string) ([]model.SessionInfoModel, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	sessions.prune()
+	now := time.Now()
+	list := make([]model.SessionInfoModel, 0)
+	for uuid, entry := range sessions.sessions {
+		if account != "" && entry.account != account {
+			continue
+		}
+		list = append(list, model.SessionInfoModel{
+			Uuid:      uuid,
+			Email:     entry.session.Email,
+			OrgId:     entry.session.OrgId,
+			ExpiresIn: entry.expires.Sub(now).Truncate(time.Second),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Uuid < list[j].Uuid
+	})
+	return list, nil
+}
+
+func (sessions *MemorySessions) Revoke(ctx context.Context, account string) (int64, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	sessions.prune()
+	deleted := int64(0)
+	for uuid, entry := range sessions.sessions {
+		if entry.account == account {
+			delete(sessions.sessions, uuid)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (sessions *MemorySessions) CountActive(ctx context.Context) (int64, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	sessions.prune()
+	count := int64(0)
+	for _, entry := range sessions.sessions {
+		if entry.refresh {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// live returns the session of uuid unless it expired, the caller holds the
+// lock.
+func (sessions *MemorySessions) live(uuid string) (memorySession, bool) {
+	entry, ok := sessions.sessions[uuid]
+	if !ok {
+		return entry, false
+	}
+	if !entry.expires.After(time.Now()) {
+		delete(sessions.sessions, uuid)
+		return entry, false
+	}
+	return entry, true
+}
+
+// prune drops every expired token, the caller holds the lock.
+func (sessions *MemorySessions) prune() {
+	now := time.Now()
+	for uuid, entry := range sessions.sessions {
+		if !entry.expires.After(now) {
+			delete(sessions.sessions, uuid)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryWebhooks keeps webhooks and their deliveries in maps.
+type MemoryWebhooks struct {
+	mutex      sync.RWMutex
+	webhooks   map[string]model.WebhookModel
+	deliveries map[string]model.WebhookDeliveryModel
+}
+
+func InitMemoryWebhooks() *MemoryWebhooks {
+	return &MemoryWebhooks{
+		webhooks:   map[string]model.WebhookModel{},
+		deliveries: map[string]model.WebhookDeliveryModel{},
+	}
+}
+
+func (webhooks *MemoryWebhooks) Create(ctx context.Context, webhook *model.WebhookModel) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	if _, ok := webhooks.webhooks[webhook.Id]; ok {
+		return ErrDuplicate
+	}
+	webhooks.webhooks[webhook.Id] = copyWebhook(*webhook)
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) List(ctx context.Context, scope Scope) ([]model.WebhookModel, error) {
+	webhooks.mutex.RLock()
+	defer webhooks.mutex.RUnlock()
+
+	list := webhooks.matching(func(webhook model.WebhookModel) bool {
+		return scope.matches(webhook.OrgId)
+	})
+	for index := range list {
+		list[index].Secret = ""
+	}
+	return list, nil
+}
+
+func (webhooks *MemoryWebhooks) FindById(ctx context.Context, scope Scope, id string) (*model.WebhookModel, error) {
+	webhooks.mutex.RLock()
+	defer webhooks.mutex.RUnlock()
+
+	webhook, ok := webhooks.webhooks[id]
+	if !ok || !scope.matches(webhook.OrgId) {
+		return nil, ErrNotFound
+	}
+	webhook = copyWebhook(webhook)
+	return &webhook, nil
+}
+
+func (webhooks *MemoryWebhooks) Update(ctx context.Context, scope Scope, webhook model.WebhookModel) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	stored, ok := webhooks.webhooks[webhook.Id]
+	if !ok || !scope.matches(stored.OrgId) {
+		return ErrNotFound
+	}
+	stored.Url = webhook.Url
+	stored.Events = webhook.Events
+	stored.Secret = webhook.Secret
+	stored.Active = webhook.Active
+	stored.UpdatedAt = webhook.UpdatedAt
+	webhooks.webhooks[webhook.Id] = copyWebhook(stored)
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) Delete(ctx context.Context, scope Scope, id string) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	webhook, ok := webhooks.webhooks[id]
+	if !ok || !scope.matches(webhook.OrgId) {
+		return ErrNotFound
+	}
+	delete(webhooks.webhooks, id)
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) DeleteByOrganization(ctx context.Context, orgId string) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	for id, webhook := range webhooks.webhooks {
+		if webhook.OrgId == orgId {
+			delete(webhooks.webhooks, id)
+		}
+	}
+	for id, delivery := range webhooks.deliveries {
+		if delivery.OrgId == orgId {
+			delete(webhooks.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) Subscribed(ctx context.Context, orgId string, event model.EnumWebhookEvent) ([]model.WebhookModel, error) {
+	webhooks.mutex.RLock()
+	defer webhooks.mutex.RUnlock()
+
+	return webhooks.matching(func(webhook model.WebhookModel) bool {
+		if webhook.Active == nil || !*webhook.Active || (webhook.OrgId != "" && webhook.OrgId != orgId) {
+			return false
+		}
+		for _, subscribed := range webhook.Events {
+			if subscribed == event {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (webhooks *MemoryWebhooks) AddDeliveries(ctx context.Context, deliveries []model.WebhookDeliveryModel) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		if _, ok := webhooks.deliveries[delivery.Id]; ok {
+			return ErrDuplicate
+		}
+	}
+	for _, delivery := range deliveries {
+		webhooks.deliveries[delivery.Id] = copyDelivery(delivery)
+	}
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) Claim(ctx context.Context, now time.Time, lockedUntil time.Time) (*model.WebhookDeliveryModel, error) {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	var claimed *model.WebhookDeliveryModel
+	for _, delivery := range webhooks.deliveries {
+		if delivery.Status != model.DELIVERY_PENDING || delivery.NextAttemptAt.After(now) || delivery.LockedUntil.After(now) {
+			continue
+		}
+		if claimed == nil || delivery.NextAttemptAt.Before(claimed.NextAttemptAt) {
+			delivery := delivery
+			claimed = &delivery
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+	claimed.LockedUntil = lockedUntil
+	webhooks.deliveries[claimed.Id] = *claimed
+	found := copyDelivery(*claimed)
+	return &found, nil
+}
+
+func (webhooks *MemoryWebhooks) Finish(ctx context.Context, id string, attempt model.WebhookAttemptModel, status model.EnumDeliveryStatus, nextAttemptAt time.Time) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	delivery, ok := webhooks.deliveries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delivery = copyDelivery(delivery)
+	delivery.Status = status
+	switch status {
+	case model.DELIVERY_SUCCEEDED:
+		deliveredAt := attempt.At
+		delivery.DeliveredAt = &deliveredAt
+	case model.DELIVERY_PENDING:
+		delivery.NextAttemptAt = nextAttemptAt
+	}
+	delivery.AttemptCount++
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.LockedUntil = time.Time{}
+	webhooks.deliveries[id] = delivery
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) Redeliver(ctx context.Context, scope Scope, id string, now time.Time) error {
+	webhooks.mutex.Lock()
+	defer webhooks.mutex.Unlock()
+
+	delivery, ok := webhooks.deliveries[id]
+	if !ok || !scope.matches(delivery.OrgId) || delivery.Status == model.DELIVERY_PENDING {
+		return ErrNotFound
+	}
+	delivery.Status = model.DELIVERY_PENDING
+	delivery.AttemptCount = 0
+	delivery.NextAttemptAt = now
+	delivery.LockedUntil = time.Time{}
+	delivery.DeliveredAt = nil
+	webhooks.deliveries[id] = delivery
+	return nil
+}
+
+func (webhooks *MemoryWebhooks) ListDeliveries(ctx context.Context, scope Scope, query DeliveryQuery) ([]model.WebhookDeliveryModel, int64, error) {
+	webhooks.mutex.RLock()
+	defer webhooks.mutex.RUnlock()
+
+	matching := make([]model.WebhookDeliveryModel, 0)
+	for _, delivery := range webhooks.deliveries {
+		switch {
+		case !scope.matches(delivery.OrgId):
+		case query.WebhookId != "" && delivery.WebhookId != query.WebhookId:
+		case query.Status != "" && delivery.Status != query.Status:
+		case query.Event != "" && delivery.Event != query.Event:
+		default:
+			matching = append(matching, copyDelivery(delivery))
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if !matching[i].CreatedAt.Equal(matching[j].CreatedAt) {
+			return matching[i].CreatedAt.After(matching[j].CreatedAt)
+		}
+		return matching[i].Id > matching[j].Id
+	})
+	start, end := pageBounds(len(matching), Page{Skip: query.Skip, Size: query.Size})
+	return matching[start:end], int64(len(matching)), nil
+}
+
+// matching returns copies of the webhooks keep accepts in the order they
+// were created, the caller holds the lock.
+func (webhooks *MemoryWebhooks) matching(keep func(webhook model.WebhookModel) bool) []model.WebhookModel {
+	list := make([]model.WebhookModel, 0)
+	for _, webhook := range webhooks.webhooks {
+		if keep(webhook) {
+			list = append(list, copyWebhook(webhook))
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// copyWebhook keeps callers from changing the stored events and flag.
+func copyWebhook(webhook model.WebhookModel) model.WebhookModel {
+	webhook.Events = append([]model.EnumWebhookEvent(nil), webhook.Events...)
+	if webhook.Active != nil {
+		active := *webhook.Active
+		webhook.Active = &active
+	}
+	return webhook
+}
+
+// copyDelivery keeps callers from changing the stored delivery log.
+func copyDelivery(delivery model.WebhookDeliveryModel) model.WebhookDeliveryModel {
+	delivery.Attempts = append([]model.WebhookAttemptModel{}, delivery.Attempts...)
+	return delivery
+}
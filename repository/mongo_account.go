@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ACCOUNT_COLLECTION = "account"
+
+// MongoAccounts keeps accounts in the account collection. Every call uses
+// the context it is given, so calls made inside a Mongo transaction take
+// part in it.
+type MongoAccounts struct {
+	Collection *mongo.Collection
+}
+
+func InitMongoAccounts(database *mongo.Database) *MongoAccounts {
+	return &MongoAccounts{
+		Collection: database.Collection(ACCOUNT_COLLECTION),
+	}
+}
+
+func (accounts *MongoAccounts) Create(ctx context.Context, account *model.AccountModel) error {
+	_, err := accounts.Collection.InsertOne(ctx, account)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (accounts *MongoAccounts) FindById(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	return accounts.findOne(ctx, scopeFilter(scope, bson.M{"_id": id}))
+}
+
+func (accounts *MongoAccounts) FindByEmail(ctx context.Context, scope Scope, emailNormalized string) (*model.AccountModel, error) {
+	return accounts.findOne(ctx, scopeFilter(scope, bson.M{"emailNormalized": emailNormalized}))
+}
+
+func (accounts *MongoAccounts) CountByEmail(ctx context.Context, emailNormalized string) (int64, error) {
+	return accounts.Collection.CountDocuments(ctx, bson.M{"emailNormalized": emailNormalized})
+}
+
+func (accounts *MongoAccounts) List(ctx context.Context, scope Scope, query AccountQuery) ([]model.AccountModel, error) {
+	filter := scopeFilter(scope, nil)
+	for name, value := range query.Attributes {
+		filter["attributes."+name] = value
+	}
+	cursor, err := accounts.Collection.Find(ctx, filter, findOptions(query.Page))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.AccountModel, 0)
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (accounts *MongoAccounts) Update(ctx context.Context, scope Scope, id string, version *int64, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	return accounts.update(ctx, versionFilter(scopeFilter(scope, bson.M{"_id": id}), version), change)
+}
+
+func (accounts *MongoAccounts) UpdateByEmail(ctx context.Context, scope Scope, emailNormalized string, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	return accounts.update(ctx, scopeFilter(scope, bson.M{"emailNormalized": emailNormalized}), change)
+}
+
+func (accounts *MongoAccounts) Delete(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	account := model.AccountModel{}
+	err := accounts.Collection.FindOneAndDelete(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (accounts *MongoAccounts) CountByOrganization(ctx context.Context, orgId string) (int64, error) {
+	return accounts.Collection.CountDocuments(ctx, bson.M{"orgId": orgId})
+}
+
+func (accounts *MongoAccounts) CountByRole(ctx context.Context) (map[string]int64, error) {
+	cursor, err := accounts.Collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$role", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[string]int64{}
+	for cursor.Next(ctx) {
+		group := struct {
+			Role  string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}{}
+		err = cursor.Decode(&group)
+		if err != nil {
+			return nil, err
+		}
+		counts[group.Role] = group.Count
+	}
+	return counts, cursor.Err()
+}
+
+func (accounts *MongoAccounts) CountWithRole(ctx context.Context, orgId string, role string) (int64, error) {
+	return accounts.Collection.CountDocuments(ctx, scopeFilter(OrganizationScope(orgId), bson.M{"role": role}))
+}
+
+// UnsetAttribute updates the accounts one by one so every update yields the
+// account it replaced, run it in a transaction to make it all or nothing.
+func (accounts *MongoAccounts) UnsetAttribute(ctx context.Context, orgId string, name string) ([]AccountUpdate, error) {
+	filter := bson.M{"orgId": orgId, "attributes." + name: bson.M{"$exists": true}}
+	cursor, err := accounts.Collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	holders := make([]model.AccountModel, 0)
+	err = cursor.All(ctx, &holders)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$unset": bson.M{"attributes." + name: ""}, "$inc": bson.M{"version": 1}}
+	updates := make([]AccountUpdate, 0, len(holders))
+	for _, holder := range holders {
+		previous := model.AccountModel{}
+		err = accounts.Collection.FindOneAndUpdate(ctx, bson.M{"_id": holder.Id, "attributes." + name: bson.M{"$exists": true}}, update).Decode(&previous)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		updated := copyAccount(previous)
+		delete(updated.Attributes, name)
+		updated.Version++
+		updates = append(updates, AccountUpdate{Previous: previous, Updated: updated})
+	}
+	return updates, nil
+}
+
+func (accounts *MongoAccounts) findOne(ctx context.Context, filter bson.M) (*model.AccountModel, error) {
+	account := model.AccountModel{}
+	err := accounts.Collection.FindOne(ctx, filter).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// update writes change and derives the updated account from the one it
+// replaced, so one round trip yields both.
+func (accounts *MongoAccounts) update(ctx context.Context, filter bson.M, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	set := bson.M{"updatedAt": change.UpdatedAt}
+	unset := bson.M{}
+	if change.Name != nil {
+		set["name"] = *change.Name
+	}
+	if change.Email != nil {
+		set["email"] = *change.Email
+	}
+	if change.EmailNormalized != nil {
+		set["emailNormalized"] = *change.EmailNormalized
+	}
+	if change.Password != nil {
+		set["password"] = *change.Password
+	}
+	if change.Role != nil {
+		set["role"] = *change.Role
+	}
+	if change.ReplaceAttributes {
+		if len(change.Attributes) > 0 {
+			set["attributes"] = change.Attributes
+		} else {
+			unset["attributes"] = ""
+		}
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	previous := model.AccountModel{}
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := accounts.Collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil, ErrDuplicate
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := previous
+	updated.Attributes = copyAttributes(previous.Attributes)
+	change.Apply(&updated)
+	return &previous, &updated, nil
+}
+
+// scopeFilter narrows filter to scope, documents without organization have
+// no orgId field.
+func scopeFilter(scope Scope, filter bson.M) bson.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if scope.Any {
+		return filter
+	}
+	if scope.OrgId == "" {
+		filter["orgId"] = bson.M{"$exists": false}
+		return filter
+	}
+	filter["orgId"] = scope.OrgId
+	return filter
+}
+
+// versionFilter narrows filter to the expected version, documents written
+// before versioning have no version field and count as 0.
+func versionFilter(filter bson.M, version *int64) bson.M {
+	if version == nil {
+		return filter
+	}
+	if *version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		return filter
+	}
+	filter["version"] = *version
+	return filter
+}
+
+func findOptions(page Page) *options.FindOptions {
+	pageOptions := options.Find()
+	pageOptions.SetLimit(int64(page.Size))
+	if page.Order != "" && page.OrderBy != "" {
+		if page.Order == "asc" {
+			pageOptions.SetSort(bson.M{page.OrderBy: 1})
+		} else {
+			pageOptions.SetSort(bson.M{page.OrderBy: -1})
+		}
+	}
+	pageOptions.SetSkip(int64(page.Skip))
+	return pageOptions
+}
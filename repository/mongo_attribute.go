@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ATTRIBUTE_SCHEMA_COLLECTION = "attribute_schema"
+
+// MongoAttributeSchemas keeps attribute schemas in the attribute_schema
+// collection.
+type MongoAttributeSchemas struct {
+	Collection *mongo.Collection
+}
+
+func InitMongoAttributeSchemas(database *mongo.Database) *MongoAttributeSchemas {
+	return &MongoAttributeSchemas{
+		Collection: database.Collection(ATTRIBUTE_SCHEMA_COLLECTION),
+	}
+}
+
+func (schemas *MongoAttributeSchemas) Create(ctx context.Context, schema *model.AttributeSchemaModel) error {
+	_, err := schemas.Collection.InsertOne(ctx, schema)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (schemas *MongoAttributeSchemas) FindById(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	schema := model.AttributeSchemaModel{}
+	err := schemas.Collection.FindOne(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&schema)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (schemas *MongoAttributeSchemas) FindByOrganization(ctx context.Context, orgId string) ([]model.AttributeSchemaModel, error) {
+	return schemas.List(ctx, OrganizationScope(orgId), Page{})
+}
+
+func (schemas *MongoAttributeSchemas) List(ctx context.Context, scope Scope, page Page) ([]model.AttributeSchemaModel, error) {
+	cursor, err := schemas.Collection.Find(ctx, scopeFilter(scope, nil), findOptions(page))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.AttributeSchemaModel, 0)
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (schemas *MongoAttributeSchemas) Update(ctx context.Context, scope Scope, id string, change AttributeSchemaChange) (*model.AttributeSchemaModel, *model.AttributeSchemaModel, error) {
+	update := bson.M{"$set": bson.M{
+		"type":        change.Type,
+		"required":    change.Required,
+		"enum":        change.Enum,
+		"regex":       change.Regex,
+		"tokenClaim":  change.TokenClaim,
+		"description": change.Description,
+		"updatedAt":   change.UpdatedAt,
+	}}
+
+	previous := model.AttributeSchemaModel{}
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := schemas.Collection.FindOneAndUpdate(ctx, scopeFilter(scope, bson.M{"_id": id}), update, updateOptions).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := previous
+	change.Apply(&updated)
+	return &previous, &updated, nil
+}
+
+func (schemas *MongoAttributeSchemas) Delete(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	schema := model.AttributeSchemaModel{}
+	err := schemas.Collection.FindOneAndDelete(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&schema)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (schemas *MongoAttributeSchemas) DeleteByOrganization(ctx context.Context, orgId string) error {
+	_, err := schemas.Collection.DeleteMany(ctx, bson.M{"orgId": orgId})
+	return err
+}
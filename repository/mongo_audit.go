@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+const AUDIT_COLLECTION = "audit_log"
+const AUDIT_CHECKPOINT_COLLECTION = "audit_checkpoint"
+
+// MongoAudit keeps the audit chain in the audit_log collection. The hash
+// covers the exact BSON bytes of an event without its hash field, the same
+// bytes are inserted so they can be read back for verification. The unique
+// sequence index makes two writers race for a link instead of forking the
+// chain.
+type MongoAudit struct {
+	Collection           *mongo.Collection
+	CheckpointCollection *mongo.Collection
+}
+
+func InitMongoAudit(database *mongo.Database) *MongoAudit {
+	return &MongoAudit{
+		Collection:           database.Collection(AUDIT_COLLECTION),
+		CheckpointCollection: database.Collection(AUDIT_CHECKPOINT_COLLECTION),
+	}
+}
+
+func (audit *MongoAudit) Head(ctx context.Context) (int64, string, error) {
+	head := model.AuditEventModel{}
+	findOptions := options.FindOne().SetSort(bson.M{"sequence": -1}).SetProjection(bson.M{"sequence": 1, "hash": 1})
+	err := audit.Collection.FindOne(ctx, bson.M{"sequence": bson.M{"$exists": true}}, findOptions).Decode(&head)
+	if err == mongo.ErrNoDocuments {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return head.Sequence, head.Hash, nil
+}
+
+func (audit *MongoAudit) Content(event model.AuditEventModel) ([]byte, error) {
+	event.Hash = ""
+	return bson.Marshal(event)
+}
+
+func (audit *MongoAudit) Append(ctx context.Context, event model.AuditEventModel, content []byte) error {
+	document, err := withHash(content, event.Hash)
+	if err != nil {
+		return err
+	}
+	_, err = audit.Collection.InsertOne(ctx, document)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (audit *MongoAudit) AddCheckpoint(ctx context.Context, checkpoint model.AuditCheckpointModel) error {
+	_, err := audit.CheckpointCollection.InsertOne(ctx, checkpoint)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (audit *MongoAudit) Checkpoints(ctx context.Context) ([]model.AuditCheckpointModel, error) {
+	cursor, err := audit.CheckpointCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]model.AuditCheckpointModel, 0)
+	err = cursor.All(ctx, &checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+func (audit *MongoAudit) CountUnchained(ctx context.Context) (int64, error) {
+	return audit.Collection.CountDocuments(ctx, bson.M{"sequence": bson.M{"$exists": false}})
+}
+
+func (audit *MongoAudit) Walk(ctx context.Context, fn func(event model.AuditEventModel, content []byte) error) error {
+	cursor, err := audit.Collection.Find(ctx, bson.M{"sequence": bson.M{"$exists": true}}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		event := model.AuditEventModel{}
+		err = cursor.Decode(&event)
+		if err != nil {
+			return err
+		}
+		content, err := withoutHash(cursor.Current)
+		if err != nil {
+			return err
+		}
+		err = fn(event, content)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (audit *MongoAudit) List(ctx context.Context, scope Scope, query AuditQuery) ([]model.AuditEventModel, int64, error) {
+	filter := scopeFilter(scope, nil)
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.ActorEmail != "" {
+		filter["actor.email"] = query.ActorEmail
+	}
+	if query.TargetType != "" {
+		filter["target.type"] = query.TargetType
+	}
+	if query.TargetId != "" {
+		filter["target.id"] = query.TargetId
+	}
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lt"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	total, err := audit.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort := 1
+	if query.Descending {
+		sort = -1
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: sort}, {Key: "_id", Value: sort}}).
+		SetSkip(int64(query.Skip)).
+		SetLimit(int64(query.Size))
+	cursor, err := audit.Collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	events := make([]model.AuditEventModel, 0)
+	err = cursor.All(ctx, &events)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func withHash(content bson.Raw, hash string) (bson.Raw, error) {
+	elements, err := content.Elements()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(elements)+1)
+	for _, element := range elements {
+		values = append(values, element)
+	}
+	values = append(values, bsoncore.AppendStringElement(nil, "hash", hash))
+	return bsoncore.BuildDocument(nil, values...), nil
+}
+
+func withoutHash(document bson.Raw) (bson.Raw, error) {
+	elements, err := document.Elements()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(elements))
+	for _, element := range elements {
+		if element.Key() == "hash" {
+			continue
+		}
+		values = append(values, element)
+	}
+	return bsoncore.BuildDocument(nil, values...), nil
+}
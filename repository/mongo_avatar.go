@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"ima-svc-management/model"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const AVATAR_BUCKET = "avatar"
+
+// MongoAvatars keeps avatars in the avatar GridFS bucket. The bucket methods
+// of this driver take no context, a cancelled request is only noticed
+// between two of them.
+type MongoAvatars struct {
+	Database *mongo.Database
+}
+
+func InitMongoAvatars(database *mongo.Database) *MongoAvatars {
+	return &MongoAvatars{
+		Database: database,
+	}
+}
+
+func (avatars *MongoAvatars) Replace(ctx context.Context, accountId string, variants []model.AvatarVariantModel) error {
+	bucket, err := avatars.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	previous, err := avatars.files(ctx, bucket, bson.M{"metadata.accountId": accountId})
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+		uploadOptions := options.GridFSUpload().SetMetadata(variant.Metadata)
+		_, err = bucket.UploadFromStream(accountId+"-"+variant.Metadata.Variant, bytes.NewReader(variant.Content), uploadOptions)
+		if err != nil {
+			return err
+		}
+	}
+	return avatars.remove(ctx, bucket, previous)
+}
+
+func (avatars *MongoAvatars) Open(ctx context.Context, accountId string, variant string) (*model.AvatarFileModel, io.ReadCloser, error) {
+	bucket, err := avatars.bucket(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := avatars.files(ctx, bucket, bson.M{"metadata.accountId": accountId, "metadata.variant": variant})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	file := files[len(files)-1]
+	stream, err := bucket.OpenDownloadStream(file.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &file, stream, nil
+}
+
+func (avatars *MongoAvatars) Delete(ctx context.Context, accountId string) error {
+	bucket, err := avatars.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	files, err := avatars.files(ctx, bucket, bson.M{"metadata.accountId": accountId})
+	if err != nil {
+		return err
+	}
+	return avatars.remove(ctx, bucket, files)
+}
+
+func (avatars *MongoAvatars) DeleteByOrganization(ctx context.Context, orgId string) error {
+	bucket, err := avatars.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	files, err := avatars.files(ctx, bucket, bson.M{"metadata.orgId": orgId})
+	if err != nil {
+		return err
+	}
+	return avatars.remove(ctx, bucket, files)
+}
+
+// bucket opens the avatar bucket bounded by the deadline of ctx.
+func (avatars *MongoAvatars) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(avatars.Database, options.GridFSBucket().SetName(AVATAR_BUCKET))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		err = bucket.SetReadDeadline(deadline)
+		if err != nil {
+			return nil, err
+		}
+		err = bucket.SetWriteDeadline(deadline)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+func (avatars *MongoAvatars) files(ctx context.Context, bucket *gridfs.Bucket, filter bson.M) ([]model.AvatarFileModel, error) {
+	cursor, err := bucket.Find(filter, options.GridFSFind().SetSort(bson.M{"uploadDate": 1}))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]model.AvatarFileModel, 0)
+	err = cursor.All(ctx, &files)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (avatars *MongoAvatars) remove(ctx context.Context, bucket *gridfs.Bucket, files []model.AvatarFileModel) error {
+	for _, file := range files {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		err = bucket.Delete(file.Id)
+		if err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
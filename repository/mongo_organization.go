@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ORGANIZATION_COLLECTION = "organization"
+
+// MongoOrganizations keeps organizations in the organization collection.
+type MongoOrganizations struct {
+	Collection *mongo.Collection
+}
+
+func InitMongoOrganizations(database *mongo.Database) *MongoOrganizations {
+	return &MongoOrganizations{
+		Collection: database.Collection(ORGANIZATION_COLLECTION),
+	}
+}
+
+func (organizations *MongoOrganizations) Create(ctx context.Context, organization *model.OrganizationModel) error {
+	_, err := organizations.Collection.InsertOne(ctx, organization)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (organizations *MongoOrganizations) FindById(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	return organizations.findOne(ctx, bson.M{"_id": id})
+}
+
+func (organizations *MongoOrganizations) FindByName(ctx context.Context, name string) (*model.OrganizationModel, error) {
+	return organizations.findOne(ctx, bson.M{"name": name})
+}
+
+func (organizations *MongoOrganizations) List(ctx context.Context, page Page) ([]model.OrganizationModel, error) {
+	cursor, err := organizations.Collection.Find(ctx, bson.M{}, findOptions(page))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.OrganizationModel, 0)
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (organizations *MongoOrganizations) Update(ctx context.Context, id string, change OrganizationChange) (*model.OrganizationModel, *model.OrganizationModel, error) {
+	set := bson.M{"updatedAt": change.UpdatedAt}
+	if change.Name != nil {
+		set["name"] = *change.Name
+	}
+	if change.Description != nil {
+		set["description"] = *change.Description
+	}
+
+	previous := model.OrganizationModel{}
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := organizations.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, updateOptions).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil, ErrDuplicate
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := previous
+	change.Apply(&updated)
+	return &previous, &updated, nil
+}
+
+func (organizations *MongoOrganizations) Delete(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	organization := model.OrganizationModel{}
+	err := organizations.Collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&organization)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (organizations *MongoOrganizations) findOne(ctx context.Context, filter bson.M) (*model.OrganizationModel, error) {
+	organization := model.OrganizationModel{}
+	err := organizations.Collection.FindOne(ctx, filter).Decode(&organization)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
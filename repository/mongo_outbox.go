@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const OUTBOX_COLLECTION = "outbox"
+
+// MongoOutbox keeps domain events in the outbox collection, the TTL index
+// created by the migrations expires published events.
+type MongoOutbox struct {
+	Collection *mongo.Collection
+}
+
+func InitMongoOutbox(database *mongo.Database) *MongoOutbox {
+	return &MongoOutbox{
+		Collection: database.Collection(OUTBOX_COLLECTION),
+	}
+}
+
+func (outbox *MongoOutbox) Add(ctx context.Context, event *model.OutboxEventModel) error {
+	_, err := outbox.Collection.InsertOne(ctx, event)
+	return err
+}
+
+func (outbox *MongoOutbox) Pending(ctx context.Context, limit int) ([]model.OutboxEventModel, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := outbox.Collection.Find(ctx, bson.M{"publishedAt": nil}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]model.OutboxEventModel, 0)
+	err = cursor.All(ctx, &pending)
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (outbox *MongoOutbox) MarkPublished(ctx context.Context, id string, streamId string, publishedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"publishedAt": publishedAt, "streamId": streamId}}
+	_, err := outbox.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// Purge leaves expiry to the TTL index and removes nothing.
+func (outbox *MongoOutbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
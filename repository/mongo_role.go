@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const ROLE_COLLECTION = "role"
+
+// MongoRoles keeps roles in the role collection.
+type MongoRoles struct {
+	Collection *mongo.Collection
+}
+
+func InitMongoRoles(database *mongo.Database) *MongoRoles {
+	return &MongoRoles{
+		Collection: database.Collection(ROLE_COLLECTION),
+	}
+}
+
+func (roles *MongoRoles) Create(ctx context.Context, role *model.RoleModel) error {
+	_, err := roles.Collection.InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (roles *MongoRoles) FindById(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	role := model.RoleModel{}
+	err := roles.Collection.FindOne(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (roles *MongoRoles) FindByName(ctx context.Context, scope Scope, name string) (*model.RoleModel, error) {
+	role := model.RoleModel{}
+	err := roles.Collection.FindOne(ctx, scopeFilter(scope, bson.M{"name": name})).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (roles *MongoRoles) List(ctx context.Context, scope Scope, page Page) ([]model.RoleModel, error) {
+	cursor, err := roles.Collection.Find(ctx, scopeFilter(scope, nil), findOptions(page))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.RoleModel, 0)
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (roles *MongoRoles) Update(ctx context.Context, scope Scope, id string, version *int64, change RoleChange) (*model.RoleModel, *model.RoleModel, error) {
+	set := bson.M{"updatedAt": change.UpdatedAt}
+	if change.Name != nil {
+		set["name"] = *change.Name
+	}
+	if change.Role != nil {
+		set["role"] = *change.Role
+	}
+	if change.Description != nil {
+		set["description"] = *change.Description
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+
+	previous := model.RoleModel{}
+	filter := versionFilter(scopeFilter(scope, bson.M{"_id": id}), version)
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := roles.Collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil, ErrDuplicate
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	updated := previous
+	change.Apply(&updated)
+	return &previous, &updated, nil
+}
+
+func (roles *MongoRoles) Delete(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	role := model.RoleModel{}
+	err := roles.Collection.FindOneAndDelete(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (roles *MongoRoles) DeleteByOrganization(ctx context.Context, orgId string) ([]model.RoleModel, error) {
+	deleted := make([]model.RoleModel, 0)
+	cursor, err := roles.Collection.Find(ctx, bson.M{"orgId": orgId})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &deleted)
+	if err != nil {
+		return nil, err
+	}
+	_, err = roles.Collection.DeleteMany(ctx, bson.M{"orgId": orgId})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"ima-svc-management/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const WEBHOOK_COLLECTION = "webhook"
+const WEBHOOK_DELIVERY_COLLECTION = "webhook_delivery"
+
+// MongoWebhooks keeps webhooks in the webhook collection and their
+// deliveries in webhook_delivery.
+type MongoWebhooks struct {
+	Collection         *mongo.Collection
+	DeliveryCollection *mongo.Collection
+}
+
+func InitMongoWebhooks(database *mongo.Database) *MongoWebhooks {
+	return &MongoWebhooks{
+		Collection:         database.Collection(WEBHOOK_COLLECTION),
+		DeliveryCollection: database.Collection(WEBHOOK_DELIVERY_COLLECTION),
+	}
+}
+
+func (webhooks *MongoWebhooks) Create(ctx context.Context, webhook *model.WebhookModel) error {
+	_, err := webhooks.Collection.InsertOne(ctx, webhook)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (webhooks *MongoWebhooks) List(ctx context.Context, scope Scope) ([]model.WebhookModel, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetProjection(bson.M{"secret": 0})
+	return webhooks.find(ctx, scopeFilter(scope, nil), findOptions)
+}
+
+func (webhooks *MongoWebhooks) FindById(ctx context.Context, scope Scope, id string) (*model.WebhookModel, error) {
+	webhook := model.WebhookModel{}
+	err := webhooks.Collection.FindOne(ctx, scopeFilter(scope, bson.M{"_id": id})).Decode(&webhook)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (webhooks *MongoWebhooks) Update(ctx context.Context, scope Scope, webhook model.WebhookModel) error {
+	update := bson.M{"$set": bson.M{
+		"url":       webhook.Url,
+		"events":    webhook.Events,
+		"secret":    webhook.Secret,
+		"active":    webhook.Active,
+		"updatedAt": webhook.UpdatedAt,
+	}}
+	result, err := webhooks.Collection.UpdateOne(ctx, scopeFilter(scope, bson.M{"_id": webhook.Id}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (webhooks *MongoWebhooks) Delete(ctx context.Context, scope Scope, id string) error {
+	result, err := webhooks.Collection.DeleteOne(ctx, scopeFilter(scope, bson.M{"_id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (webhooks *MongoWebhooks) DeleteByOrganization(ctx context.Context, orgId string) error {
+	_, err := webhooks.Collection.DeleteMany(ctx, bson.M{"orgId": orgId})
+	if err != nil {
+		return err
+	}
+	_, err = webhooks.DeliveryCollection.DeleteMany(ctx, bson.M{"orgId": orgId})
+	return err
+}
+
+func (webhooks *MongoWebhooks) Subscribed(ctx context.Context, orgId string, event model.EnumWebhookEvent) ([]model.WebhookModel, error) {
+	filter := bson.M{
+		"active": true,
+		"events": event,
+		"$or":    bson.A{bson.M{"orgId": orgId}, bson.M{"orgId": bson.M{"$exists": false}}},
+	}
+	return webhooks.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+}
+
+func (webhooks *MongoWebhooks) AddDeliveries(ctx context.Context, deliveries []model.WebhookDeliveryModel) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	documents := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		documents = append(documents, delivery)
+	}
+	_, err := webhooks.DeliveryCollection.InsertMany(ctx, documents)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (webhooks *MongoWebhooks) Claim(ctx context.Context, now time.Time, lockedUntil time.Time) (*model.WebhookDeliveryModel, error) {
+	filter := bson.M{
+		"status":        model.DELIVERY_PENDING,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"$or":           bson.A{bson.M{"lockedUntil": bson.M{"$exists": false}}, bson.M{"lockedUntil": bson.M{"$lte": now}}},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": lockedUntil}}
+	claimOptions := options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After)
+
+	delivery := model.WebhookDeliveryModel{}
+	err := webhooks.DeliveryCollection.FindOneAndUpdate(ctx, filter, update, claimOptions).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (webhooks *MongoWebhooks) Finish(ctx context.Context, id string, attempt model.WebhookAttemptModel, status model.EnumDeliveryStatus, nextAttemptAt time.Time) error {
+	set := bson.M{"status": status}
+	switch status {
+	case model.DELIVERY_SUCCEEDED:
+		set["deliveredAt"] = attempt.At
+	case model.DELIVERY_PENDING:
+		set["nextAttemptAt"] = nextAttemptAt
+	}
+	update := bson.M{
+		"$set":   set,
+		"$inc":   bson.M{"attemptCount": 1},
+		"$push":  bson.M{"attempts": attempt},
+		"$unset": bson.M{"lockedUntil": ""},
+	}
+	result, err := webhooks.DeliveryCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (webhooks *MongoWebhooks) Redeliver(ctx context.Context, scope Scope, id string, now time.Time) error {
+	filter := scopeFilter(scope, bson.M{"_id": id, "status": bson.M{"$ne": model.DELIVERY_PENDING}})
+	update := bson.M{
+		"$set":   bson.M{"status": model.DELIVERY_PENDING, "attemptCount": 0, "nextAttemptAt": now},
+		"$unset": bson.M{"lockedUntil": "", "deliveredAt": ""},
+	}
+	result, err := webhooks.DeliveryCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (webhooks *MongoWebhooks) ListDeliveries(ctx context.Context, scope Scope, query DeliveryQuery) ([]model.WebhookDeliveryModel, int64, error) {
+	filter := scopeFilter(scope, nil)
+	if query.WebhookId != "" {
+		filter["webhookId"] = query.WebhookId
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.Event != "" {
+		filter["event"] = query.Event
+	}
+
+	total, err := webhooks.DeliveryCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Skip)).
+		SetLimit(int64(query.Size))
+	cursor, err := webhooks.DeliveryCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	deliveries := make([]model.WebhookDeliveryModel, 0)
+	err = cursor.All(ctx, &deliveries)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (webhooks *MongoWebhooks) find(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]model.WebhookModel, error) {
+	cursor, err := webhooks.Collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.WebhookModel, 0)
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes mapped to the repository errors.
+const (
+	PG_UNIQUE_VIOLATION      = "23505"
+	PG_FOREIGN_KEY_VIOLATION = "23503"
+)
+
+type postgresTxKey struct{}
+
+// PostgresTransactor runs transactions on the postgres storage backend, the
+// transaction travels in the context to the repositories.
+type PostgresTransactor struct {
+	DB *sql.DB
+}
+
+func InitPostgresTransactor(db *sql.DB) *PostgresTransactor {
+	return &PostgresTransactor{
+		DB: db,
+	}
+}
+
+// Transaction commits when fn succeeds and rolls back otherwise, a context
+// already carrying a transaction joins it.
+func (transactor *PostgresTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction(ctx, transactor.DB, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, postgresTxKey{}, tx))
+	})
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// connection returns the transaction of ctx, the pool outside of one.
+func connection(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(postgresTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// transaction runs fn in the transaction of ctx or in a new one, reads
+// followed by writes use it to lock the rows they change.
+func transaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(postgresTxKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return postgresError(tx.Commit())
+}
+
+// postgresError translates driver errors into the repository errors.
+func postgresError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	pgError := &pq.Error{}
+	if errors.As(err, &pgError) {
+		switch pgError.Code {
+		case PG_UNIQUE_VIOLATION:
+			return ErrDuplicate
+		case PG_FOREIGN_KEY_VIOLATION:
+			return ErrReference
+		}
+	}
+	return err
+}
+
+// where collects the conditions of a statement together with their
+// arguments, each condition holds one %s for its placeholder.
+type where struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (where *where) add(condition string, arg interface{}) {
+	where.args = append(where.args, arg)
+	where.conditions = append(where.conditions, fmt.Sprintf(condition, fmt.Sprintf("$%d", len(where.args))))
+}
+
+// addScope narrows to scope, rows without organization have a null org_id.
+func (where *where) addScope(scope Scope) {
+	switch {
+	case scope.Any:
+	case scope.OrgId == "":
+		where.conditions = append(where.conditions, "org_id IS NULL")
+	default:
+		where.add("org_id = %s", scope.OrgId)
+	}
+}
+
+// addVersion narrows to the expected version, rows start at version 0.
+func (where *where) addVersion(version *int64) {
+	if version != nil {
+		where.add("version = %s", *version)
+	}
+}
+
+func (where *where) String() string {
+	if len(where.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where.conditions, " AND ")
+}
+
+// pageClause orders and slices like findOptions does in Mongo. OrderBy names
+// the stored field, fields without column do not order, like a field missing
+// from every document. Missing values come first in ascending order as they
+// do in Mongo, ties keep the order the rows were created in.
+func pageClause(page Page, columns map[string]string) string {
+	order := ""
+	if column, ok := columns[page.OrderBy]; ok && page.Order != "" {
+		if page.Order == "asc" {
+			order = column + " ASC NULLS FIRST, "
+		} else {
+			order = column + " DESC NULLS LAST, "
+		}
+	}
+	clause := " ORDER BY " + order + "created_at, id"
+	if page.Size > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", page.Size)
+	}
+	if page.Skip > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", page.Skip)
+	}
+	return clause
+}
+
+// nullable stores an empty string as null so it passes foreign keys.
+func nullable(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
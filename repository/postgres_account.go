@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"ima-svc-management/model"
+)
+
+const ACCOUNT_TABLE = "accounts"
+
+const accountColumns = "id, org_id, name, email, email_normalized, role, password, attributes, version, created_at, updated_at"
+
+// accountOrderColumns maps the stored field names accepted as orderBy to
+// their columns.
+var accountOrderColumns = map[string]string{
+	"_id":             "id",
+	"orgId":           "org_id",
+	"name":            "name",
+	"email":           "email",
+	"emailNormalized": "email_normalized",
+	"role":            "role",
+	"version":         "version",
+	"createdAt":       "created_at",
+	"updatedAt":       "updated_at",
+}
+
+// PostgresAccounts keeps accounts in the accounts table. The role of an
+// account references a role of its organization by name.
+type PostgresAccounts struct {
+	DB *sql.DB
+}
+
+func InitPostgresAccounts(db *sql.DB) *PostgresAccounts {
+	return &PostgresAccounts{
+		DB: db,
+	}
+}
+
+func (accounts *PostgresAccounts) Create(ctx context.Context, account *model.AccountModel) error {
+	attributes, err := marshalAttributes(account.Attributes)
+	if err != nil {
+		return err
+	}
+	_, err = connection(ctx, accounts.DB).ExecContext(ctx,
+		"INSERT INTO "+ACCOUNT_TABLE+" ("+accountColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		account.Id, nullable(account.OrgId), account.Name, account.Email, account.EmailNormalized, nullable(account.Role),
+		account.Password, attributes, account.Version, account.CreatedAt, account.UpdatedAt)
+	return postgresError(err)
+}
+
+func (accounts *PostgresAccounts) FindById(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	return accounts.findOne(ctx, connection(ctx, accounts.DB), filter, "")
+}
+
+func (accounts *PostgresAccounts) FindByEmail(ctx context.Context, scope Scope, emailNormalized string) (*model.AccountModel, error) {
+	filter := &where{}
+	filter.add("email_normalized = %s", emailNormalized)
+	filter.addScope(scope)
+	return accounts.findOne(ctx, connection(ctx, accounts.DB), filter, "")
+}
+
+func (accounts *PostgresAccounts) CountByEmail(ctx context.Context, emailNormalized string) (int64, error) {
+	count := int64(0)
+	err := connection(ctx, accounts.DB).QueryRowContext(ctx, "SELECT count(*) FROM "+ACCOUNT_TABLE+" WHERE email_normalized = $1", emailNormalized).Scan(&count)
+	return count, postgresError(err)
+}
+
+func (accounts *PostgresAccounts) List(ctx context.Context, scope Scope, query AccountQuery) ([]model.AccountModel, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	if len(query.Attributes) > 0 {
+		attributes, err := json.Marshal(query.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		filter.add("attributes @> %s::jsonb", string(attributes))
+	}
+	rows, err := connection(ctx, accounts.DB).QueryContext(ctx, "SELECT "+accountColumns+" FROM "+ACCOUNT_TABLE+filter.String()+pageClause(query.Page, accountOrderColumns), filter.args...)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+
+	list := make([]model.AccountModel, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *account)
+	}
+	return list, postgresError(rows.Err())
+}
+
+func (accounts *PostgresAccounts) Update(ctx context.Context, scope Scope, id string, version *int64, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	filter.addVersion(version)
+	return accounts.update(ctx, filter, change)
+}
+
+func (accounts *PostgresAccounts) UpdateByEmail(ctx context.Context, scope Scope, emailNormalized string, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	filter := &where{}
+	filter.add("email_normalized = %s", emailNormalized)
+	filter.addScope(scope)
+	return accounts.update(ctx, filter, change)
+}
+
+func (accounts *PostgresAccounts) Delete(ctx context.Context, scope Scope, id string) (*model.AccountModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, accounts.DB).QueryRowContext(ctx, "DELETE FROM "+ACCOUNT_TABLE+filter.String()+" RETURNING "+accountColumns, filter.args...)
+	return scanAccount(row)
+}
+
+func (accounts *PostgresAccounts) CountByOrganization(ctx context.Context, orgId string) (int64, error) {
+	count := int64(0)
+	err := connection(ctx, accounts.DB).QueryRowContext(ctx, "SELECT count(*) FROM "+ACCOUNT_TABLE+" WHERE org_id = $1", orgId).Scan(&count)
+	return count, postgresError(err)
+}
+
+func (accounts *PostgresAccounts) CountByRole(ctx context.Context) (map[string]int64, error) {
+	rows, err := connection(ctx, accounts.DB).QueryContext(ctx, "SELECT coalesce(role, ''), count(*) FROM "+ACCOUNT_TABLE+" GROUP BY role")
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		role, count := "", int64(0)
+		err = rows.Scan(&role, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[role] = count
+	}
+	return counts, postgresError(rows.Err())
+}
+
+func (accounts *PostgresAccounts) CountWithRole(ctx context.Context, orgId string, role string) (int64, error) {
+	filter := &where{}
+	filter.addScope(OrganizationScope(orgId))
+	filter.add("role = %s", role)
+	count := int64(0)
+	err := connection(ctx, accounts.DB).QueryRowContext(ctx, "SELECT count(*) FROM "+ACCOUNT_TABLE+filter.String(), filter.args...).Scan(&count)
+	return count, postgresError(err)
+}
+
+// UnsetAttribute locks the accounts holding the attribute and updates each
+// of them, so the accounts returned are exactly the ones changed.
+func (accounts *PostgresAccounts) UnsetAttribute(ctx context.Context, orgId string, name string) ([]AccountUpdate, error) {
+	updates := make([]AccountUpdate, 0)
+	err := transaction(ctx, accounts.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT "+accountColumns+" FROM "+ACCOUNT_TABLE+" WHERE org_id = $1 AND attributes ? $2::text ORDER BY created_at, id FOR UPDATE",
+			orgId, name)
+		if err != nil {
+			return postgresError(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			previous, err := scanAccount(rows)
+			if err != nil {
+				return err
+			}
+			updated := copyAccount(*previous)
+			delete(updated.Attributes, name)
+			updated.Version++
+			updates = append(updates, AccountUpdate{Previous: *previous, Updated: updated})
+		}
+		err = rows.Err()
+		if err != nil {
+			return postgresError(err)
+		}
+		rows.Close()
+
+		for _, update := range updates {
+			_, err = tx.ExecContext(ctx,
+				"UPDATE "+ACCOUNT_TABLE+" SET attributes = attributes - $2::text, version = $3 WHERE id = $1",
+				update.Updated.Id, name, update.Updated.Version)
+			if err != nil {
+				return postgresError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// findOne returns the first account matching filter in creation order,
+// suffix appends a locking clause.
+func (accounts *PostgresAccounts) findOne(ctx context.Context, connection querier, filter *where, suffix string) (*model.AccountModel, error) {
+	row := connection.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM "+ACCOUNT_TABLE+filter.String()+" ORDER BY created_at, id LIMIT 1"+suffix, filter.args...)
+	return scanAccount(row)
+}
+
+// update locks the account matched by filter, applies change to it and
+// writes every column back, so the stored row is what Apply computed.
+func (accounts *PostgresAccounts) update(ctx context.Context, filter *where, change AccountChange) (*model.AccountModel, *model.AccountModel, error) {
+	var previous, updated *model.AccountModel
+	err := transaction(ctx, accounts.DB, func(tx *sql.Tx) error {
+		account, err := accounts.findOne(ctx, tx, filter, " FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		previous = account
+		copied := copyAccount(*account)
+		change.Apply(&copied)
+		updated = &copied
+
+		attributes, err := marshalAttributes(updated.Attributes)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE "+ACCOUNT_TABLE+" SET name = $2, email = $3, email_normalized = $4, role = $5, password = $6, attributes = $7, version = $8, updated_at = $9 WHERE id = $1",
+			updated.Id, updated.Name, updated.Email, updated.EmailNormalized, nullable(updated.Role), updated.Password, attributes, updated.Version, updated.UpdatedAt)
+		return postgresError(err)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, updated, nil
+}
+
+func scanAccount(row scanner) (*model.AccountModel, error) {
+	account := model.AccountModel{}
+	orgId, role, attributes := sql.NullString{}, sql.NullString{}, []byte(nil)
+	err := row.Scan(&account.Id, &orgId, &account.Name, &account.Email, &account.EmailNormalized, &role,
+		&account.Password, &attributes, &account.Version, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	account.OrgId = orgId.String
+	account.Role = role.String
+	if len(attributes) > 0 {
+		err = json.Unmarshal(attributes, &account.Attributes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &account, nil
+}
+
+// marshalAttributes stores accounts without attributes as null. The JSON is
+// passed as text, the driver would send bytes as bytea.
+func marshalAttributes(attributes map[string]interface{}) (interface{}, error) {
+	if attributes == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ima-svc-management/model"
+
+	"github.com/lib/pq"
+)
+
+const ATTRIBUTE_SCHEMA_TABLE = "attribute_schemas"
+
+const attributeSchemaColumns = "id, org_id, name, type, required, enum, regex, token_claim, description, created_at, updated_at"
+
+var attributeSchemaOrderColumns = map[string]string{
+	"_id":        "id",
+	"orgId":      "org_id",
+	"name":       "name",
+	"type":       "type",
+	"required":   "required",
+	"tokenClaim": "token_claim",
+	"createdAt":  "created_at",
+	"updatedAt":  "updated_at",
+}
+
+// PostgresAttributeSchemas keeps attribute schemas in the attribute_schemas
+// table, the enum values in a text array.
+type PostgresAttributeSchemas struct {
+	DB *sql.DB
+}
+
+func InitPostgresAttributeSchemas(db *sql.DB) *PostgresAttributeSchemas {
+	return &PostgresAttributeSchemas{
+		DB: db,
+	}
+}
+
+func (schemas *PostgresAttributeSchemas) Create(ctx context.Context, schema *model.AttributeSchemaModel) error {
+	_, err := connection(ctx, schemas.DB).ExecContext(ctx,
+		"INSERT INTO "+ATTRIBUTE_SCHEMA_TABLE+" ("+attributeSchemaColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		schema.Id, schema.OrgId, schema.Name, string(schema.Type), schema.Required, pq.StringArray(schema.Enum), schema.Regex,
+		schema.TokenClaim, schema.Description, schema.CreatedAt, schema.UpdatedAt)
+	return postgresError(err)
+}
+
+func (schemas *PostgresAttributeSchemas) FindById(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, schemas.DB).QueryRowContext(ctx, "SELECT "+attributeSchemaColumns+" FROM "+ATTRIBUTE_SCHEMA_TABLE+filter.String(), filter.args...)
+	return scanAttributeSchema(row)
+}
+
+func (schemas *PostgresAttributeSchemas) FindByOrganization(ctx context.Context, orgId string) ([]model.AttributeSchemaModel, error) {
+	return schemas.List(ctx, OrganizationScope(orgId), Page{})
+}
+
+func (schemas *PostgresAttributeSchemas) List(ctx context.Context, scope Scope, page Page) ([]model.AttributeSchemaModel, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	rows, err := connection(ctx, schemas.DB).QueryContext(ctx, "SELECT "+attributeSchemaColumns+" FROM "+ATTRIBUTE_SCHEMA_TABLE+filter.String()+pageClause(page, attributeSchemaOrderColumns), filter.args...)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+	list := make([]model.AttributeSchemaModel, 0)
+	for rows.Next() {
+		schema, err := scanAttributeSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *schema)
+	}
+	return list, postgresError(rows.Err())
+}
+
+func (schemas *PostgresAttributeSchemas) Update(ctx context.Context, scope Scope, id string, change AttributeSchemaChange) (*model.AttributeSchemaModel, *model.AttributeSchemaModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+
+	var previous, updated *model.AttributeSchemaModel
+	err := transaction(ctx, schemas.DB, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "SELECT "+attributeSchemaColumns+" FROM "+ATTRIBUTE_SCHEMA_TABLE+filter.String()+" FOR UPDATE", filter.args...)
+		schema, err := scanAttributeSchema(row)
+		if err != nil {
+			return err
+		}
+		previous = schema
+		copied := *schema
+		change.Apply(&copied)
+		updated = &copied
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE "+ATTRIBUTE_SCHEMA_TABLE+" SET type = $2, required = $3, enum = $4, regex = $5, token_claim = $6, description = $7, updated_at = $8 WHERE id = $1",
+			updated.Id, string(updated.Type), updated.Required, pq.StringArray(updated.Enum), updated.Regex, updated.TokenClaim, updated.Description, updated.UpdatedAt)
+		return postgresError(err)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, updated, nil
+}
+
+func (schemas *PostgresAttributeSchemas) Delete(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, schemas.DB).QueryRowContext(ctx, "DELETE FROM "+ATTRIBUTE_SCHEMA_TABLE+filter.String()+" RETURNING "+attributeSchemaColumns, filter.args...)
+	return scanAttributeSchema(row)
+}
+
+func (schemas *PostgresAttributeSchemas) DeleteByOrganization(ctx context.Context, orgId string) error {
+	_, err := connection(ctx, schemas.DB).ExecContext(ctx, "DELETE FROM "+ATTRIBUTE_SCHEMA_TABLE+" WHERE org_id = $1", orgId)
+	return postgresError(err)
+}
+
+func scanAttributeSchema(row scanner) (*model.AttributeSchemaModel, error) {
+	schema := model.AttributeSchemaModel{}
+	attributeType, enum := "", pq.StringArray{}
+	err := row.Scan(&schema.Id, &schema.OrgId, &schema.Name, &attributeType, &schema.Required, &enum, &schema.Regex,
+		&schema.TokenClaim, &schema.Description, &schema.CreatedAt, &schema.UpdatedAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	schema.Type = model.EnumAttributeType(attributeType)
+	if len(enum) > 0 {
+		schema.Enum = enum
+	}
+	return &schema, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"ima-svc-management/model"
+	"strconv"
+)
+
+const AUDIT_TABLE = "audit_log"
+const AUDIT_CHECKPOINT_TABLE = "audit_checkpoint"
+
+const checkpointColumns = "id, sequence, hash, signature, created_at"
+
+// PostgresAudit keeps the audit chain in the audit_log table. The hash covers
+// the JSON encoding of an event without its hash, it is stored as text so
+// the exact bytes are read back, jsonb would reorder them.
+type PostgresAudit struct {
+	DB *sql.DB
+}
+
+func InitPostgresAudit(db *sql.DB) *PostgresAudit {
+	return &PostgresAudit{
+		DB: db,
+	}
+}
+
+func (audit *PostgresAudit) Head(ctx context.Context) (int64, string, error) {
+	sequence, hash := int64(0), ""
+	err := connection(ctx, audit.DB).QueryRowContext(ctx,
+		"SELECT sequence, hash FROM "+AUDIT_TABLE+" ORDER BY sequence DESC LIMIT 1").Scan(&sequence, &hash)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", postgresError(err)
+	}
+	return sequence, hash, nil
+}
+
+func (audit *PostgresAudit) Content(event model.AuditEventModel) ([]byte, error) {
+	event.Hash = ""
+	return json.Marshal(event)
+}
+
+func (audit *PostgresAudit) Append(ctx context.Context, event model.AuditEventModel, content []byte) error {
+	_, err := connection(ctx, audit.DB).ExecContext(ctx,
+		"INSERT INTO "+AUDIT_TABLE+" (id, sequence, org_id, action, actor_email, target_type, target_id, created_at, hash, content) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		event.Id, event.Sequence, nullable(event.OrgId), string(event.Action), event.Actor.Email, event.Target.Type, event.Target.Id, event.CreatedAt, event.Hash, string(content))
+	return postgresError(err)
+}
+
+func (audit *PostgresAudit) AddCheckpoint(ctx context.Context, checkpoint model.AuditCheckpointModel) error {
+	_, err := connection(ctx, audit.DB).ExecContext(ctx,
+		"INSERT INTO "+AUDIT_CHECKPOINT_TABLE+" ("+checkpointColumns+") VALUES ($1, $2, $3, $4, $5)",
+		checkpoint.Id, checkpoint.Sequence, checkpoint.Hash, checkpoint.Signature, checkpoint.CreatedAt)
+	return postgresError(err)
+}
+
+func (audit *PostgresAudit) Checkpoints(ctx context.Context) ([]model.AuditCheckpointModel, error) {
+	rows, err := connection(ctx, audit.DB).QueryContext(ctx,
+		"SELECT "+checkpointColumns+" FROM "+AUDIT_CHECKPOINT_TABLE+" ORDER BY sequence")
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+	checkpoints := make([]model.AuditCheckpointModel, 0)
+	for rows.Next() {
+		checkpoint := model.AuditCheckpointModel{}
+		err = rows.Scan(&checkpoint.Id, &checkpoint.Sequence, &checkpoint.Hash, &checkpoint.Signature, &checkpoint.CreatedAt)
+		if err != nil {
+			return nil, postgresError(err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, postgresError(rows.Err())
+}
+
+// CountUnchained is always 0, the table was created with the chain.
+func (audit *PostgresAudit) CountUnchained(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (audit *PostgresAudit) Walk(ctx context.Context, fn func(event model.AuditEventModel, content []byte) error) error {
+	rows, err := connection(ctx, audit.DB).QueryContext(ctx, "SELECT hash, content FROM "+AUDIT_TABLE+" ORDER BY sequence")
+	if err != nil {
+		return postgresError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, content, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		err = fn(*event, content)
+		if err != nil {
+			return err
+		}
+	}
+	return postgresError(rows.Err())
+}
+
+func (audit *PostgresAudit) List(ctx context.Context, scope Scope, query AuditQuery) ([]model.AuditEventModel, int64, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	if query.Action != "" {
+		filter.add("action = %s", query.Action)
+	}
+	if query.ActorEmail != "" {
+		filter.add("actor_email = %s", query.ActorEmail)
+	}
+	if query.TargetType != "" {
+		filter.add("target_type = %s", query.TargetType)
+	}
+	if query.TargetId != "" {
+		filter.add("target_id = %s", query.TargetId)
+	}
+	if !query.From.IsZero() {
+		filter.add("created_at >= %s", query.From)
+	}
+	if !query.To.IsZero() {
+		filter.add("created_at < %s", query.To)
+	}
+
+	total := int64(0)
+	err := connection(ctx, audit.DB).QueryRowContext(ctx, "SELECT count(*) FROM "+AUDIT_TABLE+filter.String(), filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, postgresError(err)
+	}
+	order := " ORDER BY created_at, id"
+	if query.Descending {
+		order = " ORDER BY created_at DESC, id DESC"
+	}
+	if query.Size > 0 {
+		order += " LIMIT " + strconv.Itoa(query.Size)
+	}
+	if query.Skip > 0 {
+		order += " OFFSET " + strconv.Itoa(query.Skip)
+	}
+	rows, err := connection(ctx, audit.DB).QueryContext(ctx, "SELECT hash, content FROM "+AUDIT_TABLE+filter.String()+order, filter.args...)
+	if err != nil {
+		return nil, 0, postgresError(err)
+	}
+	defer rows.Close()
+	events := make([]model.AuditEventModel, 0)
+	for rows.Next() {
+		event, _, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, *event)
+	}
+	return events, total, postgresError(rows.Err())
+}
+
+// scanAuditEvent decodes the event from its content, the columns next to it
+// could be changed without touching the hash.
+func scanAuditEvent(row scanner) (*model.AuditEventModel, []byte, error) {
+	hash, content := "", ""
+	err := row.Scan(&hash, &content)
+	if err != nil {
+		return nil, nil, postgresError(err)
+	}
+	event := model.AuditEventModel{}
+	err = json.Unmarshal([]byte(content), &event)
+	if err != nil {
+		return nil, nil, err
+	}
+	event.Hash = hash
+	return &event, []byte(content), nil
+}
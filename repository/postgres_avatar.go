@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"ima-svc-management/model"
+	"io"
+)
+
+const AVATAR_TABLE = "avatars"
+
+// PostgresAvatars keeps avatars in the avatars table, one row per variant.
+// A new upload replaces the rows of the previous one in a transaction.
+type PostgresAvatars struct {
+	DB *sql.DB
+}
+
+func InitPostgresAvatars(db *sql.DB) *PostgresAvatars {
+	return &PostgresAvatars{
+		DB: db,
+	}
+}
+
+func (avatars *PostgresAvatars) Replace(ctx context.Context, accountId string, variants []model.AvatarVariantModel) error {
+	return transaction(ctx, avatars.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+AVATAR_TABLE+" WHERE account_id = $1", accountId)
+		if err != nil {
+			return postgresError(err)
+		}
+		for _, variant := range variants {
+			metadata := variant.Metadata
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO "+AVATAR_TABLE+" (account_id, org_id, variant, content_type, etag, content) VALUES ($1, $2, $3, $4, $5, $6)",
+				accountId, nullable(metadata.OrgId), metadata.Variant, metadata.ContentType, metadata.ETag, variant.Content)
+			if err != nil {
+				return postgresError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (avatars *PostgresAvatars) Open(ctx context.Context, accountId string, variant string) (*model.AvatarFileModel, io.ReadCloser, error) {
+	file := model.AvatarFileModel{}
+	orgId, content := sql.NullString{}, []byte(nil)
+	err := connection(ctx, avatars.DB).QueryRowContext(ctx,
+		"SELECT account_id, org_id, variant, content_type, etag, uploaded_at, content FROM "+AVATAR_TABLE+" WHERE account_id = $1 AND variant = $2",
+		accountId, variant).Scan(&file.Metadata.AccountId, &orgId, &file.Metadata.Variant, &file.Metadata.ContentType, &file.Metadata.ETag, &file.UploadDate, &content)
+	if err != nil {
+		return nil, nil, postgresError(err)
+	}
+	file.Id = accountId + "-" + variant
+	file.Metadata.OrgId = orgId.String
+	file.Length = int64(len(content))
+	return &file, io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (avatars *PostgresAvatars) Delete(ctx context.Context, accountId string) error {
+	_, err := connection(ctx, avatars.DB).ExecContext(ctx, "DELETE FROM "+AVATAR_TABLE+" WHERE account_id = $1", accountId)
+	return postgresError(err)
+}
+
+func (avatars *PostgresAvatars) DeleteByOrganization(ctx context.Context, orgId string) error {
+	_, err := connection(ctx, avatars.DB).ExecContext(ctx, "DELETE FROM "+AVATAR_TABLE+" WHERE org_id = $1", orgId)
+	return postgresError(err)
+}
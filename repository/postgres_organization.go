@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ima-svc-management/model"
+)
+
+const ORGANIZATION_TABLE = "organizations"
+
+const organizationColumns = "id, name, description, created_at, updated_at"
+
+var organizationOrderColumns = map[string]string{
+	"_id":         "id",
+	"name":        "name",
+	"description": "description",
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+}
+
+// PostgresOrganizations keeps organizations in the organizations table.
+type PostgresOrganizations struct {
+	DB *sql.DB
+}
+
+func InitPostgresOrganizations(db *sql.DB) *PostgresOrganizations {
+	return &PostgresOrganizations{
+		DB: db,
+	}
+}
+
+func (organizations *PostgresOrganizations) Create(ctx context.Context, organization *model.OrganizationModel) error {
+	_, err := connection(ctx, organizations.DB).ExecContext(ctx,
+		"INSERT INTO "+ORGANIZATION_TABLE+" ("+organizationColumns+") VALUES ($1, $2, $3, $4, $5)",
+		organization.Id, organization.Name, organization.Description, organization.CreatedAt, organization.UpdatedAt)
+	return postgresError(err)
+}
+
+func (organizations *PostgresOrganizations) FindById(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	row := connection(ctx, organizations.DB).QueryRowContext(ctx, "SELECT "+organizationColumns+" FROM "+ORGANIZATION_TABLE+" WHERE id = $1", id)
+	return scanOrganization(row)
+}
+
+func (organizations *PostgresOrganizations) FindByName(ctx context.Context, name string) (*model.OrganizationModel, error) {
+	row := connection(ctx, organizations.DB).QueryRowContext(ctx, "SELECT "+organizationColumns+" FROM "+ORGANIZATION_TABLE+" WHERE name = $1", name)
+	return scanOrganization(row)
+}
+
+func (organizations *PostgresOrganizations) List(ctx context.Context, page Page) ([]model.OrganizationModel, error) {
+	rows, err := connection(ctx, organizations.DB).QueryContext(ctx, "SELECT "+organizationColumns+" FROM "+ORGANIZATION_TABLE+pageClause(page, organizationOrderColumns))
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+	list := make([]model.OrganizationModel, 0)
+	for rows.Next() {
+		organization, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *organization)
+	}
+	return list, postgresError(rows.Err())
+}
+
+func (organizations *PostgresOrganizations) Update(ctx context.Context, id string, change OrganizationChange) (*model.OrganizationModel, *model.OrganizationModel, error) {
+	var previous, updated *model.OrganizationModel
+	err := transaction(ctx, organizations.DB, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "SELECT "+organizationColumns+" FROM "+ORGANIZATION_TABLE+" WHERE id = $1 FOR UPDATE", id)
+		organization, err := scanOrganization(row)
+		if err != nil {
+			return err
+		}
+		previous = organization
+		copied := *organization
+		change.Apply(&copied)
+		updated = &copied
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE "+ORGANIZATION_TABLE+" SET name = $2, description = $3, updated_at = $4 WHERE id = $1",
+			updated.Id, updated.Name, updated.Description, updated.UpdatedAt)
+		return postgresError(err)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, updated, nil
+}
+
+func (organizations *PostgresOrganizations) Delete(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	row := connection(ctx, organizations.DB).QueryRowContext(ctx, "DELETE FROM "+ORGANIZATION_TABLE+" WHERE id = $1 RETURNING "+organizationColumns, id)
+	return scanOrganization(row)
+}
+
+func scanOrganization(row scanner) (*model.OrganizationModel, error) {
+	organization := model.OrganizationModel{}
+	err := row.Scan(&organization.Id, &organization.Name, &organization.Description, &organization.CreatedAt, &organization.UpdatedAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	return &organization, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ima-svc-management/model"
+	"time"
+)
+
+const OUTBOX_TABLE = "outbox"
+
+const outboxColumns = "id, type, org_id, aggregate_id, payload, created_at, published_at, stream_id"
+
+// PostgresOutbox keeps domain events in the outbox table, next to the
+// accounts and roles whose changes they announce.
+type PostgresOutbox struct {
+	DB *sql.DB
+}
+
+func InitPostgresOutbox(db *sql.DB) *PostgresOutbox {
+	return &PostgresOutbox{
+		DB: db,
+	}
+}
+
+func (outbox *PostgresOutbox) Add(ctx context.Context, event *model.OutboxEventModel) error {
+	_, err := connection(ctx, outbox.DB).ExecContext(ctx,
+		"INSERT INTO "+OUTBOX_TABLE+" ("+outboxColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		event.Id, string(event.Type), event.OrgId, event.AggregateId, event.Payload, event.CreatedAt, event.PublishedAt, event.StreamId)
+	return postgresError(err)
+}
+
+func (outbox *PostgresOutbox) Pending(ctx context.Context, limit int) ([]model.OutboxEventModel, error) {
+	rows, err := connection(ctx, outbox.DB).QueryContext(ctx,
+		"SELECT "+outboxColumns+" FROM "+OUTBOX_TABLE+" WHERE published_at IS NULL ORDER BY created_at, id LIMIT $1", limit)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+	pending := make([]model.OutboxEventModel, 0)
+	for rows.Next() {
+		event := model.OutboxEventModel{}
+		eventType := ""
+		publishedAt := sql.NullTime{}
+		err = rows.Scan(&event.Id, &eventType, &event.OrgId, &event.AggregateId, &event.Payload, &event.CreatedAt, &publishedAt, &event.StreamId)
+		if err != nil {
+			return nil, postgresError(err)
+		}
+		event.Type = model.EnumDomainEvent(eventType)
+		if publishedAt.Valid {
+			event.PublishedAt = &publishedAt.Time
+		}
+		pending = append(pending, event)
+	}
+	return pending, postgresError(rows.Err())
+}
+
+func (outbox *PostgresOutbox) MarkPublished(ctx context.Context, id string, streamId string, publishedAt time.Time) error {
+	_, err := connection(ctx, outbox.DB).ExecContext(ctx,
+		"UPDATE "+OUTBOX_TABLE+" SET published_at = $1, stream_id = $2 WHERE id = $3", publishedAt, streamId, id)
+	return postgresError(err)
+}
+
+func (outbox *PostgresOutbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := connection(ctx, outbox.DB).ExecContext(ctx,
+		"DELETE FROM "+OUTBOX_TABLE+" WHERE published_at < $1", before)
+	if err != nil {
+		return 0, postgresError(err)
+	}
+	return result.RowsAffected()
+}
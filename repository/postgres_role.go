@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ima-svc-management/model"
+)
+
+const ROLE_TABLE = "roles"
+
+const roleColumns = "id, org_id, name, role, description, version, created_at, updated_at"
+
+var roleOrderColumns = map[string]string{
+	"_id":         "id",
+	"orgId":       "org_id",
+	"name":        "name",
+	"role":        "role",
+	"description": "description",
+	"version":     "version",
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+}
+
+// PostgresRoles keeps roles in the roles table. A role assigned to accounts
+// can neither be renamed nor removed.
+type PostgresRoles struct {
+	DB *sql.DB
+}
+
+func InitPostgresRoles(db *sql.DB) *PostgresRoles {
+	return &PostgresRoles{
+		DB: db,
+	}
+}
+
+func (roles *PostgresRoles) Create(ctx context.Context, role *model.RoleModel) error {
+	_, err := connection(ctx, roles.DB).ExecContext(ctx,
+		"INSERT INTO "+ROLE_TABLE+" ("+roleColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		role.Id, role.OrgId, role.Name, string(role.Role), role.Description, role.Version, role.CreatedAt, role.UpdatedAt)
+	return postgresError(err)
+}
+
+func (roles *PostgresRoles) FindById(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, roles.DB).QueryRowContext(ctx, "SELECT "+roleColumns+" FROM "+ROLE_TABLE+filter.String(), filter.args...)
+	return scanRole(row)
+}
+
+func (roles *PostgresRoles) FindByName(ctx context.Context, scope Scope, name string) (*model.RoleModel, error) {
+	filter := &where{}
+	filter.add("name = %s", name)
+	filter.addScope(scope)
+	row := connection(ctx, roles.DB).QueryRowContext(ctx, "SELECT "+roleColumns+" FROM "+ROLE_TABLE+filter.String()+" ORDER BY created_at, id LIMIT 1", filter.args...)
+	return scanRole(row)
+}
+
+func (roles *PostgresRoles) List(ctx context.Context, scope Scope, page Page) ([]model.RoleModel, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	rows, err := connection(ctx, roles.DB).QueryContext(ctx, "SELECT "+roleColumns+" FROM "+ROLE_TABLE+filter.String()+pageClause(page, roleOrderColumns), filter.args...)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	return scanRoles(rows)
+}
+
+func (roles *PostgresRoles) Update(ctx context.Context, scope Scope, id string, version *int64, change RoleChange) (*model.RoleModel, *model.RoleModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	filter.addVersion(version)
+
+	var previous, updated *model.RoleModel
+	err := transaction(ctx, roles.DB, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "SELECT "+roleColumns+" FROM "+ROLE_TABLE+filter.String()+" FOR UPDATE", filter.args...)
+		role, err := scanRole(row)
+		if err != nil {
+			return err
+		}
+		previous = role
+		copied := *role
+		change.Apply(&copied)
+		updated = &copied
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE "+ROLE_TABLE+" SET name = $2, role = $3, description = $4, version = $5, updated_at = $6 WHERE id = $1",
+			updated.Id, updated.Name, string(updated.Role), updated.Description, updated.Version, updated.UpdatedAt)
+		return postgresError(err)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, updated, nil
+}
+
+func (roles *PostgresRoles) Delete(ctx context.Context, scope Scope, id string) (*model.RoleModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, roles.DB).QueryRowContext(ctx, "DELETE FROM "+ROLE_TABLE+filter.String()+" RETURNING "+roleColumns, filter.args...)
+	return scanRole(row)
+}
+
+func (roles *PostgresRoles) DeleteByOrganization(ctx context.Context, orgId string) ([]model.RoleModel, error) {
+	rows, err := connection(ctx, roles.DB).QueryContext(ctx,
+		"WITH deleted AS (DELETE FROM "+ROLE_TABLE+" WHERE org_id = $1 RETURNING "+roleColumns+") SELECT "+roleColumns+" FROM deleted ORDER BY created_at, id",
+		orgId)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	return scanRoles(rows)
+}
+
+func scanRole(row scanner) (*model.RoleModel, error) {
+	role := model.RoleModel{}
+	name := ""
+	updatedAt := sql.NullTime{}
+	err := row.Scan(&role.Id, &role.OrgId, &role.Name, &name, &role.Description, &role.Version, &role.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	role.Role = model.EnumRole(name)
+	if updatedAt.Valid {
+		role.UpdatedAt = &updatedAt.Time
+	}
+	return &role, nil
+}
+
+func scanRoles(rows *sql.Rows) ([]model.RoleModel, error) {
+	defer rows.Close()
+	list := make([]model.RoleModel, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *role)
+	}
+	return list, postgresError(rows.Err())
+}
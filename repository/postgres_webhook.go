@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"ima-svc-management/model"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const WEBHOOK_TABLE = "webhooks"
+const WEBHOOK_DELIVERY_TABLE = "webhook_deliveries"
+
+const webhookColumns = "id, org_id, url, events, secret, active, created_at, updated_at"
+const deliveryColumns = "id, webhook_id, org_id, event, payload, status, attempt_count, attempts, next_attempt_at, locked_until, created_at, delivered_at"
+
+// PostgresWebhooks keeps webhooks in the webhooks table and their deliveries
+// in webhook_deliveries. Workers claim deliveries with SKIP LOCKED so they
+// never wait for each other.
+type PostgresWebhooks struct {
+	DB *sql.DB
+}
+
+func InitPostgresWebhooks(db *sql.DB) *PostgresWebhooks {
+	return &PostgresWebhooks{
+		DB: db,
+	}
+}
+
+func (webhooks *PostgresWebhooks) Create(ctx context.Context, webhook *model.WebhookModel) error {
+	_, err := connection(ctx, webhooks.DB).ExecContext(ctx,
+		"INSERT INTO "+WEBHOOK_TABLE+" ("+webhookColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		webhook.Id, nullable(webhook.OrgId), webhook.Url, webhookEvents(webhook.Events), webhook.Secret, webhook.Active != nil && *webhook.Active,
+		webhook.CreatedAt, webhook.UpdatedAt)
+	return postgresError(err)
+}
+
+func (webhooks *PostgresWebhooks) List(ctx context.Context, scope Scope) ([]model.WebhookModel, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	list, err := webhooks.find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for index := range list {
+		list[index].Secret = ""
+	}
+	return list, nil
+}
+
+func (webhooks *PostgresWebhooks) FindById(ctx context.Context, scope Scope, id string) (*model.WebhookModel, error) {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	row := connection(ctx, webhooks.DB).QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM "+WEBHOOK_TABLE+filter.String(), filter.args...)
+	return scanWebhook(row)
+}
+
+func (webhooks *PostgresWebhooks) Update(ctx context.Context, scope Scope, webhook model.WebhookModel) error {
+	// the new values take the first placeholders, the conditions follow
+	filter := &where{args: []interface{}{webhook.Url, webhookEvents(webhook.Events), webhook.Secret, webhook.Active != nil && *webhook.Active, webhook.UpdatedAt}}
+	filter.add("id = %s", webhook.Id)
+	filter.addScope(scope)
+	result, err := connection(ctx, webhooks.DB).ExecContext(ctx,
+		"UPDATE "+WEBHOOK_TABLE+" SET url = $1, events = $2, secret = $3, active = $4, updated_at = $5"+filter.String(), filter.args...)
+	return affected(result, err)
+}
+
+func (webhooks *PostgresWebhooks) Delete(ctx context.Context, scope Scope, id string) error {
+	filter := &where{}
+	filter.add("id = %s", id)
+	filter.addScope(scope)
+	result, err := connection(ctx, webhooks.DB).ExecContext(ctx, "DELETE FROM "+WEBHOOK_TABLE+filter.String(), filter.args...)
+	return affected(result, err)
+}
+
+func (webhooks *PostgresWebhooks) DeleteByOrganization(ctx context.Context, orgId string) error {
+	return transaction(ctx, webhooks.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+WEBHOOK_TABLE+" WHERE org_id = $1", orgId)
+		if err != nil {
+			return postgresError(err)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+WEBHOOK_DELIVERY_TABLE+" WHERE org_id = $1", orgId)
+		return postgresError(err)
+	})
+}
+
+func (webhooks *PostgresWebhooks) Subscribed(ctx context.Context, orgId string, event model.EnumWebhookEvent) ([]model.WebhookModel, error) {
+	filter := &where{}
+	filter.conditions = append(filter.conditions, "active")
+	filter.add("%s = ANY (events)", string(event))
+	filter.add("(org_id = %s OR org_id IS NULL)", orgId)
+	return webhooks.find(ctx, filter)
+}
+
+func (webhooks *PostgresWebhooks) AddDeliveries(ctx context.Context, deliveries []model.WebhookDeliveryModel) error {
+	return transaction(ctx, webhooks.DB, func(tx *sql.Tx) error {
+		for _, delivery := range deliveries {
+			// a JSON null would not take the attempts appended by Finish
+			if delivery.Attempts == nil {
+				delivery.Attempts = []model.WebhookAttemptModel{}
+			}
+			attempts, err := json.Marshal(delivery.Attempts)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO "+WEBHOOK_DELIVERY_TABLE+" ("+deliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULL, $10, NULL)",
+				delivery.Id, delivery.WebhookId, nullable(delivery.OrgId), string(delivery.Event), delivery.Payload, string(delivery.Status),
+				delivery.AttemptCount, string(attempts), delivery.NextAttemptAt, delivery.CreatedAt)
+			if err != nil {
+				return postgresError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (webhooks *PostgresWebhooks) Claim(ctx context.Context, now time.Time, lockedUntil time.Time) (*model.WebhookDeliveryModel, error) {
+	row := connection(ctx, webhooks.DB).QueryRowContext(ctx,
+		"UPDATE "+WEBHOOK_DELIVERY_TABLE+" SET locked_until = $2 WHERE id = ("+
+			"SELECT id FROM "+WEBHOOK_DELIVERY_TABLE+" WHERE status = $3 AND next_attempt_at <= $1 AND (locked_until IS NULL OR locked_until <= $1) "+
+			"ORDER BY next_attempt_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING "+deliveryColumns,
+		now, lockedUntil, string(model.DELIVERY_PENDING))
+	delivery, err := scanDelivery(row)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+func (webhooks *PostgresWebhooks) Finish(ctx context.Context, id string, attempt model.WebhookAttemptModel, status model.EnumDeliveryStatus, nextAttemptAt time.Time) error {
+	attempts, err := json.Marshal([]model.WebhookAttemptModel{attempt})
+	if err != nil {
+		return err
+	}
+	deliveredAt, next := sql.NullTime{}, sql.NullTime{}
+	switch status {
+	case model.DELIVERY_SUCCEEDED:
+		deliveredAt = sql.NullTime{Time: attempt.At, Valid: true}
+	case model.DELIVERY_PENDING:
+		next = sql.NullTime{Time: nextAttemptAt, Valid: true}
+	}
+	result, err := connection(ctx, webhooks.DB).ExecContext(ctx,
+		"UPDATE "+WEBHOOK_DELIVERY_TABLE+" SET status = $2, attempt_count = attempt_count + 1, attempts = attempts || $3::jsonb, locked_until = NULL, "+
+			"delivered_at = coalesce($4, delivered_at), next_attempt_at = coalesce($5, next_attempt_at) WHERE id = $1",
+		id, string(status), string(attempts), deliveredAt, next)
+	return affected(result, err)
+}
+
+func (webhooks *PostgresWebhooks) Redeliver(ctx context.Context, scope Scope, id string, now time.Time) error {
+	filter := &where{args: []interface{}{string(model.DELIVERY_PENDING), now}}
+	filter.add("id = %s", id)
+	filter.add("status <> %s", string(model.DELIVERY_PENDING))
+	filter.addScope(scope)
+	result, err := connection(ctx, webhooks.DB).ExecContext(ctx,
+		"UPDATE "+WEBHOOK_DELIVERY_TABLE+" SET status = $1, attempt_count = 0, next_attempt_at = $2, locked_until = NULL, delivered_at = NULL"+filter.String(),
+		filter.args...)
+	return affected(result, err)
+}
+
+func (webhooks *PostgresWebhooks) ListDeliveries(ctx context.Context, scope Scope, query DeliveryQuery) ([]model.WebhookDeliveryModel, int64, error) {
+	filter := &where{}
+	filter.addScope(scope)
+	if query.WebhookId != "" {
+		filter.add("webhook_id = %s", query.WebhookId)
+	}
+	if query.Status != "" {
+		filter.add("status = %s", string(query.Status))
+	}
+	if query.Event != "" {
+		filter.add("event = %s", string(query.Event))
+	}
+
+	total := int64(0)
+	err := connection(ctx, webhooks.DB).QueryRowContext(ctx, "SELECT count(*) FROM "+WEBHOOK_DELIVERY_TABLE+filter.String(), filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, postgresError(err)
+	}
+	order := " ORDER BY created_at DESC, id DESC"
+	if query.Size > 0 {
+		order += " LIMIT " + strconv.Itoa(query.Size)
+	}
+	if query.Skip > 0 {
+		order += " OFFSET " + strconv.Itoa(query.Skip)
+	}
+	rows, err := connection(ctx, webhooks.DB).QueryContext(ctx, "SELECT "+deliveryColumns+" FROM "+WEBHOOK_DELIVERY_TABLE+filter.String()+order, filter.args...)
+	if err != nil {
+		return nil, 0, postgresError(err)
+	}
+	defer rows.Close()
+	deliveries := make([]model.WebhookDeliveryModel, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, total, postgresError(rows.Err())
+}
+
+func (webhooks *PostgresWebhooks) find(ctx context.Context, filter *where) ([]model.WebhookModel, error) {
+	rows, err := connection(ctx, webhooks.DB).QueryContext(ctx, "SELECT "+webhookColumns+" FROM "+WEBHOOK_TABLE+filter.String()+" ORDER BY created_at, id", filter.args...)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+	list := make([]model.WebhookModel, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *webhook)
+	}
+	return list, postgresError(rows.Err())
+}
+
+// affected is ErrNotFound for a statement that changed no row.
+func affected(result sql.Result, err error) error {
+	if err != nil {
+		return postgresError(err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func webhookEvents(events []model.EnumWebhookEvent) pq.StringArray {
+	names := make(pq.StringArray, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return names
+}
+
+func scanWebhook(row scanner) (*model.WebhookModel, error) {
+	webhook := model.WebhookModel{}
+	orgId, events, active := sql.NullString{}, pq.StringArray{}, false
+	err := row.Scan(&webhook.Id, &orgId, &webhook.Url, &events, &webhook.Secret, &active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	webhook.OrgId = orgId.String
+	webhook.Active = &active
+	webhook.Events = make([]model.EnumWebhookEvent, 0, len(events))
+	for _, event := range events {
+		webhook.Events = append(webhook.Events, model.EnumWebhookEvent(event))
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row scanner) (*model.WebhookDeliveryModel, error) {
+	delivery := model.WebhookDeliveryModel{}
+	orgId, event, status, attempts := sql.NullString{}, "", "", []byte(nil)
+	lockedUntil, deliveredAt := sql.NullTime{}, sql.NullTime{}
+	err := row.Scan(&delivery.Id, &delivery.WebhookId, &orgId, &event, &delivery.Payload, &status, &delivery.AttemptCount, &attempts,
+		&delivery.NextAttemptAt, &lockedUntil, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	delivery.OrgId = orgId.String
+	delivery.Event = model.EnumWebhookEvent(event)
+	delivery.Status = model.EnumDeliveryStatus(status)
+	err = json.Unmarshal(attempts, &delivery.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		delivery.LockedUntil = lockedUntil.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
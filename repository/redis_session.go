@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"ima-svc-management/model"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const SESSION_INDEX_PREFIX = "session:"
+
+// SESSION_ACTIVE_KEY scores every refresh token by its expiry so live
+// sessions can be counted without scanning.
+const SESSION_ACTIVE_KEY = "sessions:active"
+
+// RedisSessions keeps every token id as a key expiring with the token and a
+// set per account indexing them. Commands touch one key each so they also
+// work against a cluster.
+type RedisSessions struct {
+	RedisClient redis.UniversalClient
+}
+
+func InitRedisSessions(redisClient redis.UniversalClient) *RedisSessions {
+	return &RedisSessions{
+		RedisClient: redisClient,
+	}
+}
+
+func (sessions *RedisSessions) Create(ctx context.Context, account string, session model.SessionModel, access string, accessExpires time.Time, refresh string, refreshExpires time.Time) error {
+	now := time.Now()
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	err = sessions.RedisClient.Set(ctx, access, value, accessExpires.Sub(now)).Err()
+	if err != nil {
+		return err
+	}
+	err = sessions.RedisClient.Set(ctx, refresh, value, refreshExpires.Sub(now)).Err()
+	if err != nil {
+		return err
+	}
+
+	indexKey := SESSION_INDEX_PREFIX + account
+	err = sessions.RedisClient.SAdd(ctx, indexKey, access, refresh).Err()
+	if err != nil {
+		return err
+	}
+	err = sessions.RedisClient.ExpireAt(ctx, indexKey, refreshExpires).Err()
+	if err != nil {
+		return err
+	}
+	return sessions.RedisClient.ZAdd(ctx, SESSION_ACTIVE_KEY, &redis.Z{Score: float64(refreshExpires.Unix()), Member: refresh}).Err()
+}
+
+func (sessions *RedisSessions) Fetch(ctx context.Context, uuid string) (*model.SessionModel, error) {
+	value, err := sessions.RedisClient.Get(ctx, uuid).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	session := &model.SessionModel{}
+	err = json.Unmarshal([]byte(value), session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (sessions *RedisSessions) Delete(ctx context.Context, uuid string) (int64, error) {
+	deleted, err := sessions.RedisClient.Del(ctx, uuid).Result()
+	if err != nil {
+		return 0, err
+	}
+	err = sessions.RedisClient.ZRem(ctx, SESSION_ACTIVE_KEY, uuid).Err()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// List prunes token ids that already expired from the index while reading it.
+func (sessions *RedisSessions) List(ctx context.Context, account string) ([]model.SessionInfoModel, error) {
+	indexKeys := []string{SESSION_INDEX_PREFIX + account}
+	if account == "" {
+		keys, err := sessions.scanKeys(ctx, SESSION_INDEX_PREFIX+"*")
+		if err != nil {
+			return nil, err
+		}
+		indexKeys = keys
+	}
+
+	list := make([]model.SessionInfoModel, 0)
+	for _, indexKey := range indexKeys {
+		uuids, err := sessions.RedisClient.SMembers(ctx, indexKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, sessionUuid := range uuids {
+			session, err := sessions.Fetch(ctx, sessionUuid)
+			if err == ErrNotFound {
+				sessions.RedisClient.SRem(ctx, indexKey, sessionUuid)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ttl, err := sessions.RedisClient.TTL(ctx, sessionUuid).Result()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, model.SessionInfoModel{Uuid: sessionUuid, Email: session.Email, OrgId: session.OrgId, ExpiresIn: ttl})
+		}
+	}
+	return list, nil
+}
+
+func (sessions *RedisSessions) Revoke(ctx context.Context, account string) (int64, error) {
+	indexKey := SESSION_INDEX_PREFIX + account
+	uuids, err := sessions.RedisClient.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	deleted := int64(0)
+	if len(uuids) > 0 {
+		// one DEL per token, the keys of a cluster live in different slots
+		commands, err := sessions.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, sessionUuid := range uuids {
+				pipe.Del(ctx, sessionUuid)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, command := range commands {
+			deleted += command.(*redis.IntCmd).Val()
+		}
+		members := make([]interface{}, len(uuids))
+		for index, sessionUuid := range uuids {
+			members[index] = sessionUuid
+		}
+		err = sessions.RedisClient.ZRem(ctx, SESSION_ACTIVE_KEY, members...).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = sessions.RedisClient.Del(ctx, indexKey).Err()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// CountActive drops expired refresh tokens from the count first.
+func (sessions *RedisSessions) CountActive(ctx context.Context) (int64, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	err := sessions.RedisClient.ZRemRangeByScore(ctx, SESSION_ACTIVE_KEY, "-inf", now).Err()
+	if err != nil {
+		return 0, err
+	}
+	return sessions.RedisClient.ZCard(ctx, SESSION_ACTIVE_KEY).Result()
+}
+
+// scanKeys scans every master of a cluster, SCAN only sees the node it runs
+// on.
+func (sessions *RedisSessions) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	cluster, ok := sessions.RedisClient.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, sessions.RedisClient, pattern)
+	}
+	mutex := sync.Mutex{}
+	keys := make([]string, 0)
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scanNode(ctx, node, pattern)
+		mutex.Lock()
+		keys = append(keys, nodeKeys...)
+		mutex.Unlock()
+		return err
+	})
+	return keys, err
+}
+
+func scanNode(ctx context.Context, node redis.Cmdable, pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	iterator := node.Scan(ctx, 0, pattern, 100).Iterator()
+	for iterator.Next(ctx) {
+		keys = append(keys, iterator.Val())
+	}
+	return keys, iterator.Err()
+}
@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ima-svc-management/model"
+	"io"
+	"time"
+)
+
+// Errors shared by every implementation so callers do not depend on the
+// storage behind a repository.
+var ErrNotFound = errors.New("not found")
+var ErrDuplicate = errors.New("duplicate")
+
+// ErrReference is returned by stores enforcing foreign keys, when an account
+// names a role its organization does not have or a role still in use is
+// renamed or removed. Callers check roles in use up front so every store
+// behaves alike, the foreign keys only catch concurrent changes.
+var ErrReference = errors.New("reference")
+
+// Scope narrows a query to the accounts or roles of one organization, an empty
+// OrgId addresses the ones without organization. Any lifts the restriction
+// for platform superadmins.
+type Scope struct {
+	OrgId string
+	Any   bool
+}
+
+// OrganizationScope addresses the documents of organization orgId only.
+func OrganizationScope(orgId string) Scope {
+	return Scope{OrgId: orgId}
+}
+
+// AnyScope addresses the documents of every organization.
+func AnyScope() Scope {
+	return Scope{Any: true}
+}
+
+func (scope Scope) matches(orgId string) bool {
+	return scope.Any || scope.OrgId == orgId
+}
+
+// Page selects a slice of a listing. Skip is the number of documents passed
+// over and Size the most returned, zero means no limit. OrderBy is a stored
+// field name, Order is asc or desc.
+type Page struct {
+	Order   string
+	OrderBy string
+	Skip    int
+	Size    int
+}
+
+// AccountQuery lists accounts, Attributes narrows to accounts holding every
+// given attribute value.
+type AccountQuery struct {
+	Page       Page
+	Attributes map[string]interface{}
+}
+
+// AccountChange describes an update of an account, nil fields are kept. With
+// ReplaceAttributes the attributes are replaced by Attributes, an empty map
+// removes them.
+type AccountChange struct {
+	Name              *string
+	Email             *string
+	EmailNormalized   *string
+	Password          *string
+	Role              *string
+	Attributes        map[string]interface{}
+	ReplaceAttributes bool
+	UpdatedAt         int64
+}
+
+// Apply changes account like the stored update does and counts the version up.
+func (change AccountChange) Apply(account *model.AccountModel) {
+	if change.Name != nil {
+		account.Name = *change.Name
+	}
+	if change.Email != nil {
+		account.Email = *change.Email
+	}
+	if change.EmailNormalized != nil {
+		account.EmailNormalized = *change.EmailNormalized
+	}
+	if change.Password != nil {
+		account.Password = *change.Password
+	}
+	if change.Role != nil {
+		account.Role = *change.Role
+	}
+	if change.ReplaceAttributes {
+		account.Attributes = nil
+		if len(change.Attributes) > 0 {
+			account.Attributes = change.Attributes
+		}
+	}
+	account.UpdatedAt = change.UpdatedAt
+	account.Version++
+}
+
+// RoleChange describes an update of a role, nil fields are kept.
+type RoleChange struct {
+	Name        *string
+	Role        *model.EnumRole
+	Description *string
+	UpdatedAt   time.Time
+}
+
+// Apply changes role like the stored update does and counts the version up.
+func (change RoleChange) Apply(role *model.RoleModel) {
+	if change.Name != nil {
+		role.Name = *change.Name
+	}
+	if change.Role != nil {
+		role.Role = *change.Role
+	}
+	if change.Description != nil {
+		role.Description = *change.Description
+	}
+	updatedAt := change.UpdatedAt
+	role.UpdatedAt = &updatedAt
+	role.Version++
+}
+
+// OrganizationChange describes an update of an organization, nil fields are
+// kept.
+type OrganizationChange struct {
+	Name        *string
+	Description *string
+	UpdatedAt   int64
+}
+
+// Apply changes organization like the stored update does.
+func (change OrganizationChange) Apply(organization *model.OrganizationModel) {
+	if change.Name != nil {
+		organization.Name = *change.Name
+	}
+	if change.Description != nil {
+		organization.Description = *change.Description
+	}
+	organization.UpdatedAt = change.UpdatedAt
+}
+
+// AttributeSchemaChange replaces the definition of an attribute schema, its
+// name and organization are kept.
+type AttributeSchemaChange struct {
+	Type        model.EnumAttributeType
+	Required    bool
+	Enum        []string
+	Regex       string
+	TokenClaim  bool
+	Description string
+	UpdatedAt   int64
+}
+
+// Apply changes schema like the stored update does.
+func (change AttributeSchemaChange) Apply(schema *model.AttributeSchemaModel) {
+	schema.Type = change.Type
+	schema.Required = change.Required
+	schema.Enum = change.Enum
+	schema.Regex = change.Regex
+	schema.TokenClaim = change.TokenClaim
+	schema.Description = change.Description
+	schema.UpdatedAt = change.UpdatedAt
+}
+
+// AccountUpdate is an account before and after a change that touched several
+// accounts at once.
+type AccountUpdate struct {
+	Previous model.AccountModel
+	Updated  model.AccountModel
+}
+
+// Transactor runs fn in a transaction of the store, repository calls made
+// with the context passed to fn take part in it.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// AccountRepository stores accounts. Emails are looked up by their
+// normalized form, which the caller sets on the account.
+type AccountRepository interface {
+	// Create stores account with the id set by the caller, ErrDuplicate when
+	// the email is already registered in the organization.
+	Create(ctx context.Context, account *model.AccountModel) error
+	FindById(ctx context.Context, scope Scope, id string) (*model.AccountModel, error)
+	FindByEmail(ctx context.Context, scope Scope, emailNormalized string) (*model.AccountModel, error)
+	// CountByEmail counts the organizations emailNormalized is registered in.
+	CountByEmail(ctx context.Context, emailNormalized string) (int64, error)
+	List(ctx context.Context, scope Scope, query AccountQuery) ([]model.AccountModel, error)
+	// Update applies change to the account with id, only while it is at
+	// version when version is not nil. Accounts written before versioning
+	// count as version 0. It returns the account before and after the change,
+	// ErrNotFound when no account matched.
+	Update(ctx context.Context, scope Scope, id string, version *int64, change AccountChange) (*model.AccountModel, *model.AccountModel, error)
+	// UpdateByEmail is Update addressing the account by email.
+	UpdateByEmail(ctx context.Context, scope Scope, emailNormalized string, change AccountChange) (*model.AccountModel, *model.AccountModel, error)
+	// Delete removes the account with id and returns it.
+	Delete(ctx context.Context, scope Scope, id string) (*model.AccountModel, error)
+	CountByOrganization(ctx context.Context, orgId string) (int64, error)
+	// CountByRole returns the number of accounts of every role across all
+	// organizations.
+	CountByRole(ctx context.Context) (map[string]int64, error)
+	// CountWithRole counts the accounts of orgId holding the role name.
+	CountWithRole(ctx context.Context, orgId string, role string) (int64, error)
+	// UnsetAttribute removes attribute name from every account of orgId
+	// holding it and returns those accounts before and after.
+	UnsetAttribute(ctx context.Context, orgId string, name string) ([]AccountUpdate, error)
+}
+
+// RoleRepository stores roles, names are unique within an organization.
+type RoleRepository interface {
+	// Create stores role with the id set by the caller, ErrDuplicate when the
+	// name is taken in the organization.
+	Create(ctx context.Context, role *model.RoleModel) error
+	FindById(ctx context.Context, scope Scope, id string) (*model.RoleModel, error)
+	FindByName(ctx context.Context, scope Scope, name string) (*model.RoleModel, error)
+	List(ctx context.Context, scope Scope, page Page) ([]model.RoleModel, error)
+	// Update applies change to the role with id, only while it is at version
+	// when version is not nil. It returns the role before and after the
+	// change, ErrNotFound when no role matched.
+	Update(ctx context.Context, scope Scope, id string, version *int64, change RoleChange) (*model.RoleModel, *model.RoleModel, error)
+	// Delete removes the role with id and returns it.
+	Delete(ctx context.Context, scope Scope, id string) (*model.RoleModel, error)
+	// DeleteByOrganization removes every role of orgId and returns them.
+	DeleteByOrganization(ctx context.Context, orgId string) ([]model.RoleModel, error)
+}
+
+// OrganizationRepository stores organizations, names are unique.
+type OrganizationRepository interface {
+	// Create stores organization with the id set by the caller, ErrDuplicate
+	// when the name is taken.
+	Create(ctx context.Context, organization *model.OrganizationModel) error
+	FindById(ctx context.Context, id string) (*model.OrganizationModel, error)
+	FindByName(ctx context.Context, name string) (*model.OrganizationModel, error)
+	List(ctx context.Context, page Page) ([]model.OrganizationModel, error)
+	// Update applies change to the organization with id. It returns the
+	// organization before and after the change, ErrNotFound when there is
+	// none and ErrDuplicate when the new name is taken.
+	Update(ctx context.Context, id string, change OrganizationChange) (*model.OrganizationModel, *model.OrganizationModel, error)
+	// Delete removes the organization with id and returns it.
+	Delete(ctx context.Context, id string) (*model.OrganizationModel, error)
+}
+
+// AttributeSchemaRepository stores the custom account attributes of the
+// organizations, names are unique within an organization.
+type AttributeSchemaRepository interface {
+	// Create stores schema with the id set by the caller, ErrDuplicate when
+	// the name is taken in the organization.
+	Create(ctx context.Context, schema *model.AttributeSchemaModel) error
+	FindById(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error)
+	// FindByOrganization returns every schema of orgId.
+	FindByOrganization(ctx context.Context, orgId string) ([]model.AttributeSchemaModel, error)
+	List(ctx context.Context, scope Scope, page Page) ([]model.AttributeSchemaModel, error)
+	// Update applies change to the schema with id and returns it before and
+	// after the change, ErrNotFound when no schema matched.
+	Update(ctx context.Context, scope Scope, id string, change AttributeSchemaChange) (*model.AttributeSchemaModel, *model.AttributeSchemaModel, error)
+	// Delete removes the schema with id and returns it.
+	Delete(ctx context.Context, scope Scope, id string) (*model.AttributeSchemaModel, error)
+	// DeleteByOrganization removes every schema of orgId.
+	DeleteByOrganization(ctx context.Context, orgId string) error
+}
+
+// WebhookRepository stores webhook subscriptions and their deliveries.
+// Webhooks without organization receive the events of every organization.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.WebhookModel) error
+	// List returns the webhooks of scope in the order they were created,
+	// without their secrets.
+	List(ctx context.Context, scope Scope) ([]model.WebhookModel, error)
+	FindById(ctx context.Context, scope Scope, id string) (*model.WebhookModel, error)
+	// Update stores the url, events, secret, active flag and update time of
+	// webhook, ErrNotFound when scope holds no webhook with its id.
+	Update(ctx context.Context, scope Scope, webhook model.WebhookModel) error
+	Delete(ctx context.Context, scope Scope, id string) error
+	// DeleteByOrganization removes the webhooks of orgId and every delivery
+	// of the organization, webhooks without organization are kept.
+	DeleteByOrganization(ctx context.Context, orgId string) error
+	// Subscribed returns the active webhooks of orgId and those without
+	// organization that subscribed to event.
+	Subscribed(ctx context.Context, orgId string, event model.EnumWebhookEvent) ([]model.WebhookModel, error)
+
+	AddDeliveries(ctx context.Context, deliveries []model.WebhookDeliveryModel) error
+	// Claim locks the most overdue pending delivery that is due at now and
+	// not locked until lockedUntil, nil when there is none. An expired lock
+	// counts as released.
+	Claim(ctx context.Context, now time.Time, lockedUntil time.Time) (*model.WebhookDeliveryModel, error)
+	// Finish appends attempt to the delivery log, counts it and releases the
+	// lock. A succeeded delivery records the attempt time as delivery time,
+	// a pending one is due again at nextAttemptAt.
+	Finish(ctx context.Context, id string, attempt model.WebhookAttemptModel, status model.EnumDeliveryStatus, nextAttemptAt time.Time) error
+	// Redeliver makes the delivery with id due at now with a fresh attempt
+	// budget, ErrNotFound when scope holds no such delivery that is not
+	// pending. The delivery log is kept.
+	Redeliver(ctx context.Context, scope Scope, id string, now time.Time) error
+	// ListDeliveries returns a page of the deliveries matching query, newest
+	// first, and the number of matching deliveries.
+	ListDeliveries(ctx context.Context, scope Scope, query DeliveryQuery) ([]model.WebhookDeliveryModel, int64, error)
+}
+
+// DeliveryQuery lists webhook deliveries, empty fields do not narrow.
+type DeliveryQuery struct {
+	WebhookId string
+	Status    model.EnumDeliveryStatus
+	Event     model.EnumWebhookEvent
+	Skip      int
+	Size      int
+}
+
+// AvatarStore keeps the images of account avatars, one file per variant.
+type AvatarStore interface {
+	// Replace stores variants as the avatar of accountId and then removes
+	// the files of the previous one.
+	Replace(ctx context.Context, accountId string, variants []model.AvatarVariantModel) error
+	// Open returns the newest file of variant and its content, ErrNotFound
+	// when there is none. The caller closes the content.
+	Open(ctx context.Context, accountId string, variant string) (*model.AvatarFileModel, io.ReadCloser, error)
+	// Delete removes every variant of the avatar of accountId.
+	Delete(ctx context.Context, accountId string) error
+	// DeleteByOrganization removes the avatars of every account of orgId.
+	DeleteByOrganization(ctx context.Context, orgId string) error
+}
+
+// SessionStore keeps the access and refresh token ids of logged in accounts
+// until they expire. Sessions are indexed by an account key so every session
+// of one account can be listed and revoked, an empty key lists all accounts.
+type SessionStore interface {
+	// Create stores the access and refresh token of one login.
+	Create(ctx context.Context, account string, session model.SessionModel, access string, accessExpires time.Time, refresh string, refreshExpires time.Time) error
+	// Fetch returns the session of a token id, ErrNotFound once it expired
+	// or was deleted.
+	Fetch(ctx context.Context, uuid string) (*model.SessionModel, error)
+	// Delete removes one token id and returns how many were still alive.
+	Delete(ctx context.Context, uuid string) (int64, error)
+	List(ctx context.Context, account string) ([]model.SessionInfoModel, error)
+	// Revoke deletes every token of account and returns how many were still
+	// alive.
+	Revoke(ctx context.Context, account string) (int64, error)
+	// CountActive returns how many refresh tokens are still alive.
+	CountActive(ctx context.Context) (int64, error)
+}
+
+// OutboxRepository keeps domain events until the relay published them. Add
+// takes part in the transaction of the context it is given, so an event is
+// only stored together with the change that caused it.
+type OutboxRepository interface {
+	Add(ctx context.Context, event *model.OutboxEventModel) error
+	// Pending returns up to limit unpublished events in the order they were
+	// written.
+	Pending(ctx context.Context, limit int) ([]model.OutboxEventModel, error)
+	// MarkPublished records the stream id the event with id was published as.
+	MarkPublished(ctx context.Context, id string, streamId string, publishedAt time.Time) error
+	// Purge removes events published before the given time and returns how
+	// many were removed.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditQuery lists audit events, empty fields do not narrow. From is
+// inclusive and To exclusive. Events come in the order they were created,
+// newest first with Descending.
+type AuditQuery struct {
+	Action     string
+	ActorEmail string
+	TargetType string
+	TargetId   string
+	From       time.Time
+	To         time.Time
+	Descending bool
+	Skip       int
+	Size       int
+}
+
+// AuditRepository appends events to the audit hash chain. Each store hashes
+// its own encoding of an event, Content returns it and Walk reads it back
+// unchanged so a verifier can recompute every hash.
+type AuditRepository interface {
+	// Head returns the sequence and hash of the last chained event, zero
+	// values for an empty chain.
+	Head(ctx context.Context) (int64, string, error)
+	// Content encodes event without its hash the way Append stores it.
+	Content(event model.AuditEventModel) ([]byte, error)
+	// Append stores event together with the content its hash covers,
+	// ErrDuplicate when another writer took the sequence number first.
+	Append(ctx context.Context, event model.AuditEventModel, content []byte) error
+	AddCheckpoint(ctx context.Context, checkpoint model.AuditCheckpointModel) error
+	// Checkpoints returns every checkpoint by sequence.
+	Checkpoints(ctx context.Context) ([]model.AuditCheckpointModel, error)
+	// CountUnchained counts the events recorded before the chain existed.
+	CountUnchained(ctx context.Context) (int64, error)
+	// Walk calls fn with every chained event by sequence and the content it
+	// was read back from, until fn fails.
+	Walk(ctx context.Context, fn func(event model.AuditEventModel, content []byte) error) error
+	// List returns a page of the events matching query and the number of
+	// matching events.
+	List(ctx context.Context, scope Scope, query AuditQuery) ([]model.AuditEventModel, int64, error)
+}
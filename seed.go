@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ima-svc-management/audit"
+	"ima-svc-management/config"
+	"ima-svc-management/controllers"
+	"ima-svc-management/events"
+	"ima-svc-management/model"
+	"ima-svc-management/webhook"
+
+	"github.com/rs/zerolog"
+)
+
+// seedRoles are created in every seeded organization.
+var seedRoles = []model.RoleModel{
+	{Name: "superadmin", Role: model.SUPERADMIN, Description: "Manage accounts, roles and attributes of the organization"},
+	{Name: "user", Role: model.USER, Description: "Run and follow reprocess jobs"},
+	{Name: "guest", Role: model.GUEST, Description: "Read only access"},
+}
+
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	loader := config.Bind(flags)
+	orgName := flags.String("org-name", "default", "name of the organization to seed, reused when it exists")
+	orgDescription := flags.String("org-description", "", "description of a newly created organization")
+	adminEmail := flags.String("admin-email", "", "also create an organization superadmin with this email")
+	adminName := flags.String("admin-name", "Superadmin", "display name of the organization superadmin")
+	adminPassword := flags.String("admin-password", "", "password of the organization superadmin")
+	adminPasswordStdin := flags.Bool("admin-password-stdin", false, "read the superadmin password from the first line of stdin")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.Load(config.STORAGE, config.AUDIT, config.WEBHOOK)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	auditor := audit.InitAuditor(store.Audit, cfg.Audit)
+	outbox := events.InitOutbox(store.Mongo, store.Outbox, store.Transactor)
+	err = outbox.Check(ctx)
+	if err != nil {
+		return err
+	}
+	organizationController := controllers.InitOrganization(store.Organizations, store.Accounts, store.Roles, store.Attributes, store.Webhooks, store.Avatars, auditor, outbox)
+	webhooks := webhook.InitDispatcher(store.Webhooks, cfg.Webhook, zerolog.Nop())
+	roleController := controllers.InitRole(store.Accounts, store.Roles, auditor, webhooks, outbox)
+	accountController := controllers.InitAccount(store.Accounts, store.Roles, store.Organizations, store.Attributes, store.Avatars, auditor, webhooks, outbox, cfg.Compat)
+
+	orgId := ""
+	organization, err := organizationController.FindOrganizationByName(ctx, *orgName)
+	if err == controllers.ErrOrganizationNotFound {
+		orgId, err = organizationController.CreateOrganization(ctx, model.OrganizationModel{Name: *orgName, Description: *orgDescription})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created organization %s with id %s\n", *orgName, orgId)
+	} else if err != nil {
+		return err
+	} else {
+		orgId = organization.Id
+		fmt.Printf("using organization %s with id %s\n", *orgName, orgId)
+	}
+
+	for _, role := range seedRoles {
+		role.OrgId = orgId
+		_, err = roleController.CreateRole(ctx, role)
+		if err == controllers.ErrRoleRegistered {
+			fmt.Printf("role %s already exists\n", role.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created role %s\n", role.Name)
+	}
+
+	if *adminEmail == "" {
+		return nil
+	}
+	password, err := readPassword(*adminPassword, *adminPasswordStdin)
+	if err != nil {
+		return err
+	}
+	account := model.AccountModel{
+		OrgId:    orgId,
+		Name:     *adminName,
+		Email:    *adminEmail,
+		Password: password,
+		Role:     string(model.SUPERADMIN),
+	}
+	_, err = accountController.CreateAccount(ctx, account)
+	if err == controllers.ErrEmailRegistered {
+		fmt.Printf("superadmin %s already exists\n", *adminEmail)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created superadmin %s\n", *adminEmail)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"ima-svc-management/config"
+	"ima-svc-management/migrations"
+	"ima-svc-management/repository"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// storage holds the repositories of the configured backend and the
+// connection they use, Mongo with the mongo backend and Postgres with the
+// postgres one. Transactor is nil when they live in Mongo.
+type storage struct {
+	Backend       string
+	Accounts      repository.AccountRepository
+	Roles         repository.RoleRepository
+	Organizations repository.OrganizationRepository
+	Attributes    repository.AttributeSchemaRepository
+	Avatars       repository.AvatarStore
+	Webhooks      repository.WebhookRepository
+	Outbox        repository.OutboxRepository
+	Audit         repository.AuditRepository
+	Transactor    repository.Transactor
+	Mongo         *mongo.Database
+	Postgres      *sql.DB
+}
+
+// openStorage connects the configured backend, Mongo is not connected with
+// the postgres backend. monitors watch the commands sent to Mongo.
+func openStorage(cfg *config.Config, monitors ...*event.CommandMonitor) (*storage, error) {
+	if cfg.Storage.Backend != "postgres" {
+		client, err := config.Mongo(cfg.Mongo, monitors...)
+		if err != nil {
+			return nil, err
+		}
+		database := client.Database(cfg.Mongo.Database)
+		return &storage{
+			Backend:       cfg.Storage.Backend,
+			Accounts:      repository.InitMongoAccounts(database),
+			Roles:         repository.InitMongoRoles(database),
+			Organizations: repository.InitMongoOrganizations(database),
+			Attributes:    repository.InitMongoAttributeSchemas(database),
+			Avatars:       repository.InitMongoAvatars(database),
+			Webhooks:      repository.InitMongoWebhooks(database),
+			Outbox:        repository.InitMongoOutbox(database),
+			Audit:         repository.InitMongoAudit(database),
+			Mongo:         database,
+		}, nil
+	}
+	db, err := config.Postgres(cfg.Postgres)
+	if err != nil {
+		return nil, err
+	}
+	return &storage{
+		Backend:       cfg.Storage.Backend,
+		Accounts:      repository.InitPostgresAccounts(db),
+		Roles:         repository.InitPostgresRoles(db),
+		Organizations: repository.InitPostgresOrganizations(db),
+		Attributes:    repository.InitPostgresAttributeSchemas(db),
+		Avatars:       repository.InitPostgresAvatars(db),
+		Webhooks:      repository.InitPostgresWebhooks(db),
+		Outbox:        repository.InitPostgresOutbox(db),
+		Audit:         repository.InitPostgresAudit(db),
+		Transactor:    repository.InitPostgresTransactor(db),
+		Postgres:      db,
+	}, nil
+}
+
+func (storage *storage) Close() {
+	if storage.Mongo != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		storage.Mongo.Client().Disconnect(ctx)
+	}
+	if storage.Postgres != nil {
+		storage.Postgres.Close()
+	}
+}
+
+// Ping checks the connection of the backend.
+func (storage *storage) Ping(ctx context.Context) error {
+	if storage.Mongo != nil {
+		return storage.Mongo.Client().Ping(ctx, nil)
+	}
+	return storage.Postgres.PingContext(ctx)
+}
+
+// Migrations returns the migration runner of the backend.
+func (storage *storage) Migrations() (migrationRunner, error) {
+	if storage.Mongo != nil {
+		return migrations.InitRunner(storage.Mongo), nil
+	}
+	runner, err := migrations.InitPostgresRunner(storage.Postgres)
+	if err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
+
+// openSessions returns the configured session store and a function closing
+// it. redisClient is only used by the redis store.
+func openSessions(cfg *config.Config, redisClient redis.UniversalClient) (repository.SessionStore, func(), error) {
+	switch cfg.Session.Store {
+	case "memory":
+		return repository.InitMemorySessions(), func() {}, nil
+	case "bolt":
+		sessions, err := repository.InitBoltSessions(cfg.Session.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sessions, func() { sessions.Close() }, nil
+	}
+	return repository.InitRedisSessions(redisClient), func() {}, nil
+}
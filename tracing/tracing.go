@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"ima-svc-management/config"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/extra/redisotel/v8"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACE_ID_HEADER = "X-Trace-Id"
+const CONTEXT_TRACE_ID = "traceId"
+const TRACER_NAME = "ima-svc-management"
+
+// Init installs the global tracer provider and W3C trace context propagation.
+// Spans are always created so trace ids reach headers and logs, they are only
+// exported when an exporter is configured. The returned function flushes
+// pending spans and must be called on shutdown.
+func Init(ctx context.Context, tracingConfig config.TracingConfig) (func(context.Context) error, error) {
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio))),
+	}
+
+	serviceResource, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(tracingConfig.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	providerOptions = append(providerOptions, sdktrace.WithResource(serviceResource))
+
+	switch tracingConfig.Exporter {
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx, otlpOptions(tracingConfig)...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdout exporter: %w", err)
+		}
+		providerOptions = append(providerOptions, sdktrace.WithSyncer(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOptions...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// otlpOptions accepts the endpoint either as host:port or as a URL, whose
+// scheme decides on TLS and whose path replaces the default /v1/traces.
+func otlpOptions(tracingConfig config.TracingConfig) []otlptracehttp.Option {
+	endpoint := tracingConfig.Endpoint
+	options := []otlptracehttp.Option{}
+	if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+		endpoint = parsed.Host
+		if parsed.Scheme == "http" {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		if strings.Trim(parsed.Path, "/") != "" {
+			options = append(options, otlptracehttp.WithURLPath(parsed.Path))
+		}
+	}
+	options = append(options, otlptracehttp.WithEndpoint(endpoint))
+	if tracingConfig.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return options
+}
+
+// Middleware continues the trace of an incoming traceparent header, or starts
+// one, and echoes the trace id in the X-Trace-Id response header.
+func Middleware(serviceName string) []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		otelgin.Middleware(serviceName),
+		func(c *gin.Context) {
+			spanContext := trace.SpanContextFromContext(c.Request.Context())
+			if spanContext.IsValid() {
+				c.Header(TRACE_ID_HEADER, spanContext.TraceID().String())
+				c.Set(CONTEXT_TRACE_ID, spanContext.TraceID().String())
+			}
+			c.Next()
+		},
+	}
+}
+
+// Action wraps the handler chain of a route in a span named after the
+// controller method, so Mongo and Redis spans nest under the action.
+func Action() gin.HandlerFunc {
+	tracer := otel.Tracer(TRACER_NAME)
+	return func(c *gin.Context) {
+		if c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		ctx, span := tracer.Start(c.Request.Context(), actionName(c.HandlerName()))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if c.Writer.Status() >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", c.Writer.Status()))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err)
+		}
+	}
+}
+
+// actionName turns "ima-svc-management/controllers.AccountController.GetAccount-fm"
+// into "controllers.AccountController.GetAccount".
+func actionName(handlerName string) string {
+	name := handlerName[strings.LastIndex(handlerName, "/")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
+
+func MongoMonitor() *event.CommandMonitor {
+	return otelmongo.NewMonitor()
+}
+
+func RedisHook() redis.Hook {
+	return redisotel.NewTracingHook()
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"fmt"
+	"ima-svc-management/apierror"
+)
+
+// messages holds the text of every rule per language, %[1]s is the field and
+// %[2]s the parameter of the rule. Length rules on strings use the _text
+// variant.
+var messages = map[string]map[string]string{
+	apierror.LANGUAGE_EN: {
+		"required":            "%[1]s is required",
+		"email":               "%[1]s must be a valid email address",
+		"min":                 "%[1]s must be at least %[2]s",
+		"min_text":            "%[1]s must be at least %[2]s characters long",
+		"max":                 "%[1]s must be at most %[2]s",
+		"max_text":            "%[1]s must be at most %[2]s characters long",
+		"oneof":               "%[1]s must be one of %[2]s",
+		RULE_ENUM_ROLE:        "%[1]s must be one of %[2]s",
+		RULE_ROLE_EXISTS:      "%[1]s is not a role of the organization",
+		RULE_TYPE:             "%[1]s must be a %[2]s",
+		RULE_READ_ONLY:        "%[1]s can not be changed",
+		RULE_ATTRIBUTE_NAME:   "%[1]s must start with a letter and only contain letters, digits or underscore",
+		RULE_REGEXP:           "%[1]s must be a valid regular expression",
+		RULE_STRING_ATTRIBUTE: "%[1]s is only allowed for string attributes",
+		"":                    "%[1]s is invalid",
+	},
+	apierror.LANGUAGE_ID: {
+		"required":            "%[1]s wajib diisi",
+		"email":               "%[1]s harus berupa alamat email yang valid",
+		"min":                 "%[1]s minimal %[2]s",
+		"min_text":            "%[1]s minimal %[2]s karakter",
+		"max":                 "%[1]s maksimal %[2]s",
+		"max_text":            "%[1]s maksimal %[2]s karakter",
+		"oneof":               "%[1]s harus salah satu dari %[2]s",
+		RULE_ENUM_ROLE:        "%[1]s harus salah satu dari %[2]s",
+		RULE_ROLE_EXISTS:      "%[1]s bukan peran yang terdaftar di organisasi",
+		RULE_TYPE:             "%[1]s harus bertipe %[2]s",
+		RULE_READ_ONLY:        "%[1]s tidak dapat diubah",
+		RULE_ATTRIBUTE_NAME:   "%[1]s harus diawali huruf dan hanya berisi huruf, angka atau garis bawah",
+		RULE_REGEXP:           "%[1]s harus berupa regular expression yang valid",
+		RULE_STRING_ATTRIBUTE: "%[1]s hanya boleh untuk atribut bertipe string",
+		"":                    "%[1]s tidak valid",
+	},
+}
+
+// Message describes the failure in language, rules without a text of their
+// own get a generic one.
+func (failure Failure) Message(language string) string {
+	texts, ok := messages[language]
+	if !ok {
+		texts = messages[apierror.DEFAULT_LANGUAGE]
+	}
+	rule := failure.Rule
+	if failure.Text {
+		if _, ok := texts[rule+"_text"]; ok {
+			rule += "_text"
+		}
+	}
+	text, ok := texts[rule]
+	if !ok {
+		text = texts[""]
+	}
+	return fmt.Sprintf(text, failure.Field, failure.Param)
+}
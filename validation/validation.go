@@ -0,0 +1,240 @@
+// Package validation checks requests against the binding rules of the models
+// and answers broken rules with VALIDATION_FAILED, listing every invalid
+// field with its rule and a localized message.
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ima-svc-management/apierror"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Rules added to the binding tags of gin.
+const RULE_ENUM_ROLE = "enumrole"
+const RULE_ATTRIBUTE_NAME = "attributename"
+const RULE_REGEXP = "regexp"
+
+// RULE_STRING_ATTRIBUTE allows a value only when the Type field next to it
+// is string, enum and regex only constrain string attributes.
+const RULE_STRING_ATTRIBUTE = "stringattribute"
+
+// RULE_ROLE_EXISTS needs the organization of the request and a lookup, it is
+// checked by RoleExists instead of a binding tag.
+const RULE_ROLE_EXISTS = "role_exists"
+
+// RULE_TYPE reports a JSON value of the wrong type.
+const RULE_TYPE = "type"
+
+// RULE_READ_ONLY reports a patch touching a field it can not change.
+const RULE_READ_ONLY = "readonly"
+
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Failure is one broken rule, Param is the parameter of the rule like the
+// bound of max. Text tells length rules on strings from bounds on numbers.
+type Failure struct {
+	Field string
+	Rule  string
+	Param string
+	Text  bool
+}
+
+// Error is a request breaking one or more rules.
+type Error struct {
+	Failures []Failure
+}
+
+func (err *Error) Error() string {
+	messages := make([]string, 0, len(err.Failures))
+	for _, failure := range err.Failures {
+		messages = append(messages, failure.Message(apierror.DEFAULT_LANGUAGE))
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Register adds the custom rules to the validator of gin and makes it name
+// fields by their json or form name. Call it once before serving.
+func Register() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin does not validate with go-playground/validator")
+	}
+	engine.RegisterTagNameFunc(fieldName)
+	rules := map[string]validator.Func{
+		RULE_ENUM_ROLE:        enumRole,
+		RULE_ATTRIBUTE_NAME:   attributeName,
+		RULE_REGEXP:           validRegexp,
+		RULE_STRING_ATTRIBUTE: stringAttribute,
+	}
+	for tag, rule := range rules {
+		err := engine.RegisterValidation(tag, rule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Struct checks obj against its binding tags, for values that do not come
+// through gin's binding like patched documents.
+func Struct(obj interface{}) error {
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// RoleExists is the role_exists rule: role has to name a role of organization
+// orgId. Accounts without role and platform superadmins, which belong to no
+// organization, pass.
+func RoleExists(ctx context.Context, roles repository.RoleRepository, orgId string, field string, role string) error {
+	if role == "" || role == string(model.PLATFORM_SUPERADMIN) {
+		return nil
+	}
+	_, err := roles.FindByName(ctx, repository.OrganizationScope(orgId), role)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &Error{Failures: []Failure{{Field: field, Rule: RULE_ROLE_EXISTS}}}
+	}
+	return err
+}
+
+// Failures returns the broken rules err reports, nil when err is not about
+// the content of the request, like malformed JSON.
+func Failures(err error) []Failure {
+	invalid := &Error{}
+	if errors.As(err, &invalid) {
+		return invalid.Failures
+	}
+	failures := validator.ValidationErrors{}
+	if errors.As(err, &failures) {
+		list := make([]Failure, 0, len(failures))
+		for _, failure := range failures {
+			list = append(list, Failure{
+				Field: fieldPath(failure.Namespace()),
+				Rule:  ruleName(failure.Tag()),
+				Param: ruleParam(failure),
+				Text:  failure.Kind() == reflect.String,
+			})
+		}
+		return list
+	}
+	typeError := &json.UnmarshalTypeError{}
+	if errors.As(err, &typeError) {
+		return []Failure{{Field: typeError.Field, Rule: RULE_TYPE, Param: jsonType(typeError.Type)}}
+	}
+	return nil
+}
+
+// Abort answers a request that failed binding or validation. Broken rules
+// give VALIDATION_FAILED, anything else is a malformed request.
+func Abort(c *gin.Context, err error) {
+	failures := Failures(err)
+	if failures == nil {
+		apierror.AbortWithReason(c, apierror.INVALID_REQUEST, err)
+		return
+	}
+	language := apierror.Negotiate(c.GetHeader("Accept-Language"))
+	fields := make([]model.FieldErrorModel, 0, len(failures))
+	for _, failure := range failures {
+		fields = append(fields, model.FieldErrorModel{
+			Field:   failure.Field,
+			Rule:    failure.Rule,
+			Message: failure.Message(language),
+		})
+	}
+	apierror.AbortWithDetails(c, apierror.VALIDATION_FAILED, fields)
+}
+
+func enumRole(field validator.FieldLevel) bool {
+	value := field.Field().String()
+	for _, role := range model.ROLES {
+		if value == string(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func attributeName(field validator.FieldLevel) bool {
+	return attributeNamePattern.MatchString(field.Field().String())
+}
+
+func validRegexp(field validator.FieldLevel) bool {
+	_, err := regexp.Compile(field.Field().String())
+	return err == nil
+}
+
+func stringAttribute(field validator.FieldLevel) bool {
+	if field.Field().Len() == 0 {
+		return true
+	}
+	attributeType := field.Parent().FieldByName("Type")
+	return attributeType.IsValid() && attributeType.String() == string(model.ATTRIBUTE_STRING)
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldPath drops the struct name validator puts in front of every field.
+func fieldPath(namespace string) string {
+	parts := strings.SplitN(namespace, ".", 2)
+	return parts[len(parts)-1]
+}
+
+// ruleName reports the conditional required rules as required, clients only
+// need to know the value is missing.
+func ruleName(tag string) string {
+	if strings.HasPrefix(tag, "required") {
+		return "required"
+	}
+	return tag
+}
+
+func ruleParam(failure validator.FieldError) string {
+	switch failure.Tag() {
+	case RULE_ENUM_ROLE:
+		roles := make([]string, 0, len(model.ROLES))
+		for _, role := range model.ROLES {
+			roles = append(roles, string(role))
+		}
+		return strings.Join(roles, ", ")
+	case "oneof":
+		return strings.Join(strings.Fields(failure.Param()), ", ")
+	}
+	return failure.Param()
+}
+
+func jsonType(kind reflect.Type) string {
+	switch kind.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return kind.String()
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is a webhook url or address inside the networks of the
+// service, tenants must not reach or probe them through deliveries.
+var ErrForbiddenTarget = errors.New("webhook target is an internal address")
+
+// sharedAddressSpace is the carrier grade NAT range, net.IP has no test for it.
+var sharedAddressSpace = mustParseCIDR("100.64.0.0/10")
+
+// ParseNetworks reads a comma separated list of CIDRs.
+func ParseNetworks(list string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0)
+	for _, cidr := range strings.Split(list, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ValidateUrl checks a subscribed url. Hosts given as internal addresses are
+// rejected right away, names are checked again on every delivery once they
+// resolve.
+func (dispatcher *Dispatcher) ValidateUrl(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return fmt.Errorf("url %q must be an absolute http or https url", raw)
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url %q: %w", raw, ErrForbiddenTarget)
+	}
+	if ip := net.ParseIP(host); ip != nil && !dispatcher.allowedIP(ip) {
+		return fmt.Errorf("url %q: %w", raw, ErrForbiddenTarget)
+	}
+	return nil
+}
+
+// allowedIP reports whether deliveries may connect to ip. Loopback, private,
+// link local (cloud metadata), shared and unspecified addresses are only
+// reachable when an allowed network contains them.
+func (dispatcher *Dispatcher) allowedIP(ip net.IP) bool {
+	for _, network := range dispatcher.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip) || ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// client posts deliveries. The address is checked when the connection is
+// made, after name resolution, so a name rebound to an internal address is
+// refused as well. Redirects are not followed and no proxy is used.
+func (dispatcher *Dispatcher) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !dispatcher.allowedIP(ip) {
+				return fmt.Errorf("%s: %w", address, ErrForbiddenTarget)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
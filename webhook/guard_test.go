@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"errors"
+	"ima-svc-management/config"
+	"ima-svc-management/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// testDispatcher is a dispatcher without stores, enough to check urls and post
+// single attempts.
+func testDispatcher(t *testing.T, allowedNetworks string) *Dispatcher {
+	t.Helper()
+	allowed, err := ParseNetworks(allowedNetworks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := &Dispatcher{
+		Config:  config.WebhookConfig{Timeout: time.Second * 2},
+		Logger:  zerolog.Nop(),
+		allowed: allowed,
+	}
+	dispatcher.Client = dispatcher.client(dispatcher.Config.Timeout)
+	return dispatcher
+}
+
+func TestAllowedIP(t *testing.T) {
+	cases := []struct {
+		name    string
+		ip      string
+		allowed string
+		want    bool
+	}{
+		{name: "loopback", ip: "127.0.0.1", want: false},
+		{name: "loopback range", ip: "127.1.2.3", want: false},
+		{name: "ipv6 loopback", ip: "::1", want: false},
+		{name: "rfc1918 10/8", ip: "10.0.0.1", want: false},
+		{name: "rfc1918 172.16/12", ip: "172.31.255.254", want: false},
+		{name: "rfc1918 192.168/16", ip: "192.168.1.1", want: false},
+		{name: "cloud metadata", ip: "169.254.169.254", want: false},
+		{name: "link local", ip: "169.254.0.1", want: false},
+		{name: "ipv6 link local", ip: "fe80::1", want: false},
+		{name: "ipv6 unique local fc00", ip: "fc00::1", want: false},
+		{name: "ipv6 unique local fd00", ip: "fd12:3456:789a::1", want: false},
+		{name: "v4 mapped loopback", ip: "::ffff:127.0.0.1", want: false},
+		{name: "v4 mapped private", ip: "::ffff:10.0.0.1", want: false},
+		{name: "v4 mapped metadata", ip: "::ffff:169.254.169.254", want: false},
+		{name: "shared address space", ip: "100.64.0.1", want: false},
+		{name: "this network", ip: "0.1.2.3", want: false},
+		{name: "unspecified", ip: "0.0.0.0", want: false},
+		{name: "ipv6 unspecified", ip: "::", want: false},
+		{name: "multicast", ip: "224.0.0.1", want: false},
+		{name: "ipv6 multicast", ip: "ff02::1", want: false},
+		{name: "public", ip: "93.184.216.34", want: true},
+		{name: "ipv6 public", ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{name: "v4 mapped public", ip: "::ffff:93.184.216.34", want: true},
+		{name: "allowed network", ip: "10.1.2.3", allowed: "10.1.0.0/16", want: true},
+		{name: "outside allowed network", ip: "10.2.0.1", allowed: "10.1.0.0/16", want: false},
+		{name: "allowed ipv6 network", ip: "fd00::5", allowed: "192.168.0.0/24, fd00::/64", want: true},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ip := net.ParseIP(test.ip)
+			if ip == nil {
+				t.Fatalf("%s is not an ip", test.ip)
+			}
+			got := testDispatcher(t, test.allowed).allowedIP(ip)
+			if got != test.want {
+				t.Errorf("allowedIP(%s) = %v, want %v", test.ip, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateUrl(t *testing.T) {
+	cases := []struct {
+		name      string
+		url       string
+		allowed   string
+		forbidden bool
+		invalid   bool
+	}{
+		{name: "public name", url: "https://hooks.example.com/ima"},
+		{name: "public address", url: "http://93.184.216.34:8080/hook"},
+		{name: "localhost", url: "http://localhost:8080/hook", forbidden: true},
+		{name: "localhost subdomain", url: "http://api.LOCALHOST/hook", forbidden: true},
+		{name: "loopback", url: "http://127.0.0.1/hook", forbidden: true},
+		{name: "ipv6 loopback", url: "http://[::1]:9000/hook", forbidden: true},
+		{name: "private", url: "https://192.168.10.4/hook", forbidden: true},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest/meta-data/", forbidden: true},
+		{name: "ipv6 unique local", url: "http://[fd00::1]/hook", forbidden: true},
+		{name: "v4 mapped", url: "http://[::ffff:10.0.0.1]/hook", forbidden: true},
+		{name: "allowed network", url: "http://10.1.2.3/hook", allowed: "10.1.0.0/16"},
+		{name: "scheme", url: "ftp://hooks.example.com/ima", invalid: true},
+		{name: "relative", url: "/hook", invalid: true},
+		{name: "no host", url: "http:///hook", invalid: true},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			err := testDispatcher(t, test.allowed).ValidateUrl(test.url)
+			switch {
+			case test.forbidden:
+				if !errors.Is(err, ErrForbiddenTarget) {
+					t.Errorf("ValidateUrl(%s) = %v, want ErrForbiddenTarget", test.url, err)
+				}
+			case test.invalid:
+				if err == nil || errors.Is(err, ErrForbiddenTarget) {
+					t.Errorf("ValidateUrl(%s) = %v, want an invalid url error", test.url, err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("ValidateUrl(%s) = %v, want nil", test.url, err)
+				}
+			}
+		})
+	}
+}
+
+// TestPostRefusesInternalTargets posts to a receiver on the loopback
+// interface, by address and by a name resolving to it, which the dialer must
+// refuse unless the loopback network is allowed.
+func TestPostRefusesInternalTargets(t *testing.T) {
+	hits := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer receiver.Close()
+	receiverUrl, err := url.Parse(receiver.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		url     string
+		allowed string
+		refused bool
+	}{
+		{name: "address", url: receiver.URL, refused: true},
+		{name: "name resolving to loopback", url: "http://localhost:" + receiverUrl.Port() + "/", refused: true},
+		{name: "allowed network", url: receiver.URL, allowed: "127.0.0.0/8"},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			before := hits
+			attempt := testDispatcher(t, test.allowed).post(
+				model.WebhookModel{Id: "webhook", Url: test.url, Secret: "secret"},
+				model.WebhookDeliveryModel{Id: "delivery", Event: model.WEBHOOK_ACCOUNT_CREATED, Payload: "{}"},
+			)
+			if test.refused {
+				if !strings.Contains(attempt.Error, ErrForbiddenTarget.Error()) || hits != before {
+					t.Errorf("post to %s was not refused: %+v", test.url, attempt)
+				}
+				return
+			}
+			if attempt.Error != "" || attempt.StatusCode != http.StatusOK || hits != before+1 {
+				t.Errorf("post to %s failed: %+v", test.url, attempt)
+			}
+		})
+	}
+}
+
+// TestPostRefusesRedirect makes sure a receiver can not bounce a delivery to
+// another address, the redirect is recorded as a failed attempt instead.
+func TestPostRefusesRedirect(t *testing.T) {
+	followed := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer internal.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	attempt := testDispatcher(t, "127.0.0.0/8").post(
+		model.WebhookModel{Id: "webhook", Url: receiver.URL, Secret: "secret"},
+		model.WebhookDeliveryModel{Id: "delivery", Event: model.WEBHOOK_ACCOUNT_CREATED, Payload: "{}"},
+	)
+	if followed {
+		t.Fatal("redirect was followed")
+	}
+	if attempt.StatusCode != http.StatusTemporaryRedirect || attempt.Error == "" {
+		t.Errorf("redirect not recorded as a failed attempt: %+v", attempt)
+	}
+}
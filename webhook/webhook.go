@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"ima-svc-management/config"
+	"ima-svc-management/helpers"
+	"ima-svc-management/model"
+	"ima-svc-management/repository"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+const SIGNATURE_HEADER = "X-Ima-Signature"
+const EVENT_HEADER = "X-Ima-Event"
+const DELIVERY_HEADER = "X-Ima-Delivery"
+
+// EMIT_TIMEOUT bounds queueing the deliveries of one event. Like audit writes
+// it does not use the request context so a client hanging up can not drop
+// an event.
+const EMIT_TIMEOUT = time.Second * 5
+
+// Dispatcher queues events as deliveries in the webhook repository and
+// posts them to the subscribed webhooks from a pool of workers. A delivery
+// that keeps failing is retried with exponential backoff and dead lettered
+// after MaxAttempts.
+type Dispatcher struct {
+	Webhooks repository.WebhookRepository
+	Client   *http.Client
+	Config   config.WebhookConfig
+	Logger   zerolog.Logger
+	wake     chan struct{}
+	allowed  []*net.IPNet
+}
+
+func InitDispatcher(webhooks repository.WebhookRepository, webhookConfig config.WebhookConfig, logger zerolog.Logger) *Dispatcher {
+	// the list is checked by config.Validate
+	allowed, _ := ParseNetworks(webhookConfig.AllowedNetworks)
+	dispatcher := &Dispatcher{
+		Webhooks: webhooks,
+		Config:   webhookConfig,
+		Logger:   logger.With().Str("component", "webhook").Logger(),
+		wake:     make(chan struct{}, 1),
+		allowed:  allowed,
+	}
+	dispatcher.Client = dispatcher.client(webhookConfig.Timeout)
+	return dispatcher
+}
+
+// Emit queues event for the request c, a failure is logged and does not fail
+// the request.
+func (dispatcher *Dispatcher) Emit(c *gin.Context, orgId string, event model.EnumWebhookEvent, data interface{}) {
+	err := dispatcher.Enqueue(orgId, event, data)
+	if err != nil {
+		zerolog.Ctx(c.Request.Context()).Error().Err(err).Str("event", string(event)).Msg("queueing webhook deliveries")
+	}
+}
+
+// Enqueue creates one delivery per active webhook of orgId subscribed to
+// event. Webhooks without organization receive the events of every
+// organization.
+func (dispatcher *Dispatcher) Enqueue(orgId string, event model.EnumWebhookEvent, data interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), EMIT_TIMEOUT)
+	defer cancel()
+
+	webhooks, err := dispatcher.Webhooks.Subscribed(ctx, orgId, event)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	now := time.Now().UTC()
+	payload, err := json.Marshal(model.WebhookPayloadModel{
+		Id:        helpers.GenerateId(),
+		Type:      event,
+		OrgId:     orgId,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	deliveries := make([]model.WebhookDeliveryModel, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, model.WebhookDeliveryModel{
+			Id:            helpers.GenerateId(),
+			WebhookId:     webhook.Id,
+			OrgId:         webhook.OrgId,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.DELIVERY_PENDING,
+			Attempts:      []model.WebhookAttemptModel{},
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	err = dispatcher.Webhooks.AddDeliveries(ctx, deliveries)
+	if err != nil {
+		return err
+	}
+	dispatcher.Wake()
+	return nil
+}
+
+// Wake lets an idle worker look for due deliveries before the next poll.
+func (dispatcher *Dispatcher) Wake() {
+	select {
+	case dispatcher.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Redeliver queues the delivery with id again with a fresh attempt budget,
+// its delivery log is kept. It fails with repository.ErrNotFound when scope
+// holds no such delivery that is not pending.
+func (dispatcher *Dispatcher) Redeliver(ctx context.Context, scope repository.Scope, id string) error {
+	err := dispatcher.Webhooks.Redeliver(ctx, scope, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	dispatcher.Wake()
+	return nil
+}
+
+// Run delivers due deliveries until ctx is cancelled and then waits for the
+// requests in flight.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	group := sync.WaitGroup{}
+	for worker := 0; worker < dispatcher.Config.Workers; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			dispatcher.work(ctx)
+		}()
+	}
+	group.Wait()
+}
+
+func (dispatcher *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.Config.PollInterval)
+	defer ticker.Stop()
+	for {
+		delivery, err := dispatcher.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			dispatcher.Logger.Error().Err(err).Msg("claiming delivery")
+		}
+		if delivery != nil {
+			dispatcher.deliver(ctx, *delivery)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-dispatcher.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim locks the most overdue pending delivery for this worker. The lock
+// expires so a delivery held by a crashed instance is picked up again.
+func (dispatcher *Dispatcher) claim(ctx context.Context) (*model.WebhookDeliveryModel, error) {
+	now := time.Now().UTC()
+	return dispatcher.Webhooks.Claim(ctx, now, now.Add(dispatcher.Config.Timeout*2))
+}
+
+func (dispatcher *Dispatcher) deliver(ctx context.Context, delivery model.WebhookDeliveryModel) {
+	webhook, err := dispatcher.Webhooks.FindById(ctx, repository.AnyScope(), delivery.WebhookId)
+	if err == repository.ErrNotFound {
+		dispatcher.finish(delivery, model.WebhookAttemptModel{At: time.Now().UTC(), Error: "webhook was deleted"}, model.DELIVERY_DEAD)
+		return
+	}
+	if err != nil {
+		dispatcher.Logger.Error().Err(err).Str("webhook_id", delivery.WebhookId).Str("delivery_id", delivery.Id).Msg("loading webhook")
+		return
+	}
+	if webhook.Active == nil || !*webhook.Active {
+		dispatcher.finish(delivery, model.WebhookAttemptModel{At: time.Now().UTC(), Error: "webhook is inactive"}, model.DELIVERY_DEAD)
+		return
+	}
+
+	attempt := dispatcher.post(*webhook, delivery)
+	status := model.DELIVERY_PENDING
+	if attempt.Error == "" {
+		status = model.DELIVERY_SUCCEEDED
+	} else if delivery.AttemptCount+1 >= dispatcher.Config.MaxAttempts {
+		status = model.DELIVERY_DEAD
+	}
+	dispatcher.finish(delivery, attempt, status)
+}
+
+// post sends one attempt. It is not cancelled on shutdown, Run waits for it
+// and the client timeout bounds it.
+func (dispatcher *Dispatcher) post(webhook model.WebhookModel, delivery model.WebhookDeliveryModel) model.WebhookAttemptModel {
+	start := time.Now()
+	attempt := model.WebhookAttemptModel{At: start.UTC()}
+
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "ima-svc-management-webhook")
+	request.Header.Set(EVENT_HEADER, string(delivery.Event))
+	request.Header.Set(DELIVERY_HEADER, delivery.Id)
+	request.Header.Set(SIGNATURE_HEADER, Signature(webhook.Secret, start.Unix(), body))
+
+	response, err := dispatcher.Client.Do(request)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", response.StatusCode)
+	}
+	return attempt
+}
+
+// finish appends attempt to the delivery log, schedules the next retry of a
+// delivery still pending and releases the lock.
+func (dispatcher *Dispatcher) finish(delivery model.WebhookDeliveryModel, attempt model.WebhookAttemptModel, status model.EnumDeliveryStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), EMIT_TIMEOUT)
+	defer cancel()
+
+	nextAttemptAt := time.Now().UTC().Add(dispatcher.backoff(delivery.AttemptCount + 1))
+	err := dispatcher.Webhooks.Finish(ctx, delivery.Id, attempt, status, nextAttemptAt)
+	if err != nil {
+		dispatcher.Logger.Error().Err(err).Str("webhook_id", delivery.WebhookId).Str("delivery_id", delivery.Id).Msg("recording delivery attempt")
+	}
+}
+
+// backoff doubles BackoffBase for every failed attempt up to BackoffMax and
+// adds up to a fifth of jitter so failing receivers are not hit in bursts.
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	delay := dispatcher.Config.BackoffBase
+	for attempt := 1; attempt < attempts && delay < dispatcher.Config.BackoffMax; attempt++ {
+		delay *= 2
+	}
+	if delay > dispatcher.Config.BackoffMax {
+		delay = dispatcher.Config.BackoffMax
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// Signature is the X-Ima-Signature header of a delivery: the unix timestamp
+// of the attempt and the hex HMAC-SHA256 of "timestamp.body" keyed with the
+// webhook secret. Receivers recompute it and reject stale timestamps.
+func Signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}